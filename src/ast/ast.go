@@ -302,6 +302,11 @@ type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
 	Body       *BlockStatement
+	Generator  bool // declared with fn*, calling it returns a generator instead of running the body
+
+	// Names the body reads or assigns that are not its own parameters or locals, filled in by the parser
+	// through the resolver package. The evaluator captures only these when it builds the closure
+	FreeVariables []string
 }
 
 // implements expression
@@ -318,6 +323,9 @@ func (fl *FunctionLiteral) String() string {
 	}
 
 	out.WriteString(fl.TokenLiteral())
+	if fl.Generator {
+		out.WriteString("*")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(")")
@@ -363,6 +371,40 @@ func (sl *StringLiteral) expressionNode()      {}
 func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
 func (sl *StringLiteral) String() string       { return sl.Token.Literal }
 
+// b"\x00\xff", Value holds the bytes with the escape sequences replaced
+type BytesLiteral struct {
+	Token token.Token // the token.BYTES token, its literal is the source between the quotes
+	Value []byte
+}
+
+func (bl *BytesLiteral) expressionNode()      {}
+func (bl *BytesLiteral) TokenLiteral() string { return bl.Token.Literal }
+func (bl *BytesLiteral) String() string       { return "b\"" + bl.Token.Literal + "\"" }
+
+// "text ${expression} text", Parts holds the text as *StringLiteral and the expressions in between in order
+type InterpolatedString struct {
+	Token token.Token // the TEMPLATE token
+	Parts []Expression
+}
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("\"")
+	for _, part := range is.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			out.WriteString(text.Value)
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+	out.WriteString("\"")
+
+	return out.String()
+}
+
 // implements Expression
 type ArrayLiteral struct {
 	Token    token.Token // the '[' token
@@ -386,6 +428,29 @@ func (al *ArrayLiteral) String() string {
 	return out.String()
 }
 
+// implements Expression
+type SetLiteral struct {
+	Token    token.Token // the '#{' token
+	Elements []Expression
+}
+
+func (sl *SetLiteral) expressionNode()      {}
+func (sl *SetLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *SetLiteral) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range sl.Elements {
+		elements = append(elements, el.String())
+	}
+
+	out.WriteString("#{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
 // implements Expression
 type IndexExpression struct {
 	Token token.Token // The [ token
@@ -407,9 +472,42 @@ func (ie *IndexExpression) String() string {
 	return out.String()
 }
 
+// s[start:end], either bound can be left out. implements Expression
+type SliceExpression struct {
+	Token token.Token // The [ token
+	Left  Expression
+	Start Expression // nil when left out
+	End   Expression // nil when left out
+}
+
+func (se *SliceExpression) expressionNode()      {}
+func (se *SliceExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(se.Left.String())
+	out.WriteString("[")
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteString(":")
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	out.WriteString("])")
+
+	return out.String()
+}
+
 type HashLiteral struct {
-	Token token.Token // the '{' token
-	Pairs map[Expression]Expression
+	Token token.Token       // the '{' token
+	Pairs []HashLiteralPair // in source order, which is the order the hash keeps its keys in
+}
+
+type HashLiteralPair struct {
+	Key   Expression
+	Value Expression
 }
 
 // implements Expression
@@ -421,8 +519,8 @@ func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s : %s", key.String(), value.String()))
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, fmt.Sprintf("%s : %s", pair.Key.String(), pair.Value.String()))
 	}
 
 	out.WriteString("{")
@@ -430,3 +528,290 @@ func (hl *HashLiteral) String() string {
 	out.WriteString("}")
 	return out.String()
 }
+
+// implements Expression
+type AssignExpression struct {
+	Token  token.Token // the '=' token
+	Target Expression  // the Identifier being re-bound or the MemberExpression being set
+	Value  Expression
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" = ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+type MemberExpression struct {
+	Token    token.Token // the '.' token
+	Object   Expression
+	Property *Identifier
+}
+
+func (me *MemberExpression) expressionNode()      {}
+func (me *MemberExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MemberExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(me.Object.String())
+	out.WriteString(".")
+	out.WriteString(me.Property.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// for (x in xs) { ... }
+type ForStatement struct {
+	Token    token.Token // the token.FOR token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForStatement) statementNode()       {}
+func (fs *ForStatement) TokenLiteral() string { return fs.Token.Literal }
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
+
+// struct Point { x, y }
+type StructStatement struct {
+	Token  token.Token // the token.STRUCT token
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (ss *StructStatement) statementNode()       {}
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StructStatement) String() string {
+	fields := []string{}
+	for _, field := range ss.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return fmt.Sprintf("struct %s { %s }", ss.Name.String(), strings.Join(fields, ", "))
+}
+
+// impl Point { fn dist(self, other) { ... } }
+type ImplStatement struct {
+	Token   token.Token // the token.IMPL token
+	Name    *Identifier // the struct the methods are for
+	Methods []*Method
+}
+
+// A method in an impl block, the receiver is passed as its first parameter
+type Method struct {
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+func (is *ImplStatement) statementNode()       {}
+func (is *ImplStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImplStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("impl ")
+	out.WriteString(is.Name.String())
+	out.WriteString(" { ")
+
+	for _, method := range is.Methods {
+		params := []string{}
+		for _, param := range method.Function.Parameters {
+			params = append(params, param.String())
+		}
+
+		out.WriteString(fmt.Sprintf("fn %s(%s) %s ", method.Name.String(), strings.Join(params, ", "), method.Function.Body.String()))
+	}
+
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// enum Result { Ok(value), Err(message), Pending }
+type EnumStatement struct {
+	Token    token.Token // the token.ENUM token
+	Name     *Identifier
+	Variants []*EnumVariant
+}
+
+// A variant of an enum, Fields is empty for one that carries no data
+type EnumVariant struct {
+	Name   *Identifier
+	Fields []*Identifier
+}
+
+func (es *EnumStatement) statementNode()       {}
+func (es *EnumStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *EnumStatement) String() string {
+	variants := []string{}
+	for _, variant := range es.Variants {
+		variants = append(variants, variant.String())
+	}
+
+	return fmt.Sprintf("enum %s { %s }", es.Name.String(), strings.Join(variants, ", "))
+}
+
+func (ev *EnumVariant) String() string {
+	if len(ev.Fields) == 0 {
+		return ev.Name.String()
+	}
+
+	fields := []string{}
+	for _, field := range ev.Fields {
+		fields = append(fields, field.String())
+	}
+
+	return fmt.Sprintf("%s(%s)", ev.Name.String(), strings.Join(fields, ", "))
+}
+
+// yield value; inside a fn* hands value to whoever asked the generator for its next one
+type YieldStatement struct {
+	Token token.Token // the token.YIELD token
+	Value Expression
+}
+
+func (ys *YieldStatement) statementNode()       {}
+func (ys *YieldStatement) TokenLiteral() string { return ys.Token.Literal }
+func (ys *YieldStatement) String() string       { return "yield " + ys.Value.String() + ";" }
+
+// import "lib/strings" as str;
+type ImportStatement struct {
+	Token token.Token // the token.IMPORT token
+	Path  *StringLiteral
+	Name  *Identifier // what the module is bound to
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	return fmt.Sprintf("import %q as %s;", is.Path.Value, is.Name.String())
+}
+
+// export let f = ...; makes the name declared by a let, struct or enum visible to modules importing this one
+type ExportStatement struct {
+	Token     token.Token // the token.EXPORT token
+	Statement Statement
+}
+
+func (es *ExportStatement) statementNode()       {}
+func (es *ExportStatement) TokenLiteral() string { return es.Token.Literal }
+func (es *ExportStatement) String() string       { return "export " + es.Statement.String() }
+
+// The identifier the exported statement declares
+func (es *ExportStatement) Name() *Identifier {
+	switch statement := es.Statement.(type) {
+	case *LetStatement:
+		return statement.Name
+	case *StructStatement:
+		return statement.Name
+	case *EnumStatement:
+		return statement.Name
+	}
+
+	return nil
+}
+
+// match (subject) { pattern => value, ... }
+type MatchExpression struct {
+	Token   token.Token // the token.MATCH token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+// An arm of a match, a plain expression after the => is parsed as a block holding just that expression
+type MatchArm struct {
+	Token   token.Token // the '=>' token
+	Pattern Pattern
+	Body    *BlockStatement
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, fmt.Sprintf("%s => %s", arm.Pattern.String(), arm.Body.String()))
+	}
+
+	return fmt.Sprintf("match (%s) { %s }", me.Subject.String(), strings.Join(arms, ", "))
+}
+
+// What a match arm compares the subject against
+type Pattern interface {
+	Node
+	patternNode()
+}
+
+// _ matches anything
+type WildcardPattern struct {
+	Token token.Token
+}
+
+func (wp *WildcardPattern) patternNode()         {}
+func (wp *WildcardPattern) TokenLiteral() string { return wp.Token.Literal }
+func (wp *WildcardPattern) String() string       { return "_" }
+
+// A name matches anything and binds it in the arm
+type BindingPattern struct {
+	Name *Identifier
+}
+
+func (bp *BindingPattern) patternNode()         {}
+func (bp *BindingPattern) TokenLiteral() string { return bp.Name.TokenLiteral() }
+func (bp *BindingPattern) String() string       { return bp.Name.String() }
+
+// A number, string or boolean matches values equal to it
+type LiteralPattern struct {
+	Value Expression
+}
+
+func (lp *LiteralPattern) patternNode()         {}
+func (lp *LiteralPattern) TokenLiteral() string { return lp.Value.TokenLiteral() }
+func (lp *LiteralPattern) String() string       { return lp.Value.String() }
+
+/*
+Result.Ok(v) matches an Ok variant of the Result enum whose value matches v. Without the parentheses, Result.Ok
+matches any Ok whatever its values.
+*/
+type VariantPattern struct {
+	Token     token.Token // the '.' token
+	Enum      *Identifier
+	Variant   *Identifier
+	Arguments []Pattern // nil when there are no parentheses
+}
+
+func (vp *VariantPattern) patternNode()         {}
+func (vp *VariantPattern) TokenLiteral() string { return vp.Token.Literal }
+func (vp *VariantPattern) String() string {
+	name := vp.Enum.String() + "." + vp.Variant.String()
+	if vp.Arguments == nil {
+		return name
+	}
+
+	arguments := []string{}
+	for _, argument := range vp.Arguments {
+		arguments = append(arguments, argument.String())
+	}
+
+	return fmt.Sprintf("%s(%s)", name, strings.Join(arguments, ", "))
+}
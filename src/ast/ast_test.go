@@ -2,6 +2,7 @@ package ast
 
 import (
 	"monkey/token"
+	"strings"
 	"testing"
 )
 
@@ -26,3 +27,52 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestInspect(t *testing.T) {
+	ident := func(name string) *Identifier {
+		return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+	}
+
+	// match (x) { R.A(v) => y }, after a statement that failed to parse
+	var failed *LetStatement
+	program := &Program{
+		Statements: []Statement{
+			failed,
+			&ExpressionStatement{Expression: &MatchExpression{
+				Subject: ident("x"),
+				Arms: []*MatchArm{{
+					Pattern: &VariantPattern{Enum: ident("R"), Variant: ident("A"), Arguments: []Pattern{
+						&BindingPattern{Name: ident("v")},
+					}},
+					Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("y")}}},
+				}},
+			}},
+		},
+	}
+
+	names := []string{}
+	Inspect(program, func(node Node) bool {
+		if ident, ok := node.(*Identifier); ok {
+			names = append(names, ident.Value)
+		}
+		return true
+	})
+
+	if strings.Join(names, " ") != "x R A v y" {
+		t.Errorf("wrong identifiers visited. got=%v", names)
+	}
+
+	// returning false skips the children
+	names = []string{}
+	Inspect(program, func(node Node) bool {
+		if ident, ok := node.(*Identifier); ok {
+			names = append(names, ident.Value)
+		}
+		_, ok := node.(*VariantPattern)
+		return !ok
+	})
+
+	if strings.Join(names, " ") != "x y" {
+		t.Errorf("wrong identifiers visited. got=%v", names)
+	}
+}
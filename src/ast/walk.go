@@ -0,0 +1,176 @@
+package ast
+
+/*
+Inspect walks the tree rooted at node depth first, calling f for every node before its children. When f
+returns false the children of that node are skipped. Statements that failed to parse are skipped too, so the
+tree of a program with parser errors can still be walked.
+*/
+func Inspect(node Node, f func(Node) bool) {
+	if isNil(node) || !f(node) {
+		return
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		for _, statement := range node.Statements {
+			Inspect(statement, f)
+		}
+
+	case *LetStatement:
+		Inspect(node.Name, f)
+		inspectExpression(node.Value, f)
+
+	case *ReturnStatement:
+		inspectExpression(node.ReturnValue, f)
+
+	case *ExpressionStatement:
+		inspectExpression(node.Expression, f)
+
+	case *BlockStatement:
+		for _, statement := range node.Statements {
+			Inspect(statement, f)
+		}
+
+	case *ForStatement:
+		Inspect(node.Variable, f)
+		inspectExpression(node.Iterable, f)
+		Inspect(node.Body, f)
+
+	case *StructStatement:
+		Inspect(node.Name, f)
+		for _, field := range node.Fields {
+			Inspect(field, f)
+		}
+
+	case *ImplStatement:
+		Inspect(node.Name, f)
+		for _, method := range node.Methods {
+			Inspect(method.Function, f)
+		}
+
+	case *EnumStatement:
+		Inspect(node.Name, f)
+
+	case *YieldStatement:
+		inspectExpression(node.Value, f)
+
+	case *ImportStatement:
+		Inspect(node.Path, f)
+		Inspect(node.Name, f)
+
+	case *ExportStatement:
+		Inspect(node.Statement, f)
+
+	case *PrefixExpression:
+		inspectExpression(node.Right, f)
+
+	case *InfixExpression:
+		inspectExpression(node.Left, f)
+		inspectExpression(node.Right, f)
+
+	case *AssignExpression:
+		inspectExpression(node.Target, f)
+		inspectExpression(node.Value, f)
+
+	case *IfExpression:
+		inspectExpression(node.Condition, f)
+		Inspect(node.Consequence, f)
+		for _, elseIf := range node.ElseIfs {
+			inspectExpression(elseIf.Condition, f)
+			Inspect(elseIf.Consequence, f)
+		}
+		Inspect(node.Alternative, f)
+
+	case *FunctionLiteral:
+		for _, param := range node.Parameters {
+			Inspect(param, f)
+		}
+		Inspect(node.Body, f)
+
+	case *CallExpression:
+		inspectExpression(node.Function, f)
+		for _, argument := range node.Arguments {
+			inspectExpression(argument, f)
+		}
+
+	case *InterpolatedString:
+		for _, part := range node.Parts {
+			inspectExpression(part, f)
+		}
+
+	case *ArrayLiteral:
+		for _, element := range node.Elements {
+			inspectExpression(element, f)
+		}
+
+	case *SetLiteral:
+		for _, element := range node.Elements {
+			inspectExpression(element, f)
+		}
+
+	case *IndexExpression:
+		inspectExpression(node.Left, f)
+		inspectExpression(node.Index, f)
+
+	case *SliceExpression:
+		inspectExpression(node.Left, f)
+		inspectExpression(node.Start, f)
+		inspectExpression(node.End, f)
+
+	case *MemberExpression:
+		inspectExpression(node.Object, f)
+		Inspect(node.Property, f)
+
+	case *HashLiteral:
+		for _, pair := range node.Pairs {
+			inspectExpression(pair.Key, f)
+			inspectExpression(pair.Value, f)
+		}
+
+	case *MatchExpression:
+		inspectExpression(node.Subject, f)
+		for _, arm := range node.Arms {
+			Inspect(arm.Pattern, f)
+			Inspect(arm.Body, f)
+		}
+
+	case *BindingPattern:
+		Inspect(node.Name, f)
+
+	case *LiteralPattern:
+		inspectExpression(node.Value, f)
+
+	case *VariantPattern:
+		Inspect(node.Enum, f)
+		Inspect(node.Variant, f)
+		for _, argument := range node.Arguments {
+			Inspect(argument, f)
+		}
+	}
+}
+
+func inspectExpression(expression Expression, f func(Node) bool) {
+	if expression != nil {
+		Inspect(expression, f)
+	}
+}
+
+// The parser hands back typed nil pointers for statements it couldn't parse
+func isNil(node Node) bool {
+	switch node := node.(type) {
+	case nil:
+		return true
+	case *LetStatement:
+		return node == nil
+	case *ReturnStatement:
+		return node == nil
+	case *ExpressionStatement:
+		return node == nil
+	case *BlockStatement:
+		return node == nil
+	case *Identifier:
+		return node == nil
+	}
+
+	return false
+}
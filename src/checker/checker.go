@@ -0,0 +1,249 @@
+/*
+The checker looks for mistakes in a parsed program that can be found without running it. For now that is
+match expressions over enums: a variant pattern naming a variant the enum doesn't have or with the wrong
+number of fields, and a match that doesn't handle every variant of the enum it matches on, e.g.
+
+	enum Result { Ok(value), Err(message) }
+	match (r) { Result.Ok(v) => v }  // non-exhaustive match on Result at line 2, column 1: missing Err
+
+Enums are known by name from their declarations anywhere in the program. A match is only checked when its arms
+use variant patterns of one known enum; anything else (literal patterns, enums the host defines) is left to
+the runtime, which fails with an error when no arm matches.
+
+A variant counts as handled when an arm matches it whatever its values are. Nested patterns are followed into
+variants with a single field, so Option.Some(Result.Ok(v)) and Option.Some(Result.Err(e)) together handle
+Some. With several fields each is only checked on its own, which can miss a combination no arm handles; the
+runtime error still catches that.
+*/
+package checker
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/token"
+	"strings"
+)
+
+type checker struct {
+	enums  map[string]*ast.EnumStatement
+	errors []string
+}
+
+// Returns a message for every problem found, in the order they appear in the program
+func Check(program *ast.Program) []string {
+	c := &checker{enums: make(map[string]*ast.EnumStatement), errors: []string{}}
+
+	// enums first, a function can match on an enum declared further down
+	ast.Inspect(program, func(node ast.Node) bool {
+		if enum, ok := node.(*ast.EnumStatement); ok {
+			c.enums[enum.Name.Value] = enum
+		}
+		return true
+	})
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if match, ok := node.(*ast.MatchExpression); ok {
+			c.match(match)
+		}
+		return true
+	})
+
+	return c.errors
+}
+
+func (c *checker) errorf(tok token.Token, format string, a ...interface{}) {
+	message := fmt.Sprintf(format, a...)
+	c.errors = append(c.errors, fmt.Sprintf("%s at line %d, column %d", message, tok.Line, tok.Column))
+}
+
+func (c *checker) match(match *ast.MatchExpression) {
+	patterns := make([]ast.Pattern, len(match.Arms))
+	for i, arm := range match.Arms {
+		patterns[i] = arm.Pattern
+		c.validate(arm.Pattern)
+	}
+
+	enum := c.enumOf(patterns)
+	if enum == nil {
+		return
+	}
+
+	if missing := c.missing(enum, patterns); len(missing) > 0 {
+		c.errorf(match.Token, "non-exhaustive match on %s: missing %s", enum.Name.Value, strings.Join(missing, ", "))
+	}
+}
+
+// Reports variant patterns that can never match
+func (c *checker) validate(pattern ast.Pattern) {
+	variantPattern, ok := pattern.(*ast.VariantPattern)
+	if !ok {
+		return
+	}
+
+	for _, argument := range variantPattern.Arguments {
+		c.validate(argument)
+	}
+
+	enum, ok := c.enums[variantPattern.Enum.Value]
+	if !ok {
+		return
+	}
+
+	variant := findVariant(enum, variantPattern.Variant.Value)
+	if variant == nil {
+		c.errorf(variantPattern.Token, "%s has no variant %s", enum.Name.Value, variantPattern.Variant.Value)
+		return
+	}
+
+	if variantPattern.Arguments != nil && len(variantPattern.Arguments) != len(variant.Fields) {
+		c.errorf(variantPattern.Token, "pattern %s has %d fields, %s.%s has %d", variantPattern.String(),
+			len(variantPattern.Arguments), enum.Name.Value, variant.Name.Value, len(variant.Fields))
+	}
+}
+
+// The known enum all the refutable patterns are variants of, nil if there is none or they are mixed
+func (c *checker) enumOf(patterns []ast.Pattern) *ast.EnumStatement {
+	var enum *ast.EnumStatement
+
+	for _, pattern := range patterns {
+		if irrefutable(pattern) {
+			continue
+		}
+
+		variantPattern, ok := pattern.(*ast.VariantPattern)
+		if !ok {
+			return nil
+		}
+
+		patternEnum, ok := c.enums[variantPattern.Enum.Value]
+		if !ok || (enum != nil && enum != patternEnum) {
+			return nil
+		}
+
+		enum = patternEnum
+	}
+
+	return enum
+}
+
+// The variants of enum none of patterns handle, described the way a pattern for them would be written
+func (c *checker) missing(enum *ast.EnumStatement, patterns []ast.Pattern) []string {
+	for _, pattern := range patterns {
+		if irrefutable(pattern) {
+			return nil
+		}
+	}
+
+	missing := []string{}
+
+	for _, variant := range enum.Variants {
+		// the patterns of each field, over every arm for this variant
+		var columns [][]ast.Pattern
+		handled := false
+
+		for _, pattern := range patterns {
+			variantPattern, ok := pattern.(*ast.VariantPattern)
+			if !ok || variantPattern.Variant.Value != variant.Name.Value {
+				continue
+			}
+
+			if variantPattern.Arguments == nil {
+				handled = true
+				break
+			}
+
+			if len(variantPattern.Arguments) != len(variant.Fields) {
+				continue // already reported by validate
+			}
+
+			if columns == nil {
+				columns = make([][]ast.Pattern, len(variant.Fields))
+			}
+
+			for i, argument := range variantPattern.Arguments {
+				columns[i] = append(columns[i], argument)
+			}
+		}
+
+		if handled || (columns != nil && len(variant.Fields) == 0) {
+			continue
+		}
+
+		if columns == nil {
+			missing = append(missing, variant.Name.Value)
+			continue
+		}
+
+		for i, column := range columns {
+			inner := c.enumOf(column)
+			if inner == nil {
+				if !anyIrrefutable(column) && !allVariants(column) {
+					// literal patterns only, they can't cover every value
+					missing = append(missing, describe(variant, i, "_"))
+					break
+				}
+
+				continue
+			}
+
+			if innerMissing := c.missing(inner, column); len(innerMissing) > 0 {
+				missing = append(missing, describe(variant, i, inner.Name.Value+"."+innerMissing[0]))
+				break
+			}
+		}
+	}
+
+	return missing
+}
+
+// How a pattern for variant with field i set to inner would look, e.g. Ok(Option.None)
+func describe(variant *ast.EnumVariant, i int, inner string) string {
+	fields := make([]string, len(variant.Fields))
+	for n := range fields {
+		fields[n] = "_"
+	}
+	fields[i] = inner
+
+	return fmt.Sprintf("%s(%s)", variant.Name.Value, strings.Join(fields, ", "))
+}
+
+func findVariant(enum *ast.EnumStatement, name string) *ast.EnumVariant {
+	for _, variant := range enum.Variants {
+		if variant.Name.Value == name {
+			return variant
+		}
+	}
+
+	return nil
+}
+
+// Whether the pattern matches every value
+func irrefutable(pattern ast.Pattern) bool {
+	switch pattern.(type) {
+	case *ast.WildcardPattern, *ast.BindingPattern:
+		return true
+	}
+
+	return false
+}
+
+func anyIrrefutable(patterns []ast.Pattern) bool {
+	for _, pattern := range patterns {
+		if irrefutable(pattern) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Variant patterns of enums we don't know can't be checked, so they are given the benefit of the doubt
+func allVariants(patterns []ast.Pattern) bool {
+	for _, pattern := range patterns {
+		if _, ok := pattern.(*ast.VariantPattern); !ok {
+			return false
+		}
+	}
+
+	return true
+}
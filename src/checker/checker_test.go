@@ -0,0 +1,70 @@
+package checker
+
+import (
+	"monkey/lexer"
+	"monkey/parser"
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"enum R { A(x), B }; match (R.B) { R.A(v) => v, R.B => 0 }", []string{}},
+		{"enum R { A(x), B }; match (R.B) { R.A(v) => v, _ => 0 }", []string{}},
+		{"enum R { A(x), B }; match (R.B) { R.A => 1, other => other }", []string{}},
+		{"enum R { A(x), B }; match (R.B) { R.A(v) => v }",
+			[]string{"non-exhaustive match on R: missing B at line 1, column 21"}},
+		{"enum R { A, B, C }; match (R.B) { R.B => 0 }",
+			[]string{"non-exhaustive match on R: missing A, C at line 1, column 21"}},
+		// literal patterns for a field don't cover it
+		{"enum R { A(x), B }; match (R.B) { R.A(1) => 1, R.B => 0 }",
+			[]string{"non-exhaustive match on R: missing A(_) at line 1, column 21"}},
+		{"enum R { A(x), B }; match (R.B) { R.A(1) => 1, R.A(n) => n, R.B => 0 }", []string{}},
+		// nested patterns are followed into variants with one field
+		{`
+enum Option { Some(value), None }
+enum Result { Ok(value), Err(message) }
+match (r) {
+  Result.Ok(Option.Some(v)) => v,
+  Result.Err(_) => 0,
+}`, []string{"non-exhaustive match on Result: missing Ok(Option.None) at line 4, column 1"}},
+		{`
+enum Option { Some(value), None }
+enum Result { Ok(value), Err(message) }
+match (r) {
+  Result.Ok(Option.Some(v)) => v,
+  Result.Ok(Option.None) => 0,
+  Result.Err(_) => 0,
+}`, []string{}},
+		// enums can be declared after a function matching on them, and matches are found anywhere
+		{"let f = fn(r) { if (true) { match (r) { R.A => 1 } } }; enum R { A, B }",
+			[]string{"non-exhaustive match on R: missing B at line 1, column 29"}},
+		// and inside string placeholders, at their place in the source
+		{"enum R { A, B }\nlet s = \"r is\n  ${match (r) { R.A => 1 }}\";",
+			[]string{"non-exhaustive match on R: missing B at line 3, column 5"}},
+		// patterns that can never match
+		{"enum R { A(x), B }; match (R.B) { R.C => 1, _ => 0 }",
+			[]string{"R has no variant C at line 1, column 36"}},
+		{"enum R { A(x), B }; match (R.B) { R.A(x, y) => 1, _ => 0 }",
+			[]string{"pattern R.A(x, y) has 2 fields, R.A has 1 at line 1, column 36"}},
+		// nothing to check without a known enum
+		{"match (1) { 1 => 1, 2 => 2 }", []string{}},
+		{"match (x) { Host.A => 1 }", []string{}},
+		{"enum R { A, B }; match (x) { R.A => 1, 2 => 2 }", []string{}},
+	}
+
+	for _, tc := range tests {
+		p := parser.New(lexer.New(tc.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("%s: parser errors: %v", tc.input, p.Errors())
+		}
+
+		if got := Check(program); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
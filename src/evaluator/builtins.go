@@ -0,0 +1,281 @@
+package evaluator
+
+import (
+	"math"
+	"monkey/object"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The builtins every new Interpreter starts out with
+var defaultBuiltins = map[string]*object.Builtin{
+	"len": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, expected=1", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Array:
+				return &object.Integer{Value: int64(len(arg.Elements))}
+
+			case *object.String:
+				// characters rather than bytes
+				return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+
+			case *object.Set:
+				return &object.Integer{Value: int64(arg.Len())}
+
+			case *object.Bytes:
+				return &object.Integer{Value: int64(len(arg.Value))}
+
+			default:
+				return newError("argument to `len` not supported, got %s", args[0].Type())
+			}
+		},
+	},
+	"first": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments.\nexpected=1, got=%d", len(args))
+			}
+
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError("argument to \"first\" must be an ARRAY type.\ngot %s", args[0].Type())
+			}
+
+			arr := args[0].(*object.Array)
+			if len(arr.Elements) > 0 {
+				return arr.Elements[0]
+			}
+
+			return NULL
+		},
+	},
+	"last": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments.\nexpected=1, got=%d", len(args))
+			}
+
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError("argument to \"last\" must be an ARRAY type.\ngot %s", args[0].Type())
+			}
+
+			arr := args[0].(*object.Array)
+			if len(arr.Elements) > 0 {
+				return arr.Elements[len(arr.Elements)-1]
+			}
+
+			return NULL
+		},
+	},
+	"rest": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments.\nexpected=1, got=%d", len(args))
+			}
+
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError("argument to \"rest\" must be an ARRAY type.\ngot %s", args[0].Type())
+			}
+
+			arr := args[0].(*object.Array)
+			length := len(arr.Elements)
+			if length > 0 {
+				newElements := make([]object.Object, length-1)
+				copy(newElements, arr.Elements[1:length])
+				return &object.Array{Elements: newElements}
+			}
+
+			return NULL
+		},
+	},
+	"push": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments.\nexpected=2, got=%d", len(args))
+			}
+
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError("argument to \"push\" must be an ARRAY type.\ngot %s", args[0].Type())
+			}
+
+			arr := args[0].(*object.Array)
+			value := args[1]
+
+			arr.Elements = append(arr.Elements, value)
+			return arr
+		},
+	},
+	"pop": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments.\nexpected=1, got=%d", len(args))
+			}
+
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError("argument to \"pop\" must be an ARRAY type.\ngot %s", args[0].Type())
+			}
+
+			arr := args[0].(*object.Array)
+
+			if len(arr.Elements) <= 0 {
+				return newError("cannot pop an empty array")
+			}
+
+			lastElement := arr.Elements[len(arr.Elements)-1]
+			arr.Elements = arr.Elements[:len(arr.Elements)-1]
+			return lastElement
+		},
+	},
+	"int": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments.\nexpected=1, got=%d", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				return arg
+
+			case *object.Float:
+				// truncate towards zero like a Go conversion, but refuse values that have no integer equivalent
+				if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) || arg.Value < math.MinInt64 || arg.Value >= math.MaxInt64 {
+					return newError("cannot convert %s to INTEGER", arg.Inspect())
+				}
+				return &object.Integer{Value: int64(arg.Value)}
+
+			case *object.Boolean:
+				if arg.Value {
+					return &object.Integer{Value: 1}
+				}
+				return &object.Integer{Value: 0}
+
+			case *object.String:
+				value, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 0, 64)
+				if err != nil {
+					return newError("cannot convert %q to INTEGER", arg.Value)
+				}
+				return &object.Integer{Value: value}
+
+			default:
+				return newError("argument to \"int\" not supported.\ngot %s", args[0].Type())
+			}
+		},
+	},
+	"float": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments.\nexpected=1, got=%d", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Float:
+				return arg
+
+			case *object.Integer:
+				return &object.Float{Value: float64(arg.Value)}
+
+			case *object.String:
+				value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+				if err != nil {
+					return newError("cannot convert %q to FLOAT", arg.Value)
+				}
+				return &object.Float{Value: value}
+
+			default:
+				return newError("argument to \"float\" not supported.\ngot %s", args[0].Type())
+			}
+		},
+	},
+	"abs": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments.\nexpected=1, got=%d", len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer:
+				// the smallest integer has no positive counterpart, negating it gives it back
+				if arg.Value == math.MinInt64 {
+					return newError("abs(%d) does not fit in INTEGER", arg.Value)
+				}
+
+				if arg.Value < 0 {
+					return &object.Integer{Value: -arg.Value}
+				}
+				return arg
+
+			case *object.Float:
+				return &object.Float{Value: math.Abs(arg.Value)}
+
+			default:
+				return newError("argument to \"abs\" must be INTEGER or FLOAT.\ngot %s", args[0].Type())
+			}
+		},
+	},
+	"floor": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return roundingBuiltin("floor", math.Floor, args)
+		},
+	},
+	"ceil": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return roundingBuiltin("ceil", math.Ceil, args)
+		},
+	},
+	// round(x) rounds half away from zero, round(x, digits) keeps that many decimal places
+	"round": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return roundingBuiltin("round", math.Round, args)
+			}
+
+			digits, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError("second argument to \"round\" must be INTEGER.\ngot %s", args[1].Type())
+			}
+
+			scale := math.Pow(10, float64(digits.Value))
+			return roundingBuiltin("round", func(value float64) float64 {
+				scaled := value * scale
+				switch {
+				case math.IsInf(scaled, 0) || math.IsNaN(scaled):
+					// more decimal places than the float holds, so there is nothing to round
+					return value
+				case scale == 0:
+					// rounding to a power of ten bigger than any float
+					return math.Copysign(0, value)
+				}
+
+				return math.Round(scaled) / scale
+			}, args[:1])
+		},
+	},
+	"set": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			return newSet(args)
+		},
+	},
+}
+
+// floor, ceil and round keep the type of their argument, integers are already whole so they are returned as is.
+// Use int() on the result to get an INTEGER out of a FLOAT
+func roundingBuiltin(name string, fn func(float64) float64, args []object.Object) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments.\nexpected=1, got=%d", len(args))
+	}
+
+	switch arg := args[0].(type) {
+	case *object.Integer:
+		return arg
+
+	case *object.Float:
+		return &object.Float{Value: fn(arg.Value)}
+
+	default:
+		return newError("argument to \"%s\" must be INTEGER or FLOAT.\ngot %s", name, args[0].Type())
+	}
+}
@@ -0,0 +1,378 @@
+package evaluator
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"monkey/object"
+	"unicode/utf8"
+)
+
+/*
+Builtins for binary data:
+
+	bytes(s, encoding)         the bytes s stands for, encoding is "utf-8" (the default), "hex" or "base64"
+	bytes(xs)                  bytes with the values of a collection of integers from 0 to 255
+	decode(b, encoding)        the string b holds, the other way round from bytes(s, encoding)
+	pack(format, a, b)         integers laid out the way format says, see packLayout
+	unpack(format, b)          the integers back out of b as an array
+
+decode is a method of bytes as well, b.decode("hex"), and so is len.
+*/
+func (in *Interpreter) addBytesFunctions() {
+	in.builtins["bytes"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 && len(args) != 2 {
+			return wrongArgumentCount(1, 2, len(args))
+		}
+
+		if str, ok := args[0].(*object.String); ok {
+			encoding, err := encodingArgument("bytes", args[1:])
+			if err != nil {
+				return err
+			}
+
+			return encodeString(str.Value, encoding)
+		}
+
+		if len(args) == 2 {
+			return newError("only a STRING has an encoding.\ngot %s", args[0].Type())
+		}
+
+		if b, ok := args[0].(*object.Bytes); ok {
+			return b
+		}
+
+		values, err := in.collectArgument("bytes", args[0])
+		if err != nil {
+			return err
+		}
+
+		b := make([]byte, len(values))
+		for i, value := range values {
+			integer, ok := value.(*object.Integer)
+			if !ok || integer.Value < 0 || integer.Value > math.MaxUint8 {
+				return newError("byte values must be INTEGER from 0 to 255.\ngot %s", value.Inspect())
+			}
+
+			b[i] = byte(integer.Value)
+		}
+
+		return &object.Bytes{Value: b}
+	}}
+
+	in.builtins["decode"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 && len(args) != 2 {
+			return wrongArgumentCount(1, 2, len(args))
+		}
+
+		b, ok := args[0].(*object.Bytes)
+		if !ok {
+			return newError("argument to \"decode\" must be BYTES.\ngot %s", args[0].Type())
+		}
+
+		return decodeBytes(b, args[1:])
+	}}
+
+	in.builtins["pack"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if len(args) == 0 {
+			return wrongArgumentCount(1, -1, 0)
+		}
+
+		format, err := stringArgument("pack", args[0])
+		if err != nil {
+			return err
+		}
+
+		return pack(format, args[1:])
+	}}
+
+	in.builtins["unpack"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if len(args) != 2 {
+			return wrongArgumentCount(2, 2, len(args))
+		}
+
+		format, err := stringArgument("unpack", args[0])
+		if err != nil {
+			return err
+		}
+
+		b, ok := args[1].(*object.Bytes)
+		if !ok {
+			return newError("argument to \"unpack\" must be BYTES.\ngot %s", args[1].Type())
+		}
+
+		return unpack(format, b.Value)
+	}}
+
+	in.methods[object.BYTES_OBJ] = map[string]object.Object{
+		"len": method(0, func(receiver object.Object, args []object.Object) object.Object {
+			return &object.Integer{Value: int64(len(receiver.(*object.Bytes).Value))}
+		}),
+		"decode": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return wrongArgumentCount(0, 1, len(args)-1)
+			}
+
+			return decodeBytes(args[0].(*object.Bytes), args[1:])
+		}},
+	}
+}
+
+// The encoding in args, "utf-8" when there is none
+func encodingArgument(name string, args []object.Object) (string, object.Object) {
+	if len(args) == 0 {
+		return "utf-8", nil
+	}
+
+	encoding, err := stringArgument(name, args[0])
+	if err != nil {
+		return "", err
+	}
+
+	switch encoding {
+	case "utf-8", "utf8":
+		return "utf-8", nil
+	case "hex", "base64":
+		return encoding, nil
+	}
+
+	return "", newError("unknown encoding %q, expected utf-8, hex or base64", encoding)
+}
+
+func encodeString(s string, encoding string) object.Object {
+	var b []byte
+	var err error
+
+	switch encoding {
+	case "hex":
+		b, err = hex.DecodeString(s)
+	case "base64":
+		b, err = base64.StdEncoding.DecodeString(s)
+	default:
+		b = []byte(s)
+	}
+
+	if err != nil {
+		return newError("invalid %s: %s", encoding, err)
+	}
+
+	return &object.Bytes{Value: b}
+}
+
+func decodeBytes(b *object.Bytes, args []object.Object) object.Object {
+	encoding, err := encodingArgument("decode", args)
+	if err != nil {
+		return err
+	}
+
+	switch encoding {
+	case "hex":
+		return &object.String{Value: hex.EncodeToString(b.Value)}
+	case "base64":
+		return &object.String{Value: base64.StdEncoding.EncodeToString(b.Value)}
+	}
+
+	if !utf8.Valid(b.Value) {
+		return newError("bytes are not valid utf-8")
+	}
+
+	return &object.String{Value: string(b.Value)}
+}
+
+// One integer of a pack format
+type packField struct {
+	size   int // in bytes
+	signed bool
+}
+
+func (f packField) String() string {
+	if f.signed {
+		return fmt.Sprintf("int%d", f.size*8)
+	}
+
+	return fmt.Sprintf("uint%d", f.size*8)
+}
+
+var packCodes = map[byte]packField{
+	'b': {1, true}, 'B': {1, false},
+	'h': {2, true}, 'H': {2, false},
+	'i': {4, true}, 'I': {4, false},
+	'q': {8, true}, 'Q': {8, false},
+}
+
+// The largest count a pack format may put before a code
+const maxPackCount = 1 << 20
+
+// A code of a pack format with the count before it, "4B" is four uint8 fields
+type packRun struct {
+	field packField
+	count int
+}
+
+/*
+Reads a pack format like Python's struct module does. It may start with "<" for little endian or ">" or "!" for
+big endian, which is the default. Then come the integers: b, h, i and q are signed 8, 16, 32 and 64 bit ones
+and B, H, I and Q unsigned ones. A count before a code repeats it, "4B" is the same as "BBBB". The repeats are
+kept as runs so a big count costs nothing until the values or bytes it asks for are checked.
+*/
+func packLayout(format string) (binary.ByteOrder, []packRun, object.Object) {
+	var order binary.ByteOrder = binary.BigEndian
+	rest := format
+
+	if rest != "" {
+		switch rest[0] {
+		case '<':
+			order, rest = binary.LittleEndian, rest[1:]
+		case '>', '!':
+			rest = rest[1:]
+		}
+	}
+
+	runs := []packRun{}
+	for i := 0; i < len(rest); i++ {
+		// only a missing count means one, an explicit "0B" is no fields at all like in Python
+		count, digits := 0, false
+		for ; i < len(rest) && isDigit(rest[i]); i++ {
+			digits = true
+			count = count*10 + int(rest[i]-'0')
+			if count > maxPackCount {
+				return nil, nil, newError("count in pack format %q must be at most %d", format, maxPackCount)
+			}
+		}
+
+		if i == len(rest) {
+			return nil, nil, newError("pack format %q ends in a count", format)
+		}
+
+		field, ok := packCodes[rest[i]]
+		if !ok {
+			return nil, nil, newError("unknown code %q in pack format %q", rest[i], format)
+		}
+
+		if !digits {
+			count = 1
+		}
+
+		runs = append(runs, packRun{field, count})
+	}
+
+	return order, runs, nil
+}
+
+// The fields of the runs one by one, only called once the values or bytes are known to match
+func packFields(runs []packRun) []packField {
+	fields := []packField{}
+	for _, run := range runs {
+		for i := 0; i < run.count; i++ {
+			fields = append(fields, run.field)
+		}
+	}
+
+	return fields
+}
+
+// How many values and how many bytes the runs of a pack format stand for
+func packTotals(runs []packRun) (count, size int) {
+	for _, run := range runs {
+		count += run.count
+		size += run.count * run.field.size
+	}
+
+	return count, size
+}
+
+func pack(format string, values []object.Object) object.Object {
+	order, runs, err := packLayout(format)
+	if err != nil {
+		return err
+	}
+
+	count, size := packTotals(runs)
+	if len(values) != count {
+		return newError("pack format %q needs %d values.\ngot %d", format, count, len(values))
+	}
+
+	out := make([]byte, size)
+	at := 0
+	for i, field := range packFields(runs) {
+		integer, ok := values[i].(*object.Integer)
+		if !ok {
+			return newError("values to \"pack\" must be INTEGER.\ngot %s", values[i].Type())
+		}
+
+		value := integer.Value
+		bits := field.size * 8
+
+		fits := value >= 0 && (bits == 64 || value < 1<<bits)
+		if field.signed {
+			fits = bits == 64 || value >= -(1<<(bits-1)) && value < 1<<(bits-1)
+		}
+
+		if !fits {
+			return newError("%d does not fit in %s", value, field)
+		}
+
+		switch field.size {
+		case 1:
+			out[at] = byte(value)
+		case 2:
+			order.PutUint16(out[at:], uint16(value))
+		case 4:
+			order.PutUint32(out[at:], uint32(value))
+		default:
+			order.PutUint64(out[at:], uint64(value))
+		}
+		at += field.size
+	}
+
+	return &object.Bytes{Value: out}
+}
+
+func unpack(format string, b []byte) object.Object {
+	order, runs, err := packLayout(format)
+	if err != nil {
+		return err
+	}
+
+	count, size := packTotals(runs)
+	if len(b) != size {
+		return newError("unpack format %q needs %d bytes.\ngot %d", format, size, len(b))
+	}
+
+	values := make([]object.Object, count)
+	for i, field := range packFields(runs) {
+		var value uint64
+		switch field.size {
+		case 1:
+			value = uint64(b[0])
+		case 2:
+			value = uint64(order.Uint16(b))
+		case 4:
+			value = uint64(order.Uint32(b))
+		default:
+			value = order.Uint64(b)
+		}
+		b = b[field.size:]
+
+		// a signed value is sign extended from its own width
+		bits := field.size * 8
+		result := int64(value)
+		if field.signed && bits < 64 {
+			result = int64(value<<(64-bits)) >> (64 - bits)
+		}
+
+		if !field.signed && bits == 64 && value > math.MaxInt64 {
+			return newError("%d does not fit in INTEGER", value)
+		}
+
+		values[i] = &object.Integer{Value: result}
+	}
+
+	return &object.Array{Elements: values}
+}
+
+func isDigit(ch byte) bool {
+	return '0' <= ch && ch <= '9'
+}
@@ -0,0 +1,610 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"sort"
+	"strings"
+)
+
+/*
+A function over the elements of a collection. Each one is both a builtin taking the collection as its first
+argument, map(xs, f), and a method of arrays, sets and generators, xs.map(f).
+
+The collection can be an array, a set or anything Iterable, e.g. a generator. Elements are taken from it one at a
+time, so find, findIndex, any and all stop as soon as they know the answer, even on a generator that never
+ends. The functions that build a collection always return a new array and leave their argument as it was.
+*/
+type collectionFunction struct {
+	// how many arguments it takes besides the collection, maxArgs is -1 when there is no limit
+	minArgs, maxArgs int
+
+	fn func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object
+}
+
+var collectionFunctions = map[string]collectionFunction{
+	"map": {1, 1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		mapped := []object.Object{}
+
+		return in.each(elements, func(element object.Object) object.Object {
+			result := in.applyFunction(args[0], []object.Object{element})
+			if isError(result) {
+				return result
+			}
+
+			mapped = append(mapped, result)
+			return nil
+		}, func() object.Object { return &object.Array{Elements: mapped} })
+	}},
+	"filter": {1, 1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		filtered := []object.Object{}
+
+		return in.each(elements, func(element object.Object) object.Object {
+			keep := in.applyFunction(args[0], []object.Object{element})
+			if isError(keep) {
+				return keep
+			}
+
+			if isTruthy(keep) {
+				filtered = append(filtered, element)
+			}
+			return nil
+		}, func() object.Object { return &object.Array{Elements: filtered} })
+	}},
+	// reduce(xs, f, initial), without initial the first element is used and xs mustn't be empty
+	"reduce": {1, 2, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		var accumulator object.Object
+		if len(args) == 2 {
+			accumulator = args[1]
+		}
+
+		return in.each(elements, func(element object.Object) object.Object {
+			if accumulator == nil {
+				accumulator = element
+				return nil
+			}
+
+			accumulator = in.applyFunction(args[0], []object.Object{accumulator, element})
+			if isError(accumulator) {
+				return accumulator
+			}
+			return nil
+		}, func() object.Object {
+			if accumulator == nil {
+				return newError("reduce of an empty collection with no initial value")
+			}
+
+			return accumulator
+		})
+	}},
+	// the first element f is truthy for, null when there is none
+	"find": {1, 1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		var found object.Object = NULL
+
+		return in.each(elements, func(element object.Object) object.Object {
+			matches := in.applyFunction(args[0], []object.Object{element})
+			if isError(matches) {
+				return matches
+			}
+
+			if isTruthy(matches) {
+				found = element
+				return stopIteration
+			}
+			return nil
+		}, func() object.Object { return found })
+	}},
+	// the index of the first element f is truthy for, -1 when there is none
+	"findIndex": {1, 1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		index, found := int64(0), int64(-1)
+
+		return in.each(elements, func(element object.Object) object.Object {
+			matches := in.applyFunction(args[0], []object.Object{element})
+			if isError(matches) {
+				return matches
+			}
+
+			if isTruthy(matches) {
+				found = index
+				return stopIteration
+			}
+
+			index++
+			return nil
+		}, func() object.Object { return &object.Integer{Value: found} })
+	}},
+	"any": {1, 1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		result := FALSE
+
+		return in.each(elements, func(element object.Object) object.Object {
+			matches := in.applyFunction(args[0], []object.Object{element})
+			if isError(matches) {
+				return matches
+			}
+
+			if isTruthy(matches) {
+				result = TRUE
+				return stopIteration
+			}
+			return nil
+		}, func() object.Object { return result })
+	}},
+	"all": {1, 1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		result := TRUE
+
+		return in.each(elements, func(element object.Object) object.Object {
+			matches := in.applyFunction(args[0], []object.Object{element})
+			if isError(matches) {
+				return matches
+			}
+
+			if !isTruthy(matches) {
+				result = FALSE
+				return stopIteration
+			}
+			return nil
+		}, func() object.Object { return result })
+	}},
+	/*
+		sort(xs) orders numbers by value and strings alphabetically. sort(xs, f) uses f(a, b) instead, which
+		returns a negative number when a comes before b, a positive one when it comes after, and 0 when it doesn't
+		matter. Elements f considers equal keep their order
+	*/
+	"sort": {0, 1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		sorted, err := collect(elements)
+		if err != nil {
+			return err
+		}
+
+		var failed object.Object
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if failed != nil {
+				return false
+			}
+
+			var order int
+			if len(args) == 0 {
+				order, failed = compareObjects(sorted[i], sorted[j])
+			} else {
+				order, failed = in.callComparator(args[0], sorted[i], sorted[j])
+			}
+
+			return order < 0
+		})
+
+		if failed != nil {
+			return failed
+		}
+
+		return &object.Array{Elements: sorted}
+	}},
+	"reverse": {0, 0, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		reversed, err := collect(elements)
+		if err != nil {
+			return err
+		}
+
+		for i, j := 0, len(reversed)-1; i < j; i, j = i+1, j-1 {
+			reversed[i], reversed[j] = reversed[j], reversed[i]
+		}
+
+		return &object.Array{Elements: reversed}
+	}},
+	// slice(xs, start, end) from start up to but not including end, negative indexes count from the end
+	"slice": {1, 2, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		all, err := collect(elements)
+		if err != nil {
+			return err
+		}
+
+		bounds := [2]*int64{}
+		for i, arg := range args {
+			index, ok := arg.(*object.Integer)
+			if !ok {
+				return newError("argument to \"slice\" must be INTEGER.\ngot %s", arg.Type())
+			}
+
+			bounds[i] = &index.Value
+		}
+
+		start, end := sliceBounds(bounds, len(all))
+		return &object.Array{Elements: all[start:end]}
+	}},
+	"concat": {0, -1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		concatenated, err := collect(elements)
+		if err != nil {
+			return err
+		}
+
+		for _, arg := range args {
+			more, err := in.collectArgument("concat", arg)
+			if err != nil {
+				return err
+			}
+
+			concatenated = append(concatenated, more...)
+		}
+
+		return &object.Array{Elements: concatenated}
+	}},
+	// join(xs, sep) puts sep between the elements, strings go in as they are and anything else as inspected
+	"join": {0, 1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		sep := ""
+		if len(args) == 1 {
+			var err object.Object
+			if sep, err = stringArgument("join", args[0]); err != nil {
+				return err
+			}
+		}
+
+		parts := []string{}
+
+		return in.each(elements, func(element object.Object) object.Object {
+			parts = append(parts, stringValue(element))
+			return nil
+		}, func() object.Object { return &object.String{Value: strings.Join(parts, sep)} })
+	}},
+	// flatten(xs) takes the elements of arrays in xs out of them, flatten(xs, depth) repeats that depth times
+	"flatten": {0, 1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		flattened, err := collect(elements)
+		if err != nil {
+			return err
+		}
+
+		depth := int64(1)
+		if len(args) == 1 {
+			integer, ok := args[0].(*object.Integer)
+			if !ok {
+				return newError("argument to \"flatten\" must be INTEGER.\ngot %s", args[0].Type())
+			}
+
+			depth = integer.Value
+		}
+
+		// once a pass finds no arrays the rest wouldn't change anything, so a huge depth costs nothing extra
+		for nested := true; nested && depth > 0; depth-- {
+			flattened, nested = flattenOnce(flattened)
+		}
+
+		return &object.Array{Elements: flattened}
+	}},
+	// zip(xs, ys, ...) pairs up the elements at the same index, as long as every collection has one
+	"zip": {1, -1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		first, err := collect(elements)
+		if err != nil {
+			return err
+		}
+
+		columns := [][]object.Object{first}
+		length := len(first)
+
+		for _, arg := range args {
+			column, err := in.collectArgument("zip", arg)
+			if err != nil {
+				return err
+			}
+
+			columns = append(columns, column)
+			if len(column) < length {
+				length = len(column)
+			}
+		}
+
+		zipped := make([]object.Object, length)
+		for i := range zipped {
+			row := make([]object.Object, len(columns))
+			for n, column := range columns {
+				row[n] = column[i]
+			}
+
+			zipped[i] = &object.Array{Elements: row}
+		}
+
+		return &object.Array{Elements: zipped}
+	}},
+	// the elements without the ones equal to an earlier element
+	"uniq": {0, 0, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		unique := []object.Object{}
+		seen := object.NewHash()
+
+		return in.each(elements, func(element object.Object) object.Object {
+			if object.IsHashable(element) {
+				if _, ok := seen.Get(element.(object.Hashable)); ok {
+					return nil
+				}
+
+				seen.Set(element, TRUE)
+				unique = append(unique, element)
+				return nil
+			}
+
+			for _, existing := range unique {
+				if object.Equal(existing, element) {
+					return nil
+				}
+			}
+
+			unique = append(unique, element)
+			return nil
+		}, func() object.Object { return &object.Array{Elements: unique} })
+	}},
+	// groupBy(xs, f) is a hash from each value of f to the elements it returned that value for
+	"groupBy": {1, 1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		groups := object.NewHash()
+
+		return in.each(elements, func(element object.Object) object.Object {
+			key := in.applyFunction(args[0], []object.Object{element})
+			if isError(key) {
+				return key
+			}
+
+			if !object.IsHashable(key) {
+				return newError("unusable as hash key: %s", key.Type())
+			}
+
+			group, ok := groups.Get(key.(object.Hashable))
+			if !ok {
+				group = &object.Array{Elements: []object.Object{}}
+				groups.Set(key, group)
+			}
+
+			group.(*object.Array).Elements = append(group.(*object.Array).Elements, element)
+			return nil
+		}, func() object.Object { return groups })
+	}},
+	// chunk(xs, n) splits xs into arrays of n elements, the last one holds whatever is left over
+	"chunk": {1, 1, func(in *Interpreter, elements object.Iterator, args []object.Object) object.Object {
+		size, ok := args[0].(*object.Integer)
+		if !ok {
+			return newError("argument to \"chunk\" must be INTEGER.\ngot %s", args[0].Type())
+		}
+
+		if size.Value < 1 {
+			return newError("chunk size must be positive.\ngot %d", size.Value)
+		}
+
+		chunks := []object.Object{}
+		var current []object.Object
+
+		return in.each(elements, func(element object.Object) object.Object {
+			current = append(current, element)
+			if int64(len(current)) == size.Value {
+				chunks = append(chunks, &object.Array{Elements: current})
+				current = nil
+			}
+			return nil
+		}, func() object.Object {
+			if len(current) > 0 {
+				chunks = append(chunks, &object.Array{Elements: current})
+			}
+
+			return &object.Array{Elements: chunks}
+		})
+	}},
+}
+
+// Makes every collection function a builtin, and a method of arrays and generators
+func (in *Interpreter) addCollectionFunctions() {
+	for name, function := range collectionFunctions {
+		in.builtins[name] = in.collectionBuiltin(name, function)
+
+		method := in.collectionMethod(name, function)
+		in.methods[object.ARRAY_OBJ][name] = method
+		in.methods[object.SET_OBJ][name] = method
+		in.methods[object.GENERATOR_OBJ][name] = method
+	}
+}
+
+func (in *Interpreter) collectionBuiltin(name string, function collectionFunction) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if len(args) == 0 || !function.takes(len(args)-1) {
+			// the counts include the collection
+			maxArgs := function.maxArgs
+			if maxArgs >= 0 {
+				maxArgs++
+			}
+
+			return wrongArgumentCount(function.minArgs+1, maxArgs, len(args))
+		}
+
+		elements, ok := iterateCollection(args[0])
+		if !ok {
+			return newError("argument to \"%s\" must be ARRAY or iterable.\ngot %s", name, args[0].Type())
+		}
+
+		return function.fn(in, elements, args[1:])
+	}}
+}
+
+func (in *Interpreter) collectionMethod(name string, function collectionFunction) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if !function.takes(len(args) - 1) {
+			return wrongArgumentCount(function.minArgs, function.maxArgs, len(args)-1)
+		}
+
+		elements, _ := iterateCollection(args[0])
+		return function.fn(in, elements, args[1:])
+	}}
+}
+
+func (function collectionFunction) takes(count int) bool {
+	return count >= function.minArgs && (function.maxArgs < 0 || count <= function.maxArgs)
+}
+
+func wrongArgumentCount(minArgs, maxArgs, got int) *object.Error {
+	switch {
+	case maxArgs < 0:
+		return newError("wrong number of arguments.\nexpected at least %d, got=%d", minArgs, got)
+	case minArgs == maxArgs:
+		return newError("wrong number of arguments.\nexpected=%d, got=%d", minArgs, got)
+	default:
+		return newError("wrong number of arguments.\nexpected=%d to %d, got=%d", minArgs, maxArgs, got)
+	}
+}
+
+// Arrays and Iterable objects are collections, strings and hashes aren't
+func iterateCollection(obj object.Object) (object.Iterator, bool) {
+	switch obj.(type) {
+	case *object.Array, *object.Set, *object.Bytes, object.Iterable:
+		return object.Iterate(obj)
+	}
+
+	return nil, false
+}
+
+// Returned by the function given to each to stop before the last element, it never escapes each
+var stopIteration = &object.Error{Message: "stop iteration"}
+
+/*
+Calls fn with every element in turn and then returns what done returns. When fn returns stopIteration each
+stops early and still returns what done returns, any other error it returns straight away. Errors a generator
+produces are returned as well.
+*/
+func (in *Interpreter) each(elements object.Iterator, fn func(object.Object) object.Object, done func() object.Object) object.Object {
+	for {
+		element, ok := elements.Next()
+		if !ok {
+			break
+		}
+
+		if isError(element) {
+			return element
+		}
+
+		if result := fn(element); result == stopIteration {
+			break
+		} else if result != nil {
+			return result
+		}
+	}
+
+	return done()
+}
+
+// Every element in a new slice, or the error a generator produced
+func collect(elements object.Iterator) ([]object.Object, object.Object) {
+	all := []object.Object{}
+
+	for {
+		element, ok := elements.Next()
+		if !ok {
+			return all, nil
+		}
+
+		if isError(element) {
+			return nil, element
+		}
+
+		all = append(all, element)
+	}
+}
+
+func (in *Interpreter) collectArgument(name string, arg object.Object) ([]object.Object, object.Object) {
+	elements, ok := iterateCollection(arg)
+	if !ok {
+		return nil, newError("argument to \"%s\" must be ARRAY or iterable.\ngot %s", name, arg.Type())
+	}
+
+	return collect(elements)
+}
+
+// Where index lands in a collection of length elements, negative indexes counting from the end
+func clampIndex(index int64, length int) int64 {
+	if index < 0 {
+		index += int64(length)
+	}
+
+	if index < 0 {
+		return 0
+	}
+
+	if index > int64(length) {
+		return int64(length)
+	}
+
+	return index
+}
+
+// Takes the elements of arrays out of them, nested is false when there were no arrays
+func flattenOnce(elements []object.Object) (flattened []object.Object, nested bool) {
+	flattened = []object.Object{}
+
+	for _, element := range elements {
+		if array, ok := element.(*object.Array); ok {
+			flattened = append(flattened, array.Elements...)
+			nested = true
+		} else {
+			flattened = append(flattened, element)
+		}
+	}
+
+	return flattened, nested
+}
+
+// Orders numbers by value and strings alphabetically, anything else can't be compared
+func compareObjects(a, b object.Object) (int, object.Object) {
+	if left, ok := a.(*object.String); ok {
+		if right, ok := b.(*object.String); ok {
+			return strings.Compare(left.Value, right.Value), nil
+		}
+	}
+
+	if left, ok := a.(*object.Integer); ok {
+		if right, ok := b.(*object.Integer); ok {
+			switch {
+			case left.Value < right.Value:
+				return -1, nil
+			case left.Value > right.Value:
+				return 1, nil
+			}
+
+			return 0, nil
+		}
+	}
+
+	left, leftOk := numberValue(a)
+	right, rightOk := numberValue(b)
+	if !leftOk || !rightOk {
+		return 0, newError("cannot compare %s and %s", a.Type(), b.Type())
+	}
+
+	switch {
+	case left < right:
+		return -1, nil
+	case left > right:
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+func (in *Interpreter) callComparator(comparator, a, b object.Object) (int, object.Object) {
+	result := in.applyFunction(comparator, []object.Object{a, b})
+	if isError(result) {
+		return 0, result
+	}
+
+	order, ok := numberValue(result)
+	if !ok {
+		return 0, newError("comparator must return INTEGER or FLOAT.\ngot %s", result.Type())
+	}
+
+	switch {
+	case order < 0:
+		return -1, nil
+	case order > 0:
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+func numberValue(obj object.Object) (float64, bool) {
+	switch number := obj.(type) {
+	case *object.Integer:
+		return float64(number.Value), true
+	case *object.Float:
+		return number.Value, true
+	}
+
+	return 0, false
+}
@@ -1,144 +1,286 @@
 package evaluator
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"log"
+	"io"
 	"math"
+	"math/rand"
 	"monkey/ast"
 	"monkey/object"
+	"monkey/resolver"
+	"monkey/token"
+	"os"
+	"strings"
+	"time"
 )
 
+// Aliases for the singletons in the object package, kept so existing callers keep compiling
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL  = object.NULL
+	TRUE  = object.TRUE
+	FALSE = object.FALSE
 )
 
-var builtins = map[string]*object.Builtin{
-	"len": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, expected=1", len(args))
-			}
+// Evaluates node with a fresh Interpreter using the default Options. Kept for callers that don't need
+// anything the Interpreter offers, each call gets its own builtins and limits
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New(Options{}).Eval(node, env)
+}
 
-			switch arg := args[0].(type) {
-			case *object.Array:
-				return &object.Integer{Value: int64(len(arg.Elements))}
+// Evaluates node with a fresh Interpreter, stopping with an error object as soon as one of the limits in
+// options is hit
+func EvalWithOptions(node ast.Node, env *object.Environment, options Options) object.Object {
+	return New(options).Eval(node, env)
+}
 
-			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+/*
+An Interpreter carries everything an evaluation depends on: the builtins scripts can call, the global
+environment, where output goes, the limits and the hooks. Two interpreters in the same process share nothing,
+so embedding applications can give each one its own builtins, limits and output streams.
 
-			default:
-				return newError("argument to `len` not supported, got %s", args[0].Type())
-			}
-		},
-	},
-	"first": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments.\nexpected=1, got=%d", len(args))
-			}
+An Interpreter evaluates one thing at a time, it isn't safe for concurrent use.
+*/
+type Interpreter struct {
+	builtins map[string]*object.Builtin
+	methods  map[object.ObjectType]map[string]object.Object
+	globals  *object.Environment
+	stdout   io.Writer
+	stderr   io.Writer
+	random   *rand.Rand
+	clock    func() time.Time
+	hooks    Hooks
+
+	loader  ModuleLoader
+	modules map[string]*object.Module // by id
+
+	maxDepth int
+	maxSteps int64
+	context  context.Context
+
+	// state of the evaluation in progress
+	running   int
+	depth     int
+	steps     int64
+	position  token.Token // the statement or call being evaluated, for error messages
+	importing []string    // ids of the modules being evaluated, innermost last
+}
 
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to \"first\" must be an ARRAY type.\ngot %s", args[0].Type())
-			}
+// Callbacks into the host while a script runs, e.g. for tracing or profiling. Nil hooks are skipped
+type Hooks struct {
+	// called before each node is evaluated
+	BeforeEval func(node ast.Node, env *object.Environment)
 
-			arr := args[0].(*object.Array)
-			if len(arr.Elements) > 0 {
-				return arr.Elements[0]
-			}
+	// called before each function call, builtins and tail calls included. A method call is reported as a call of
+	// the method with the receiver as its first argument
+	OnCall func(fn object.Object, args []object.Object)
+}
 
-			return NULL
-		},
-	},
-	"last": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments.\nexpected=1, got=%d", len(args))
-			}
+/*
+Configuration for an Interpreter.
 
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to \"last\" must be an ARRAY type.\ngot %s", args[0].Type())
-			}
+The limits are for running scripts we don't trust. Each limit that is hit ends the evaluation with its own
+error object saying which limit it was and where in the script it happened, e.g.
 
-			arr := args[0].(*object.Array)
-			if len(arr.Elements) > 0 {
-				return arr.Elements[len(arr.Elements)-1]
-			}
+	maximum recursion depth 1000 exceeded at line 3, column 12
+*/
+type Options struct {
+	// How many calls deep a script can go. Tail calls replace the caller so they don't count.
+	// 0 means DefaultMaxDepth, a negative number turns the limit off
+	MaxDepth int
 
-			return NULL
-		},
-	},
-	"rest": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments.\nexpected=1, got=%d", len(args))
-			}
+	// How many AST nodes an evaluation may evaluate in total, 0 means no limit
+	MaxSteps int64
 
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to \"rest\" must be an ARRAY type.\ngot %s", args[0].Type())
-			}
+	// Evaluation stops once the context is cancelled or its deadline passes, nil means never
+	Context context.Context
 
-			arr := args[0].(*object.Array)
-			length := len(arr.Elements)
-			if length > 0 {
-				newElements := make([]object.Object, length-1)
-				copy(newElements, arr.Elements[1:length])
-				return &object.Array{Elements: newElements}
-			}
+	// Where scripts write their output, os.Stdout and os.Stderr when nil
+	Stdout io.Writer
+	Stderr io.Writer
 
-			return NULL
-		},
-	},
-	"push": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 2 {
-				return newError("wrong number of arguments.\nexpected=2, got=%d", len(args))
-			}
+	// Where the random functions of the math module get their numbers, seeded from the clock when nil
+	Random rand.Source
 
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to \"push\" must be an ARRAY type.\ngot %s", args[0].Type())
-			}
+	// What the time module's now, since and until take the current time to be, time.Now when nil. Tests can
+	// freeze time by returning a fixed value
+	Clock func() time.Time
 
-			arr := args[0].(*object.Array)
-			value := args[1]
-
-			arr.Elements = append(arr.Elements, value)
-			return arr
-		},
-	},
-	"pop": &object.Builtin{
-		Fn: func(args ...object.Object) object.Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments.\nexpected=1, got=%d", len(args))
-			}
+	// Finds the modules scripts import, a DirLoader over ModulePath when nil
+	ModuleLoader ModuleLoader
 
-			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to \"pop\" must be an ARRAY type.\ngot %s", args[0].Type())
-			}
+	// Directories searched, in order, for imports that aren't found next to the importing file. Only used
+	// without a ModuleLoader
+	ModulePath []string
+
+	Hooks Hooks
+}
 
-			arr := args[0].(*object.Array)
+func New(options Options) *Interpreter {
+	in := &Interpreter{
+		builtins: make(map[string]*object.Builtin, len(defaultBuiltins)),
+		globals:  object.NewEnvironment(),
+		stdout:   options.Stdout,
+		stderr:   options.Stderr,
+		clock:    options.Clock,
+		hooks:    options.Hooks,
+
+		loader:  options.ModuleLoader,
+		modules: make(map[string]*object.Module),
+
+		maxDepth: options.MaxDepth,
+		maxSteps: options.MaxSteps,
+		context:  options.Context,
+	}
 
-			if len(arr.Elements) <= 0 {
-				return newError("cannot pop an empty array")
-			}
+	for name, builtin := range defaultBuiltins {
+		in.builtins[name] = builtin
+	}
+
+	in.methods = in.defaultMethods()
+	in.addCollectionFunctions()
+	in.addStringFunctions()
+	in.addOutputFunctions()
+	in.addBytesFunctions()
+
+	if in.stdout == nil {
+		in.stdout = os.Stdout
+	}
+
+	if in.stderr == nil {
+		in.stderr = os.Stderr
+	}
+
+	if options.Random != nil {
+		in.random = rand.New(options.Random)
+	} else {
+		in.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	if in.clock == nil {
+		in.clock = time.Now
+	}
+
+	if in.maxDepth == 0 {
+		in.maxDepth = DefaultMaxDepth
+	}
+
+	if in.loader == nil {
+		in.loader = NewDirLoader(options.ModulePath...)
+	}
+
+	return in
+}
+
+// Makes fn callable from scripts as name, replacing any builtin already registered under that name.
+// Builtins are looked up after the environment, so a script's own binding of name shadows it
+func (in *Interpreter) RegisterBuiltin(name string, fn object.BuiltinFunction) {
+	in.builtins[name] = &object.Builtin{Fn: fn}
+}
+
+// Makes fn callable as a method on every value of type typ, e.g. RegisterMethod(object.STRING_OBJ, "title", ...)
+// for "abc".title(). fn gets the receiver as its first argument
+func (in *Interpreter) RegisterMethod(typ object.ObjectType, name string, fn object.BuiltinFunction) {
+	if in.methods[typ] == nil {
+		in.methods[typ] = make(map[string]object.Object)
+	}
+
+	in.methods[typ][name] = &object.Builtin{Fn: fn}
+}
+
+// Like RegisterBuiltin, but for an ordinary Go function whose arguments and results are converted
+// automatically, see object.NewGoBuiltin for which types are supported
+func (in *Interpreter) RegisterFunc(name string, fn interface{}) error {
+	builtin, err := in.converter().NewGoBuiltin(name, fn)
+	if err != nil {
+		return err
+	}
+
+	in.builtins[name] = builtin
+	return nil
+}
+
+// Converts value into an object, see object.Converter
+func (in *Interpreter) FromGo(value interface{}) (object.Object, error) {
+	return in.converter().FromGo(value)
+}
+
+// Like object.ToGo, except that Monkey functions can be converted to Go funcs as well. Calling such a func
+// runs the function in this interpreter
+func (in *Interpreter) ToGo(obj object.Object, target interface{}) error {
+	return in.converter().ToGo(obj, target)
+}
+
+func (in *Interpreter) converter() object.Converter {
+	return object.Converter{Call: in.Call}
+}
+
+// Calls fn, a Monkey function or builtin, with args. Like Eval, it counts towards the limits of an
+// evaluation that is already running
+func (in *Interpreter) Call(fn object.Object, args ...object.Object) object.Object {
+	if in.running == 0 {
+		in.depth = 0
+		in.steps = 0
+	}
+
+	in.running++
+	result := in.applyFunction(fn, args)
+	in.running--
+
+	return result
+}
+
+// The environment Run evaluates in, it lives as long as the Interpreter
+func (in *Interpreter) Globals() *object.Environment {
+	return in.globals
+}
+
+func (in *Interpreter) Stdout() io.Writer {
+	return in.stdout
+}
+
+func (in *Interpreter) Stderr() io.Writer {
+	return in.stderr
+}
+
+// Evaluates node in the interpreter's global environment
+func (in *Interpreter) Run(node ast.Node) object.Object {
+	return in.Eval(node, in.globals)
+}
+
+// Evaluates node in env. The step limit applies to each outermost call, an Eval made from inside a builtin
+// while another one is running counts towards the running one
+func (in *Interpreter) Eval(node ast.Node, env *object.Environment) object.Object {
+	if in.running == 0 {
+		in.depth = 0
+		in.steps = 0
+	}
+
+	in.running++
+	result := in.eval(node, env)
+	in.running--
 
-			lastElement := arr.Elements[len(arr.Elements)-1]
-			arr.Elements = arr.Elements[:len(arr.Elements)-1]
-			return lastElement
-		},
-	},
+	return result
 }
 
 // We need to pass the concrete type ast.Node, for all other structs that implements ast.Node to allow the "polymorphism" to work
-func Eval(node ast.Node, env *object.Environment) object.Object {
+func (in *Interpreter) eval(node ast.Node, env *object.Environment) object.Object {
+	if err := in.step(); err != nil {
+		return err
+	}
+
+	if in.hooks.BeforeEval != nil {
+		in.hooks.BeforeEval(node, env)
+	}
 
 	switch node := node.(type) {
 	case *ast.Program:
-		return evalProgram(node.Statements, env)
+		return in.evalProgram(node.Statements, env)
 
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		in.position = node.Token
+		return in.eval(node.Expression, env)
 
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
@@ -150,7 +292,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return nativeToBooleanObject(node.Value)
 
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := in.eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
@@ -158,25 +300,30 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := in.eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
 
-		right := Eval(node.Right, env)
+		right := in.eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 		return evalInfixExpression(node.Operator, left, right)
 
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return in.evalBlockStatement(node, env)
 
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return in.evalIfExpression(node, env)
+
+	case *ast.ForStatement:
+		in.position = node.Token
+		return in.evalForStatement(node, env)
 
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		in.position = node.Token
+		val := in.eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
@@ -184,76 +331,284 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		return &object.ReturnValue{Value: val}
 
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		in.position = node.Token
+
+		// declared before the value is evaluated, so a function literal on the right can capture its own name
+		env.Declare(node.Name.Value)
+
+		val := in.eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
 
 		env.Set(node.Name.Value, val)
 
+	case *ast.StructStatement:
+		in.position = node.Token
+
+		fields := make([]string, len(node.Fields))
+		for i, field := range node.Fields {
+			fields[i] = field.Value
+		}
+
+		env.Set(node.Name.Value, &object.StructType{Name: node.Name.Value, Fields: fields})
+
+	case *ast.ImplStatement:
+		in.position = node.Token
+		return in.evalImplStatement(node, env)
+
+	case *ast.EnumStatement:
+		in.position = node.Token
+		evalEnumStatement(node, env)
+
+	case *ast.MatchExpression:
+		return in.evalMatchExpression(node, env)
+
+	case *ast.YieldStatement:
+		in.position = node.Token
+		return in.evalYieldStatement(node, env)
+
+	case *ast.ImportStatement:
+		in.position = node.Token
+		return in.evalImportStatement(node, env)
+
+	case *ast.ExportStatement:
+		return in.eval(node.Statement, env)
+
+	case *ast.AssignExpression:
+		return in.evalAssignExpression(node, env)
+
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return in.evalIdentifier(node, env)
 
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		captured := env.Capture(resolver.FreeVariables(node))
+		return &object.Function{Parameters: params, Body: body, Env: captured, Generator: node.Generator}
 
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		function := in.eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
 
-		args := evalExpression(node.Arguments, env)
+		args := in.evalExpression(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		in.position = node.Token
+		return in.applyFunction(function, args)
 
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
+	case *ast.InterpolatedString:
+		return in.evalInterpolatedString(node, env)
+
 	case *ast.ArrayLiteral:
-		elements := evalExpression(node.Elements, env)
+		elements := in.evalExpression(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
 
 		return &object.Array{Elements: elements}
 
+	case *ast.SetLiteral:
+		return in.evalSetLiteral(node, env)
+
+	case *ast.BytesLiteral:
+		return &object.Bytes{Value: node.Value}
+
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
+		left := in.eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
 
-		index := Eval(node.Index, env)
+		index := in.eval(node.Index, env)
 		if isError(index) {
 			return index
 		}
 
 		return evalIndexExpression(left, index)
 
+	case *ast.SliceExpression:
+		return in.evalSliceExpression(node, env)
+
+	case *ast.MemberExpression:
+		obj := in.eval(node.Object, env)
+		if isError(obj) {
+			return obj
+		}
+
+		return in.evalMemberExpression(obj, node.Property.Value)
+
+	case *ast.HashLiteral:
+		return in.evalHashLiteral(node, env)
+
 	}
 
 	return nil
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
-	switch fn := fn.(type) {
-	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapReturnValue(evaluated)
+// Every call that isn't a tail call counts towards the maximum recursion depth
+func (in *Interpreter) applyFunction(fn object.Object, args []object.Object) object.Object {
+	if in.maxDepth > 0 && in.depth >= in.maxDepth {
+		return newError("maximum recursion depth %d exceeded at %s", in.maxDepth, position(in.position))
+	}
 
-	case *object.Builtin:
-		return fn.Fn(args...)
+	in.depth++
+	result := in.callFunction(fn, args)
+	in.depth--
 
-	default:
-		return newError("not a function: %s", fn.Type())
+	return result
+}
+
+/*
+Calls are trampolined so recursion in tail position runs in constant Go stack. The function body is evaluated
+with evalFunctionBody, which doesn't make a call that is the last thing the function does, it hands back a
+tailCall describing it instead. We then loop around and make that call here, in place of the one that just
+finished, rather than nesting another applyFunction → Eval → evalBlockStatement round trip. The OnCall hook
+fires here, once for each function the loop calls, so it sees the tail calls as well.
+*/
+func (in *Interpreter) callFunction(fn object.Object, args []object.Object) object.Object {
+	for {
+		// a bound method only passes its receiver on to the method, which is the call the hook sees
+		if _, bound := fn.(*object.BoundMethod); !bound && in.hooks.OnCall != nil {
+			in.hooks.OnCall(fn, args)
+		}
+
+		switch function := fn.(type) {
+		case *object.Function:
+			if len(args) != len(function.Parameters) {
+				return newError("wrong number of arguments: want=%d, got=%d", len(function.Parameters), len(args))
+			}
+
+			if function.Generator {
+				return in.newGenerator(function, args)
+			}
+
+			extendedEnv := extendFunctionEnv(function, args)
+			evaluated := unwrapReturnValue(in.evalFunctionBody(function.Body, extendedEnv))
+
+			if call, ok := evaluated.(*tailCall); ok {
+				fn, args = call.function, call.arguments
+				continue
+			}
+
+			return evaluated
+
+		case *object.Builtin:
+			return function.Fn(args...)
+
+		case *object.BoundMethod:
+			fn, args = function.Method, append([]object.Object{function.Receiver}, args...)
+
+		case object.Callable:
+			return function.Call(args...)
+
+		default:
+			return newError("not a function: %s", fn.Type())
+		}
+	}
+}
+
+// A call in tail position that applyFunction still has to make, it never escapes from applyFunction
+type tailCall struct {
+	function  object.Object
+	arguments []object.Object
+}
+
+func (tc *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (tc *tailCall) Inspect() string         { return "tail call" }
+
+func (in *Interpreter) evalFunctionBody(body *ast.BlockStatement, env *object.Environment) object.Object {
+	return in.evalTailBlock(body, env, true)
+}
+
+/*
+Evaluates a block that belongs to a function body, either the body itself or the arm of an if inside it.
+
+The value of a return statement is always in tail position, it's what the function returns. The last statement
+is only in tail position when the block is (tail is true), i.e. for the body itself and for if arms that are
+themselves the last statement. An if in the middle of the body still has its return statements in tail
+position, so its arms are evaluated here as well, just with tail set to false.
+*/
+func (in *Interpreter) evalTailBlock(block *ast.BlockStatement, env *object.Environment, tail bool) object.Object {
+	var result object.Object
+
+	for i, statement := range block.Statements {
+		last := tail && i == len(block.Statements)-1
+
+		switch statement := statement.(type) {
+		case *ast.ReturnStatement:
+			in.position = statement.Token
+			val := in.evalTailExpression(statement.ReturnValue, env, true)
+			if isError(val) {
+				return val
+			}
+
+			return &object.ReturnValue{Value: val}
+
+		case *ast.ExpressionStatement:
+			in.position = statement.Token
+			result = in.evalTailExpression(statement.Expression, env, last)
+
+		default:
+			result = in.eval(statement, env)
+		}
+
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+func (in *Interpreter) evalTailExpression(expression ast.Expression, env *object.Environment, tail bool) object.Object {
+	switch node := expression.(type) {
+	case *ast.CallExpression:
+		if !tail {
+			break
+		}
+
+		function := in.eval(node.Function, env)
+		if isError(function) {
+			return function
+		}
+
+		args := in.evalExpression(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+
+		in.position = node.Token
+		return &tailCall{function: function, arguments: args}
+
+	case *ast.IfExpression:
+		branch, result := in.selectIfBranch(node, env)
+		if branch == nil {
+			return result
+		}
+
+		return in.evalTailBlock(branch, env, tail)
+
+	case *ast.MatchExpression:
+		body, armEnv, result := in.selectMatchArm(node, env)
+		if body == nil {
+			return result
+		}
+
+		return in.evalTailBlock(body, armEnv, tail)
 	}
+
+	return in.eval(expression, env)
 }
 
 func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
@@ -274,11 +629,40 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
+// Every part is shown the way Inspect shows it, so strings go in without quotes and numbers need no conversion
+func (in *Interpreter) evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+
+	for _, part := range node.Parts {
+		value := in.eval(part, env)
+		if isError(value) {
+			return value
+		}
+
+		out.WriteString(value.Inspect())
+	}
+
+	return &object.String{Value: out.String()}
+}
+
 func evalIndexExpression(left, index object.Object) object.Object {
+	if indexable, ok := left.(object.Indexable); ok {
+		return indexable.Index(index)
+	}
+
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrayIndexExpression(left, index)
 
+	case left.Type() == object.STRING_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalStringIndexExpression(left, index)
+
+	case left.Type() == object.BYTES_OBJ && index.Type() == object.INTEGER_OBJ:
+		return evalBytesIndexExpression(left, index)
+
+	case left.Type() == object.HASH_OBJ:
+		return evalHashIndexExpression(left, index)
+
 	default:
 		return newError("index operator not supported: %s", left.Type())
 	}
@@ -296,11 +680,144 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[idx]
 }
 
-func evalExpression(expression []ast.Expression, env *object.Environment) []object.Object {
+// Strings are indexed by character rather than byte, s[i] is a string holding the i-th one
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	runes := []rune(str.(*object.String).Value)
+	idx := index.(*object.Integer).Value
+
+	if idx < 0 || idx >= int64(len(runes)) {
+		return NULL
+	}
+
+	return &object.String{Value: string(runes[idx])}
+}
+
+// b[i] is the i-th byte as an INTEGER from 0 to 255
+func evalBytesIndexExpression(b, index object.Object) object.Object {
+	value := b.(*object.Bytes).Value
+	idx := index.(*object.Integer).Value
+
+	if idx < 0 || idx >= int64(len(value)) {
+		return NULL
+	}
+
+	return &object.Integer{Value: int64(value[idx])}
+}
+
+/*
+Slices arrays, strings and bytes, strings by character. Like the slice builtin, negative bounds count from the end and
+bounds past either end are clamped, so a slice is never out of range and only ever comes out empty.
+*/
+func (in *Interpreter) evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := in.eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	bounds := [2]*int64{}
+	for i, bound := range []ast.Expression{node.Start, node.End} {
+		if bound == nil {
+			continue
+		}
+
+		value := in.eval(bound, env)
+		if isError(value) {
+			return value
+		}
+
+		integer, ok := value.(*object.Integer)
+		if !ok {
+			return newError("slice bound must be INTEGER.\ngot %s", value.Type())
+		}
+		bounds[i] = &integer.Value
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		start, end := sliceBounds(bounds, len(left.Elements))
+
+		elements := make([]object.Object, end-start)
+		copy(elements, left.Elements[start:end])
+		return &object.Array{Elements: elements}
+
+	case *object.String:
+		runes := []rune(left.Value)
+		start, end := sliceBounds(bounds, len(runes))
+
+		return &object.String{Value: string(runes[start:end])}
+
+	case *object.Bytes:
+		start, end := sliceBounds(bounds, len(left.Value))
+
+		return &object.Bytes{Value: bytes.Clone(left.Value[start:end])}
+
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+}
+
+// The start and end of a slice of a collection of length elements, missing bounds being either end of it
+func sliceBounds(bounds [2]*int64, length int) (int64, int64) {
+	start, end := int64(0), int64(length)
+
+	if bounds[0] != nil {
+		start = clampIndex(*bounds[0], length)
+	}
+	if bounds[1] != nil {
+		end = clampIndex(*bounds[1], length)
+	}
+
+	if start > end {
+		return start, start
+	}
+
+	return start, end
+}
+
+func evalHashIndexExpression(hash, index object.Object) object.Object {
+	hashObject := hash.(*object.Hash)
+
+	if !object.IsHashable(index) {
+		return newError("unusable as hash key: %s", index.Type())
+	}
+
+	value, ok := hashObject.Get(index.(object.Hashable))
+	if !ok {
+		return NULL
+	}
+
+	return value
+}
+
+func (in *Interpreter) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+
+	for _, pair := range node.Pairs {
+		key := in.eval(pair.Key, env)
+		if isError(key) {
+			return key
+		}
+
+		if !object.IsHashable(key) {
+			return newError("unusable as hash key: %s", key.Type())
+		}
+
+		value := in.eval(pair.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		hash.Set(key, value)
+	}
+
+	return hash
+}
+
+func (in *Interpreter) evalExpression(expression []ast.Expression, env *object.Environment) []object.Object {
 	var result []object.Object
 
 	for _, e := range expression {
-		evaluated := Eval(e, env)
+		evaluated := in.eval(e, env)
 		if isError(evaluated) {
 			return []object.Object{evaluated}
 		}
@@ -310,12 +827,73 @@ func evalExpression(expression []ast.Expression, env *object.Environment) []obje
 	return result
 }
 
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+func (in *Interpreter) evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		val := in.eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+
+		if !env.Assign(target.Value, val) {
+			return newError("cannot assign to undeclared identifier: %s", target.Value)
+		}
+
+		return val
+
+	case *ast.MemberExpression:
+		obj := in.eval(target.Object, env)
+		if isError(obj) {
+			return obj
+		}
+
+		val := in.eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+
+		setter, ok := obj.(object.AttrSetter)
+		if !ok {
+			return newError("cannot set attribute %s on %s", target.Property.Value, obj.Type())
+		}
+
+		if err := setter.SetAttr(target.Property.Value, val); err != nil {
+			return newError("%s", err)
+		}
+
+		return val
+	}
+
+	return newError("cannot assign to %s", node.Target.String())
+}
+
+// Fields of an instance win over methods of the same name, methods win over attributes of host types
+func (in *Interpreter) evalMemberExpression(obj object.Object, name string) object.Object {
+	if instance, ok := obj.(*object.Instance); ok {
+		if index := instance.Struct.FieldIndex(name); index >= 0 {
+			return instance.Fields[index]
+		}
+	}
+
+	if method, ok := in.lookupMethod(obj, name); ok {
+		return &object.BoundMethod{Receiver: obj, Name: name, Method: method}
+	}
+
+	if getter, ok := obj.(object.AttrGetter); ok {
+		if value, ok := getter.GetAttr(name); ok {
+			return value
+		}
+	}
+
+	return newError("%s has no attribute %s", obj.Type(), name)
+}
+
+func (in *Interpreter) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
 
-	if builtin, ok := builtins[node.Value]; ok {
+	if builtin, ok := in.builtins[node.Value]; ok {
 		return builtin
 	}
 
@@ -338,36 +916,43 @@ if (5 < 10) {return "true"}
 
 In our code, we will return NULL
 */
-func evalIfExpression(ife *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(ife.Condition, env)
+func (in *Interpreter) evalIfExpression(ife *ast.IfExpression, env *object.Environment) object.Object {
+	branch, result := in.selectIfBranch(ife, env)
+	if branch == nil {
+		return result
+	}
+
+	return in.eval(branch, env)
+}
+
+// Works out which arm of an if runs. When no arm does, or a condition errors, the block is nil and the object
+// is the NULL or error the whole if evaluates to
+func (in *Interpreter) selectIfBranch(ife *ast.IfExpression, env *object.Environment) (*ast.BlockStatement, object.Object) {
+	condition := in.eval(ife.Condition, env)
 	if isError(condition) {
-		return condition
+		return nil, condition
 	}
 
 	if isTruthy(condition) {
-		return Eval(ife.Consequence, env)
-	} else if len(ife.ElseIfs) > 0 {
-		for i := 0; i < len(ife.ElseIfs); i++ {
-			eife := ife.ElseIfs[i]
-			condition := Eval(eife.Condition, env)
-
-			if isTruthy(condition) {
-				return Eval(eife.Consequence, env)
-			}
+		return ife.Consequence, nil
+	}
+
+	for _, eife := range ife.ElseIfs {
+		condition := in.eval(eife.Condition, env)
+		if isError(condition) {
+			return nil, condition
 		}
 
-		if ife.Alternative != nil {
-			return Eval(ife.Alternative, env)
-		} else {
-			log.Println("we're in")
-			return NULL
+		if isTruthy(condition) {
+			return eife.Consequence, nil
 		}
+	}
 
-	} else if ife.Alternative != nil {
-		return Eval(ife.Alternative, env)
-	} else {
-		return NULL
+	if ife.Alternative != nil {
+		return ife.Alternative, nil
 	}
+
+	return nil, NULL
 }
 
 func isTruthy(condition object.Object) bool {
@@ -385,8 +970,23 @@ func isTruthy(condition object.Object) bool {
 }
 
 func evalInfixExpression(operator string, left, right object.Object) object.Object {
+	// host types get the first say, see object.BinaryOperand
+	if operand, ok := left.(object.BinaryOperand); ok {
+		if result, ok := operand.BinaryOp(operator, right, false); ok {
+			return result
+		}
+	}
+
+	if operand, ok := right.(object.BinaryOperand); ok {
+		if result, ok := operand.BinaryOp(operator, left, true); ok {
+			return result
+		}
+	}
 
 	switch {
+	case operator == "in":
+		return evalInExpression(left, right)
+
 	// integer
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 		return evalIntegerInfixExpression(operator, left, right)
@@ -405,10 +1005,20 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 
 		return evalStringInfixExpression(operator, left, right)
 
+	case left.Type() == object.SET_OBJ && right.Type() == object.SET_OBJ:
+		return evalSetInfixExpression(operator, left, right)
+
+	case isTimeOrDuration(left) || isTimeOrDuration(right):
+		return evalTimeInfixExpression(operator, left, right)
+
+	case left.Type() == object.BYTES_OBJ && right.Type() == object.BYTES_OBJ && operator == "+":
+		return &object.Bytes{Value: append(bytes.Clone(left.(*object.Bytes).Value), right.(*object.Bytes).Value...)}
+
+	// everything else compares structurally, see object.Equal
 	case operator == "==":
-		return nativeToBooleanObject(left == right)
+		return nativeToBooleanObject(object.Equal(left, right))
 	case operator == "!=":
-		return nativeToBooleanObject(left != right)
+		return nativeToBooleanObject(!object.Equal(left, right))
 
 	case left.Type() != right.Type():
 		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
@@ -425,7 +1035,17 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 
 	switch operator {
 	case "==":
-		return &object.Boolean{Value: leftVal == rightVal}
+		return nativeToBooleanObject(leftVal == rightVal)
+
+	case "!=":
+		return nativeToBooleanObject(leftVal != rightVal)
+
+	// lexical ordering, byte-wise so it agrees with Go's string comparison
+	case "<":
+		return nativeToBooleanObject(leftVal < rightVal)
+
+	case ">":
+		return nativeToBooleanObject(leftVal > rightVal)
 
 	case "+":
 		return &object.String{Value: leftVal + rightVal}
@@ -436,45 +1056,26 @@ func evalStringInfixExpression(operator string, left, right object.Object) objec
 
 }
 
+// Mixed arithmetic promotes the integer operand to a float, keeping whichever side it was on, so 1 - 0.5 and
+// 0.5 - 1 both mean what they say. == and != compare exactly instead, the way hash keys do, so 1 == 1.0 is true
+// but an integer too big for a float isn't equal to the float it rounds to
 func evalFloatIntegerInfixExpression(operator string, left, right object.Object) object.Object {
-	var _integerValue int64
-	var _floatValue float64
-
-	_, leftFloatErr := left.(*object.Float)
-	if !leftFloatErr {
-		_integerValue = left.(*object.Integer).Value
-		_floatValue = right.(*object.Float).Value
-	}
-
-	_, rightFloatErr := right.(*object.Float)
-	if !rightFloatErr {
-		_floatValue = left.(*object.Float).Value
-		_integerValue = right.(*object.Integer).Value
-	}
-
 	switch operator {
-	case "+":
-		return &object.Float{Value: _floatValue + float64(_integerValue)}
-	case "-":
-		return &object.Float{Value: _floatValue - float64(_integerValue)}
-	case "*":
-		return &object.Float{Value: _floatValue * float64(_integerValue)}
-	case "/":
-		return &object.Float{Value: _floatValue / float64(_integerValue)}
-	case "%":
-		return &object.Float{Value: math.Mod(_floatValue, float64(_integerValue))}
-	case "<":
-		return nativeToBooleanObject(_floatValue < float64(_integerValue))
-	case ">":
-		return nativeToBooleanObject(_floatValue > float64(_integerValue))
 	case "==":
-		return nativeToBooleanObject(_floatValue == float64(_integerValue))
+		return nativeToBooleanObject(object.Equal(left, right))
 	case "!=":
-		return nativeToBooleanObject(_floatValue != float64(_integerValue))
-	default:
-		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return nativeToBooleanObject(!object.Equal(left, right))
 	}
 
+	return evalFloatInfixExpression(operator, toFloat(left), toFloat(right))
+}
+
+func toFloat(number object.Object) *object.Float {
+	if integer, ok := number.(*object.Integer); ok {
+		return &object.Float{Value: float64(integer.Value)}
+	}
+
+	return number.(*object.Float)
 }
 
 func evalFloatInfixExpression(operator string, left, right object.Object) object.Object {
@@ -492,6 +1093,8 @@ func evalFloatInfixExpression(operator string, left, right object.Object) object
 		return &object.Float{Value: leftVal / rightVal}
 	case "%":
 		return &object.Float{Value: math.Mod(leftVal, rightVal)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftVal, rightVal)}
 	case "<":
 		return nativeToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -518,9 +1121,31 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return newError("division by zero: %d / %d", leftVal, rightVal)
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	case "%":
+		if rightVal == 0 {
+			return newError("division by zero: %d %% %d", leftVal, rightVal)
+		}
 		return &object.Integer{Value: leftVal % rightVal}
+	case "**":
+		return integerPower(leftVal, rightVal)
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<", ">>":
+		if rightVal < 0 {
+			return newError("negative shift count: %d %s %d", leftVal, operator, rightVal)
+		}
+		if operator == "<<" {
+			return &object.Integer{Value: leftVal << rightVal}
+		}
+		return &object.Integer{Value: leftVal >> rightVal}
 	case "<":
 		return nativeToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -534,12 +1159,35 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 	}
 }
 
+// An integer raised to a negative power is a fraction, so that one is a FLOAT. Overflow wraps like * does
+func integerPower(base, exponent int64) object.Object {
+	if exponent < 0 {
+		return &object.Float{Value: math.Pow(float64(base), float64(exponent))}
+	}
+
+	result := int64(1)
+	for exponent > 0 {
+		if exponent&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exponent >>= 1
+	}
+
+	return &object.Integer{Value: result}
+}
+
 func evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperator(right)
+	case "~":
+		if integer, ok := right.(*object.Integer); ok {
+			return &object.Integer{Value: ^integer.Value}
+		}
+		return newError("unknown operator: ~%s", right.Type())
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
@@ -553,6 +1201,9 @@ func evalMinusPrefixOperator(right object.Object) object.Object {
 	case object.FLOAT_OBJ:
 		value := right.(*object.Float).Value
 		return &object.Float{Value: -value}
+	case object.DURATION_OBJ:
+		value := right.(*object.Duration).Value
+		return &object.Duration{Value: -value}
 	default:
 		return newError("unknown operator: -%s", right.Type())
 	}
@@ -599,11 +1250,11 @@ func nativeToBooleanObject(value bool) *object.Boolean {
 	return FALSE
 }
 
-func evalProgram(statements []ast.Statement, env *object.Environment) object.Object {
+func (in *Interpreter) evalProgram(statements []ast.Statement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range statements {
-		result = Eval(statement, env)
+		result = in.eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.ReturnValue:
@@ -616,11 +1267,11 @@ func evalProgram(statements []ast.Statement, env *object.Environment) object.Obj
 	return result
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+func (in *Interpreter) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement, env)
+		result = in.eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
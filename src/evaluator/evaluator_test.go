@@ -4,7 +4,10 @@ import (
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"runtime"
+	"runtime/debug"
 	"testing"
+	"time"
 )
 
 type ExpectedTest[T any] struct {
@@ -91,6 +94,7 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("héllo wörld")`, 11},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, expected=1"},
 
@@ -194,6 +198,304 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestNumericBuiltins(t *testing.T) {
+	tests := []ExpectedTest[interface{}]{
+		{`int(3)`, 3},
+		{`int(3.9)`, 3},
+		{`int(-3.9)`, -3},
+		{`int("42")`, 42},
+		{`int(" 0x10 ")`, 16},
+		{`int(true)`, 1},
+		{`int("4.2")`, "cannot convert \"4.2\" to INTEGER"},
+		{`int(float("NaN"))`, "cannot convert NaN to INTEGER"},
+		{`int(float("1e300"))`, "cannot convert 1e+300 to INTEGER"},
+		{`int([])`, "argument to \"int\" not supported.\ngot ARRAY"},
+		{`int()`, "wrong number of arguments.\nexpected=1, got=0"},
+
+		{`float(2)`, 2.0},
+		{`float(2.5)`, 2.5},
+		{`float("0.25")`, 0.25},
+		{`float("abc")`, "cannot convert \"abc\" to FLOAT"},
+
+		{`abs(-3)`, 3},
+		{`abs(3)`, 3},
+		{`abs(-2.5)`, 2.5},
+		{`abs("a")`, "argument to \"abs\" must be INTEGER or FLOAT.\ngot STRING"},
+		{`abs(-9223372036854775807 - 1)`, "abs(-9223372036854775808) does not fit in INTEGER"},
+		{`abs(-9223372036854775807)`, 9223372036854775807},
+
+		{`floor(2.7)`, 2.0},
+		{`floor(-2.2)`, -3.0},
+		{`floor(4)`, 4},
+		{`ceil(2.2)`, 3.0},
+		{`ceil(-2.7)`, -2.0},
+		{`round(2.5)`, 3.0},
+		{`round(-2.5)`, -3.0},
+		{`round(2.4)`, 2.0},
+		{`round(3.14159, 2)`, 3.14},
+		{`round(1234.5, -2)`, 1200.0},
+		// digits past what a float holds leave it as it is, rather than making NaN
+		{`round(3.14159, 400)`, 3.14159},
+		{`round(1.5 * 10.0 ** 300, 10) == 1.5 * 10.0 ** 300`, true},
+		{`round(3.14159, 9223372036854775807)`, 3.14159},
+		{`round(3.14159, -400)`, 0.0},
+		{`round(7)`, 7},
+		{`round(2.5, "a")`, "second argument to \"round\" must be INTEGER.\ngot STRING"},
+		{`ceil(true)`, "argument to \"ceil\" must be INTEGER or FLOAT.\ngot BOOLEAN"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestPowerAndBitOperators(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{"2 ** 10", "1024"},
+		{"2 ** 3 ** 2", "512"},
+		{"-2 ** 2", "-4"},
+		{"(-2) ** 3", "-8"},
+		{"5 ** 0", "1"},
+		{"2 ** -1", "0.5"},
+		{"2.0 ** 3", "8.0"},
+		{"4 ** 0.5", "2.0"},
+		{"6 & 3", "2"},
+		{"6 | 3", "7"},
+		{"6 ^ 3", "5"},
+		{"~5", "-6"},
+		{"1 << 10", "1024"},
+		{"-16 >> 2", "-4"},
+		{"1 << 2 + 1", "8"},
+		{"5 & 1 == 1", "true"},
+		{"1 << -1", "ERROR negative shift count: 1 << -1"},
+		{"1.5 & 1", "ERROR unknown operator: FLOAT & FLOAT"},
+		{"~1.5", "ERROR unknown operator: ~FLOAT"},
+		{`"a" ** 2`, "ERROR type mismatch: STRING ** INTEGER"},
+	}
+
+	for _, tc := range tests {
+		if got := testEval(tc.input).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestSets(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{"#{3, 1, 2, 1}", "#{3, 1, 2}"},
+		{"#{}", "#{}"},
+		{`#{1, 1.0, "1", true}`, "#{1, 1, true}"},
+		{"let s = #{1, 2}; s.add(3).add(1); s", "#{1, 2, 3}"},
+		{"let s = #{1, 2, 3}; s.remove(2).remove(9); s", "#{1, 3}"},
+		{"#{1, 2}.len() + len(#{1})", "3"},
+		{"#{1, 2}.has(2)", "true"},
+		{"set([1, 2, 2, 3])", "#{1, 2, 3}"},
+		{`set("abca".chars())`, "#{a, b, c}"},
+		{"set()", "#{}"},
+
+		// algebra
+		{"#{1, 2} | #{2, 3}", "#{1, 2, 3}"},
+		{"#{1, 2, 3} & #{3, 2, 5}", "#{2, 3}"},
+		{"#{1, 2, 3} - #{2}", "#{1, 3}"},
+		{"#{1, 2, 3} ^ #{3, 4}", "#{1, 2, 4}"},
+		{"let a = #{1}; let b = a | #{2}; a", "#{1}"},
+		{"#{1, 2}.union([3])", "#{1, 2, 3}"},
+		{"#{1, 2}.intersection(#{2})", "#{2}"},
+		{"#{1, 2}.difference([1])", "#{2}"},
+		{"#{1, 2}.symmetricDifference(#{2, 3})", "#{1, 3}"},
+		{"#{1}.isSubset(#{1, 2})", "true"},
+		{"#{1, 3}.isSubset([1, 2])", "false"},
+		{"#{1, 2}.isSuperset(#{2})", "true"},
+		{"let a = #{1}; let b = a.copy(); b.add(2); a", "#{1}"},
+		{"#{1, 2} == #{2, 1}", "true"},
+		{"#{1, 2} != #{1}", "true"},
+		{"#{1} == [1]", "false"},
+
+		// in
+		{"2 in #{1, 2}", "true"},
+		{"2.0 in #{1, 2}", "true"},
+		{"3 in #{1, 2}", "false"},
+		{`"a" in {"a": 1}`, "true"},
+		{"1 in {\"a\": 1}", "false"},
+		{"[1] in [[1], [2]]", "true"},
+		{"4 in [1, 2]", "false"},
+		{`"ell" in "hello"`, "true"},
+		{"!(1 in #{1})", "false"},
+		{"let gen = fn*() { yield 1; yield 2 }; 2 in gen()", "true"},
+
+		// iteration and collection functions
+		{"let xs = []; for (x in #{3, 1, 2}) { push(xs, x) }; xs", "[3, 1, 2]"},
+		{"#{1, 2, 3}.map(fn(x) { x * 10 })", "[10, 20, 30]"},
+		{"#{1, 2, 3}.filter(fn(x) { x > 1 })", "[2, 3]"},
+		{"set(#{1, 2}.map(fn(x) { x % 2 }))", "#{1, 0}"},
+
+		{"#{[1]}", "ERROR unusable as set element: ARRAY"},
+		{"#{1}.add({})", "ERROR unusable as set element: HASH"},
+		{"[1] in #{1}", "ERROR unusable as set element: ARRAY"},
+		{"[1] in {}", "ERROR unusable as hash key: ARRAY"},
+		{`1 in "abc"`, "ERROR type mismatch: INTEGER in STRING"},
+		{"1 in 2", "ERROR unknown operator: INTEGER in INTEGER"},
+		{"#{1} + #{2}", "ERROR unknown operator: SET + SET"},
+		{"#{1} | [2]", "ERROR type mismatch: SET | ARRAY"},
+		{"#{1}.union(2)", "ERROR argument to \"union\" must be SET or iterable.\ngot INTEGER"},
+		{"set(1)", "ERROR argument to \"set\" must be ARRAY or iterable.\ngot INTEGER"},
+		{"set([[1]])", "ERROR unusable as set element: ARRAY"},
+	}
+
+	for _, tc := range tests {
+		if got := testEval(tc.input).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestBytes(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{`b"\x00\xffab\n"`, `b"\x00\xffab\n"`},
+		{`b""`, `b""`},
+		{`b"é"`, `b"\xc3\xa9"`},
+		{`b"\x01\x02\x03"[1]`, "2"},
+		{`b"\xff"[0]`, "255"},
+		{`b"ab"[2]`, "null"},
+		{`b"abcd"[1:3]`, `b"bc"`},
+		{`b"abcd"[-2:]`, `b"cd"`},
+		{`b"ab" + b"\x00"`, `b"ab\x00"`},
+		{`len(b"\x00\x01") + b"abc".len()`, "5"},
+		{`b"ab" == b"ab"`, "true"},
+		{`b"ab" == "ab"`, "false"},
+		{`{b"k": 1}[b"k"]`, "1"},
+		{`let xs = []; for (x in b"\x01\x02") { push(xs, x) }; xs`, "[1, 2]"},
+		{`import "math" as math; math.sum(b"\x01\x02\xff")`, "258"},
+		{`2 in b"\x01\x02"`, "true"},
+
+		// conversions
+		{`bytes("héllo")`, `b"h\xc3\xa9llo"`},
+		{`bytes("00ff10", "hex")`, `b"\x00\xff\x10"`},
+		{`bytes("aGk=", "base64")`, `b"hi"`},
+		{`bytes([104, 105])`, `b"hi"`},
+		{`bytes(b"x")`, `b"x"`},
+		{`decode(b"h\xc3\xa9")`, "hé"},
+		{`b"\x00\xff".decode("hex")`, "00ff"},
+		{`b"hi".decode("base64")`, "aGk="},
+		{`bytes(bytes("a1b2", "hex").decode("hex"), "hex").decode("hex")`, "a1b2"},
+
+		// pack and unpack
+		{`pack(">HI", 1, 2)`, `b"\x00\x01\x00\x00\x00\x02"`},
+		{`pack("<HI", 1, 2)`, `b"\x01\x00\x02\x00\x00\x00"`},
+		{`pack("2b", -1, 127)`, `b"\xff\x7f"`},
+		{`pack("q", -2)`, `b"\xff\xff\xff\xff\xff\xff\xff\xfe"`},
+		{`pack("")`, `b""`},
+		{`unpack(">HI", b"\x00\x01\x00\x00\x00\x02")`, "[1, 2]"},
+		{`unpack("<h", b"\xfe\xff")`, "[-2]"},
+		{`unpack("4B", b"\x01\x02\x03\xff")`, "[1, 2, 3, 255]"},
+		{`unpack("<iQ", pack("<iQ", -5, 9007199254740993))`, "[-5, 9007199254740993]"},
+		// a count of zero is no fields
+		{`unpack("0B", b"")`, "[]"},
+		{`pack("0BH0Q", 1)`, `b"\x00\x01"`},
+		{`unpack("B0H", b"\x07")`, "[7]"},
+
+		{`b"a" + "b"`, "ERROR type mismatch: BYTES + STRING"},
+		{`b"a" - b"b"`, "ERROR unknown operator: BYTES - BYTES"},
+		{`bytes("zz", "hex")`, "ERROR invalid hex: encoding/hex: invalid byte: U+007A 'z'"},
+		{`bytes("!", "base64")`, "ERROR invalid base64: illegal base64 data at input byte 0"},
+		{`bytes("a", "latin-1")`, `ERROR unknown encoding "latin-1", expected utf-8, hex or base64`},
+		{`bytes([256])`, "ERROR byte values must be INTEGER from 0 to 255.\ngot 256"},
+		{`bytes([1], "hex")`, "ERROR only a STRING has an encoding.\ngot ARRAY"},
+		{`decode(b"\xff")`, "ERROR bytes are not valid utf-8"},
+		{`decode("a")`, "ERROR argument to \"decode\" must be BYTES.\ngot STRING"},
+		{`pack("B", 256)`, "ERROR 256 does not fit in uint8"},
+		{`pack("h", 40000)`, "ERROR 40000 does not fit in int16"},
+		{`pack("I", -1)`, "ERROR -1 does not fit in uint32"},
+		{`pack("H", 1, 2)`, "ERROR pack format \"H\" needs 1 values.\ngot 2"},
+		{`pack("x", 1)`, "ERROR unknown code 'x' in pack format \"x\""},
+		{`pack("2", 1)`, "ERROR pack format \"2\" ends in a count"},
+		{`pack("99999999999999999999B", 1)`, "ERROR count in pack format \"99999999999999999999B\" must be at most 1048576"},
+		{`unpack("1048577B", b"")`, "ERROR count in pack format \"1048577B\" must be at most 1048576"},
+		{`pack("1048576Q", 1)`, "ERROR pack format \"1048576Q\" needs 1048576 values.\ngot 1"},
+		{`unpack("1048576Q1048576Q", b"\x00")`, "ERROR unpack format \"1048576Q1048576Q\" needs 16777216 bytes.\ngot 1"},
+		{`pack("B", "a")`, "ERROR values to \"pack\" must be INTEGER.\ngot STRING"},
+		{`unpack("I", b"\x00")`, "ERROR unpack format \"I\" needs 4 bytes.\ngot 1"},
+		{`unpack("Q", b"\xff\xff\xff\xff\xff\xff\xff\xff")`, "ERROR 18446744073709551615 does not fit in INTEGER"},
+	}
+
+	for _, tc := range tests {
+		if got := testEval(tc.input).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestMathModule(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{"m.sqrt(16)", "4.0"},
+		{"m.pow(2, 8)", "256"},
+		{"m.pow(2, 0.5) == m.sqrt(2)", "true"},
+		{"m.exp(0)", "1.0"},
+		{"m.log(m.E)", "1.0"},
+		{"m.log(8, 2)", "3.0"},
+		{"m.log10(1000)", "3.0"},
+		{"m.sin(0) + m.cos(0)", "1.0"},
+		{"m.round(m.atan2(1, 1) * 4, 5)", "3.14159"},
+		{"m.PI", "3.141592653589793"},
+		{"m.INF > 1000000", "true"},
+		{"-m.INF", "-Inf"},
+		{"m.NaN", "NaN"},
+		{"m.min(3, 1.5, 2)", "1.5"},
+		{"m.max([3, 7, 2])", "7"},
+		{`m.max("pear", "apple")`, "pear"},
+		{"m.sum([1, 2, 3])", "6"},
+		{"m.sum([1, 2.5])", "3.5"},
+		{"m.sum([])", "0"},
+		{"m.mean([1, 2, 3, 4])", "2.5"},
+		{"m.median([5, 1, 3])", "3"},
+		{"m.median([4, 1, 3, 2])", "2.5"},
+		{"let g = fn*() { yield 2; yield 4 }; m.sum(g())", "6"},
+		{"m.clamp(15, 0, 10)", "10"},
+		{"m.clamp(-1, 0, 10)", "0"},
+		{"m.clamp(2.5, 0, 10)", "2.5"},
+		{"m.abs(-3)", "3"},
+
+		{`m.sqrt("x")`, "ERROR argument to \"sqrt\" must be INTEGER or FLOAT.\ngot STRING"},
+		{"m.sqrt()", "ERROR wrong number of arguments.\nexpected=1, got=0"},
+		{"m.min()", "ERROR min of no values"},
+		{"m.max([])", "ERROR max of no values"},
+		{`m.min(1, "a")`, "ERROR cannot compare STRING and INTEGER"},
+		{`m.sum([1, "a"])`, "ERROR elements of the argument to \"sum\" must be INTEGER or FLOAT.\ngot STRING"},
+		{"m.sum(1)", "ERROR argument to \"sum\" must be ARRAY or iterable.\ngot INTEGER"},
+		{"m.mean([])", "ERROR mean of an empty collection"},
+		{"m.median([])", "ERROR median of an empty collection"},
+		{"m.clamp(1, 10, 0)", "ERROR clamp bounds are reversed: 10 > 0"},
+		{"m.randInt(5, 1)", "ERROR randInt bounds are reversed: 5 > 1"},
+		{"m.nope", "ERROR module math has no export nope"},
+	}
+
+	for _, tc := range tests {
+		in := New(Options{})
+		input := `import "math" as m; ` + tc.input
+
+		if got := in.Run(parse(t, input)).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
 func TestStringComparison(t *testing.T) {
 	input := `"test" == "test"`
 
@@ -209,6 +511,122 @@ func TestStringComparison(t *testing.T) {
 
 }
 
+func TestStringInfixOperators(t *testing.T) {
+	tests := []ExpectedTest[bool]{
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" != "b"`, true},
+		{`"a" < "b"`, true},
+		{`"b" < "a"`, false},
+		{`"abc" > "abd"`, false},
+		{`"b" > "abc"`, true},
+		{`"" < "a"`, true},
+		{`if ("a" == "a") { true } else { false }`, true},
+	}
+
+	for _, tc := range tests {
+		testBooleanObject(t, testEval(tc.input), tc.expected)
+	}
+}
+
+func TestStructuralEquality(t *testing.T) {
+	tests := []ExpectedTest[bool]{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] == [1, 2, 3]", false},
+		{"[] == []", true},
+		{"[1, [2, 3]] == [1, [2, 3]]", true},
+		{"[1, [2, 3]] == [1, [2, 4]]", false},
+		{"[1] == [1.0]", true},
+		{`["a", true] == ["a", true]`, true},
+		{"let a = [1]; a == a", true},
+		{`{"a": 1, "b": [2]} == {"b": [2], "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{`{} == {}`, true},
+		{`{"a": 1} != {"a": 1}`, false},
+		{"[1] == 1", false},
+		{`[1] == {}`, false},
+		{"fn(x) { x } == fn(x) { x }", false},
+		{"let f = fn(x) { x }; f == f", true},
+		{"len == len", true},
+	}
+
+	for _, tc := range tests {
+		testBooleanObject(t, testEval(tc.input), tc.expected)
+	}
+}
+
+func TestHashLiterals(t *testing.T) {
+	input := `let two = "two";
+{
+  "one": 10 - 9,
+  two: 1 + 1,
+  "thr" + "ee": 6 / 2,
+  4: 4,
+  true: 5,
+  false: 6
+}`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("Eval didn't return Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := []struct {
+		key   object.Object
+		value int64
+	}{
+		{&object.String{Value: "one"}, 1},
+		{&object.String{Value: "two"}, 2},
+		{&object.String{Value: "three"}, 3},
+		{&object.Integer{Value: 4}, 4},
+		{TRUE, 5},
+		{FALSE, 6},
+	}
+
+	if result.Len() != len(expected) {
+		t.Fatalf("Hash has wrong num of pairs. got=%d", result.Len())
+	}
+
+	// the pairs come back in the order the literal has them
+	for i, pair := range result.Pairs() {
+		if !object.Equal(pair.Key, expected[i].key) {
+			t.Errorf("pair %d has the wrong key.\nexpected=%s, got=%s", i, expected[i].key.Inspect(), pair.Key.Inspect())
+		}
+
+		testIntegerObject(t, pair.Value, expected[i].value)
+	}
+}
+
+func TestHashIndexExpressions(t *testing.T) {
+	tests := []ExpectedTest[interface{}]{
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{5: 5}[5.0]`, 5},
+		{`{9007199254740993: 5}[9007199254740992.0]`, nil},
+		{`{9007199254740992: 5}[9007199254740992.0]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+		integer, ok := tc.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestStringConcatenation(t *testing.T) {
 	input := `"Hello" + " " + "World!"`
 
@@ -223,6 +641,26 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{`let user = {"name": "ann"}; let count = 3; "hello ${user["name"]}, you have ${count} items"`,
+			"hello ann, you have 3 items"},
+		{`"${1.5} ${true} ${[1, "a"]} ${null_value()}"`, "ERROR identifier not found: null_value"},
+		{`"${1.5} ${true} ${[1, "a"]} ${{"k": 2}}"`, "1.5 true [1, a] {k: 2}"},
+		{`"${1 + 1}${"${2 + 2}" + "!"}"`, "24!"},
+		{`"no placeholders"`, "no placeholders"},
+		{`"escaped \${1 + 1} and \"quoted\""`, `escaped ${1 + 1} and "quoted"`},
+		{`let x = 1; let f = fn() { let x = 2; "x is ${x}" }; f() + ", ${x}"`, "x is 2, 1"},
+		{`"sum: ${1 + true}"`, "ERROR type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tc := range tests {
+		if got := testEval(tc.input).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello World!"`
 
@@ -248,6 +686,699 @@ addTwo(2);`
 	testIntegerObject(t, testEval(input), 4)
 }
 
+func TestClosureCaptures(t *testing.T) {
+	tests := []ExpectedTest[interface{}]{
+		// counter: each call to makeCounter gets its own count, the closure mutates it
+		{`
+let makeCounter = fn() {
+  let count = 0;
+  fn() { count = count + 1; count };
+};
+let counter = makeCounter();
+counter();
+counter();
+counter();`, 3},
+		{`
+let makeCounter = fn() {
+  let count = 0;
+  fn() { count = count + 1; count };
+};
+let a = makeCounter();
+let b = makeCounter();
+a(); a();
+b();`, 1},
+		// generator: hands out the next value on every call
+		{`
+let from = fn(start) {
+  let next = start;
+  fn() { let current = next; next = next + 1; current };
+};
+let gen = from(10);
+gen();
+gen();
+gen();`, 12},
+		// captured by reference: re-binding after the closure was made is visible in it
+		{"let x = 1; let f = fn() { x }; x = 2; f();", 2},
+		{"let x = 1; let f = fn() { x }; let x = 3; f();", 3},
+		// and the closure's assignments are visible to the defining scope
+		{"let x = 1; let f = fn() { x = 10 }; f(); x;", 10},
+		// parameters and locals shadow, they're never captured
+		{"let x = 1; let f = fn(x) { x = x + 1; x }; f(5); x;", 1},
+		// recursion through a local let
+		{`
+let outer = fn() {
+  let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };
+  fact(5);
+};
+outer();`, 120},
+		// mutual recursion between locals defined one after the other
+		{`
+let outer = fn(n) {
+  let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+  let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+  if (isEven(n)) { 1 } else { 0 };
+};
+outer(10);`, 1},
+		// functions defined at the top level after the closure still resolve through the globals
+		{`
+let outer = fn() { fn() { helper() } };
+let call = outer();
+let helper = fn() { 7 };
+call();`, 7},
+		// a let reads the outer binding on its right hand side
+		{"let x = 5; let f = fn() { let x = x + 1; x }; f();", 6},
+		{"let x = 1; x = x + 1; x;", 2},
+		{"let x = 1; let y = 1; x = y = 5; x + y;", 10},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestClosureRetainsOnlyFreeVariables(t *testing.T) {
+	input := `
+let outer = fn() {
+  let unused = [1, 2, 3];
+  let used = 1;
+  fn() { used };
+};
+outer();`
+
+	evaluated := testEval(input)
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if _, ok := fn.Env.Get("used"); !ok {
+		t.Errorf("closure did not capture used")
+	}
+
+	if _, ok := fn.Env.Get("unused"); ok {
+		t.Errorf("closure kept unused alive")
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []ExpectedTest[interface{}]{
+		{"let total = 0; for (x in [1, 2, 3]) { total = total + x; } total;", 6},
+		{"let total = 0; for (x in []) { total = total + 1; } total;", 0},
+		{`let s = ""; for (c in "héllo") { s = c + s; } s == "olléh";`, true},
+		{`let n = 0; for (k in {"a": 1, "b": 2}) { n = n + len(k); } n;`, 2},
+		// every iteration gets its own binding, closures keep the one they were made in
+		{`
+let fs = [];
+for (i in [1, 2, 3]) { fs = push(fs, fn() { i * 10 }); }
+fs[0]() + fs[2]();`, 40},
+		// lets in the body are per iteration as well
+		{"let last = 0; for (x in [1, 2]) { let y = x * 2; last = y; } last;", 4},
+		// return leaves the enclosing function
+		{`
+let find = fn(xs, target) {
+  for (x in xs) { if (x == target) { return true; } }
+  false;
+};
+find([1, 2, 3], 2);`, true},
+		// a loop itself evaluates to null
+		{"let f = fn() { for (x in [1]) { x } }; f();", nil},
+		{"for (x in [1]) { x }", nil},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+
+	errors := []ExpectedTest[string]{
+		{"for (x in 5) { x }", "cannot iterate over INTEGER"},
+		{"for (x in [1, 2]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"for (x in y) { x }", "identifier not found: y"},
+	}
+
+	for _, tc := range errors {
+		testErrorObject(t, testEval(tc.input), tc.expected)
+	}
+}
+
+func TestStructs(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{"struct Point { x, y }; Point(1, 2)", "Point{x: 1, y: 2}"},
+		{"struct Point { x, y }; Point", "struct Point { x, y }"},
+		{"struct Unit {}; Unit()", "Unit{}"},
+		{"struct Point { x, y }; let p = Point(1, 2); p.x + p.y", "3"},
+		{"struct Point { x, y }; let p = Point(1, 2); p.x = 10; p", "Point{x: 10, y: 2}"},
+		{`struct Pair { a, b }; Pair("k", [1, 2])`, "Pair{a: k, b: [1, 2]}"},
+		// structural equality, but only between instances of the same struct
+		{"struct Point { x, y }; Point(1, 2) == Point(1, 2.0)", "true"},
+		{"struct Point { x, y }; Point(1, 2) == Point(2, 1)", "false"},
+		{"struct A { x }; struct B { x }; A(1) == B(1)", "false"},
+		{"struct Point { x, y }; let p = Point(1, 2); p != Point(1, 2)", "false"},
+		// usable as hash keys when every field is
+		{`struct Point { x, y }; let h = {Point(1, 2): "a"}; h[Point(1, 2)]`, "a"},
+		{`struct Point { x, y }; let h = {Point(1, 2): "a"}; h[Point(2, 1)]`, "null"},
+		{`struct Box { v }; {Box(Box(1)): 1}[Box(Box(1))]`, "1"},
+		// a struct declared inside a function is local to it, and closures can use it
+		{"let make = fn(x) { struct Box { v }; Box(x) }; make(1) == make(1)", "false"},
+		{"let f = fn() { let g = fn() { P(1) }; struct P { v }; g() }; f().v", "1"},
+
+		{"struct Point { x, y }; Point(1)", "ERROR wrong number of arguments.\nexpected=2, got=1"},
+		{"struct Point { x, y }; Point(1, 2).z", "ERROR Point has no field z"},
+		{"struct Point { x, y }; let p = Point(1, 2); p.z = 1", "ERROR Point has no field z"},
+		{"struct Box { v }; {Box([1]): 1}", "ERROR unusable as hash key: INSTANCE"},
+		{"struct Box { v }; let h = {}; h[Box({})]", "ERROR unusable as hash key: INSTANCE"},
+		{"struct Point { x, y }; Point(1, 2) + 1", "ERROR type mismatch: INSTANCE + INTEGER"},
+	}
+
+	for _, tc := range tests {
+		if got := testEval(tc.input).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestMethods(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{`
+struct Point { x, y }
+impl Point {
+  fn distSquared(self, other) {
+    let dx = self.x - other.x;
+    let dy = self.y - other.y;
+    dx * dx + dy * dy
+  }
+  fn moved(self, dx) { Point(self.x + dx, self.y) }
+}
+Point(0, 0).distSquared(Point(3, 4))`, "25"},
+		{"struct P { x }; impl P { fn get(self) { self.x } }; let p = P(1); p.x = 5; p.get()", "5"},
+		{"struct P { x }; impl P { fn inc(self) { self.x = self.x + 1; self } }; P(1).inc().inc()", "P{x: 3}"},
+		// instances that point at each other compare without recursing forever
+		{"struct N { next }; let a = N(0); let b = N(0); a.next = b; b.next = a; a == b", "true"},
+		{"struct N { next, v }; let a = N(0, 1); let b = N(0, 2); a.next = a; b.next = b; a == b", "false"},
+		// an instance that holds itself can still be shown, but not used as a key
+		{"struct N { next }; let a = N(0); a.next = a; a", "N{next: ...}"},
+		{"struct N { next }; let a = N(0); a.next = [a]; a", "N{next: [...]}"},
+		{"struct N { next }; let a = N(0); a.next = a; {a: 1}", "ERROR unusable as hash key: INSTANCE"},
+		{"struct N { next }; let a = N(0); a.next = a; #{a}", "ERROR unusable as set element: INSTANCE"},
+		{"struct N { next }; let a = N(0); let s = #{a}; a.next = s; s", "#{N{next: ...}}"},
+		{"let xs = [1]; push(xs, xs); xs", "[1, ...]"},
+		{"enum O { Some(v) }; struct B { v }; let b = B(0); let o = O.Some(b); b.v = o; o", "O.Some(B{v: ...})"},
+		{"enum O { Some(v) }; struct B { v }; let b = B(0); let o = O.Some(b); b.v = o; {o: 1}", "ERROR unusable as hash key: VARIANT"},
+		// methods can be added by later impl blocks and see the scope they were defined in
+		{"struct P { x }; impl P { fn a(self) { 1 } }; let k = 10; impl P { fn b(self) { k } }; P(0).a() + P(0).b()", "11"},
+		// a bound method remembers its receiver
+		{"struct P { x }; impl P { fn get(self) { self.x } }; let g = P(7).get; g()", "7"},
+		{"struct P { x }; impl P { fn get(self) { self.x } }; P(7).get", "bound method P.get"},
+		// a field shadows a method of the same name
+		{"struct P { get }; impl P { fn get(self) { 1 } }; P(2).get", "2"},
+		// methods recurse through tail calls like functions do
+		{`
+struct Counter { n }
+impl Counter { fn down(self, i) { if (i == 0) { self.n } else { self.n = self.n + 1; self.down(i - 1) } } }
+Counter(0).down(100000)`, "100000"},
+
+		// builtin types
+		{`"abc".upper()`, "ABC"},
+		{`"  ABC ".trim().lower()`, "abc"},
+		{`"a,b,c".split(",")`, "[a, b, c]"},
+		{`"hello".contains("ell")`, "true"},
+		{`"hello".len()`, "5"},
+		{"[1, 2, 3].map(fn(x) { x * 2 })", "[2, 4, 6]"},
+		{"[1, 2, 3, 4].filter(fn(x) { x % 2 == 0 })", "[2, 4]"},
+		{"[1, 2, 3].reduce(fn(acc, x) { acc + x }, 0)", "6"},
+		{"[1, 2].push(3).len()", "3"},
+		{`{"a": 1}.has("a")`, "true"},
+		{`{"a": 1}.values()`, "[1]"},
+		// hashes keep their keys in the order they were first set
+		{`{"z": 1, "a": 2, 10: 3, "m": 4}.keys()`, "[z, a, 10, m]"},
+		// a repeated key keeps its first place and its last value
+		{`{"z": 1, "a": 2, "z": 3}.values()`, "[3, 2]"},
+		{`let ks = []; for (k in {"c": 1, "b": 2, "a": 3}) { push(ks, k) }; ks`, "[c, b, a]"},
+		{`{"c": 1, "b": [2], "a": {"y": 3, "x": 4}}`, "{c: 1, b: [2], a: {y: 3, x: 4}}"},
+		{`"abc".upper`, "bound method STRING.upper"},
+
+		{"struct P { x }; P(1).nope()", "ERROR P has no field nope"},
+		{"let x = 1; impl x { fn f(self) { 1 } }", "ERROR cannot impl x: not a struct"},
+		{"impl Nope { fn f(self) { 1 } }", "ERROR identifier not found: Nope"},
+		{`"abc".nope()`, "ERROR STRING has no attribute nope"},
+		{`"abc".upper(1)`, "ERROR wrong number of arguments.\nexpected=0, got=1"},
+		{"[1].map(fn(x) { x + true })", "ERROR type mismatch: INTEGER + BOOLEAN"},
+		{"[1].map(1)", "ERROR not a function: INTEGER"},
+	}
+
+	for _, tc := range tests {
+		if got := testEval(tc.input).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestEnums(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{"enum Result { Ok(value), Err(message), Pending }; Result", "enum Result { Ok(value), Err(message), Pending }"},
+		{"enum Result { Ok(value), Err(message), Pending }; Result.Ok(1)", "Result.Ok(1)"},
+		{"enum Result { Ok(value), Err(message), Pending }; Result.Pending", "Result.Pending"},
+		{"enum Result { Ok(value), Err(message), Pending }; Result.Ok", "Result.Ok(value)"},
+		{"enum Result { Ok(value), Err(message) }; Result.Err(\"bad\").message", "bad"},
+		{"enum Shape { Rect(w, h) }; let ctor = Shape.Rect; ctor(2, 3)", "Shape.Rect(2, 3)"},
+		// structural equality between values of the same variant
+		{"enum R { A(x), B }; R.A(1) == R.A(1)", "true"},
+		{"enum R { A(x), B }; R.A(1) == R.A(2)", "false"},
+		{"enum R { A(x), B }; R.B == R.B", "true"},
+		{"enum R { A(x) }; enum S { A(x) }; R.A(1) == S.A(1)", "false"},
+		{`enum R { A(x), B }; let h = {R.A(1): "a", R.B: "b"}; h[R.A(1)] + h[R.B]`, "ab"},
+
+		{"enum R { A(x), B }; R.C", "ERROR R has no variant C"},
+		{"enum R { A(x), B }; R.A(1, 2)", "ERROR wrong number of arguments.\nexpected=1, got=2"},
+		{"enum R { A(x), B }; R.A(1).y", "ERROR R.A has no field y"},
+		{"enum R { A(x) }; {R.A([1]): 1}", "ERROR unusable as hash key: VARIANT"},
+	}
+
+	for _, tc := range tests {
+		if got := testEval(tc.input).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{"match (2) { 1 => \"one\", 2 => \"two\", _ => \"many\" }", "two"},
+		{"match (5) { 1 => \"one\", n => n * 10 }", "50"},
+		{"match (-1) { -1 => \"minus one\", _ => \"other\" }", "minus one"},
+		{"match (2.0) { 2 => \"equal\", _ => \"other\" }", "equal"},
+		{`match ("b") { "a" => 1, "b" => 2 }`, "2"},
+		{"match (1 < 2) { true => { let x = 1; x + 1 }, false => 0 }", "2"},
+		// arms run in order, and their bindings stay in the arm
+		{"let n = 0; match (3) { n => n }; n", "0"},
+		{"match (1) { _ => 1, 1 => 2 }", "1"},
+		{`
+enum Result { Ok(value), Err(message) }
+let describe = fn(r) {
+  match (r) {
+    Result.Ok(v) => "ok " + v,
+    Result.Err(m) => "error " + m,
+  }
+};
+describe(Result.Ok("yes")) + ", " + describe(Result.Err("no"))`, "ok yes, error no"},
+		{"enum R { A(x, y), B }; match (R.A(1, 2)) { R.A(_, y) => y, R.B => 0 }", "2"},
+		{"enum R { A(x), B }; match (R.A(1)) { R.A => \"any A\", R.B => \"B\" }", "any A"},
+		{"enum R { A(x), B }; match (R.B) { R.A(_) => 1, R.B => 2 }", "2"},
+		{"enum R { A(x), B }; match (R.A(3)) { R.A(1) => \"one\", R.A(n) => n }", "3"},
+		// nested patterns
+		{`
+enum Option { Some(value), None }
+enum Result { Ok(value), Err(message) }
+match (Result.Ok(Option.Some(7))) {
+  Result.Ok(Option.Some(v)) => v,
+  Result.Ok(Option.None) => 0,
+  Result.Err(_) => -1,
+}`, "7"},
+		// match in tail position doesn't grow the stack
+		{`
+enum List { Cons(head, tail), Nil }
+let sum = fn(list, acc) {
+  match (list) {
+    List.Cons(h, t) => sum(t, acc + h),
+    List.Nil => acc,
+  }
+};
+let build = fn(n, acc) { if (n == 0) { acc } else { build(n - 1, List.Cons(n, acc)) } };
+sum(build(100000, List.Nil), 0)`, "5000050000"},
+
+		{"match (3) { 1 => 1, 2 => 2 }", "ERROR no match arm for 3"},
+		{"enum R { A(x), B }; match (R.B) { R.A(v) => v }", "ERROR no match arm for R.B"},
+		{"match (1) { Nope.A => 1 }", "ERROR identifier not found: Nope"},
+		{"let x = 1; match (1) { x.A => 1 }", "ERROR x is not an enum"},
+		{"enum R { A(x), B }; match (R.B) { R.C => 1 }", "ERROR R has no variant C"},
+		{"enum R { A(x), B }; match (R.A(1)) { R.A(x, y) => 1 }", "ERROR pattern R.A(x, y) has 2 fields, R.A has 1"},
+		{"match (1) { _ => 1 + true }", "ERROR type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tc := range tests {
+		if got := testEval(tc.input).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{"let g = fn*() { yield 1; yield 2; }; let it = g(); [it.next(), it.next(), it.next()]", "[1, 2, null]"},
+		{"let g = fn*() { yield 1; }; g()", "generator"},
+		{"fn*() { yield 1 }", "fn*() {\nyield 1;\n"},
+		// nothing runs until the first value is asked for
+		{"let ran = false; let g = fn*() { ran = true; yield 1 }; let it = g(); ran", "false"},
+		{"let ran = false; let g = fn*() { ran = true; yield 1 }; let it = g(); it.next(); ran", "true"},
+		// done tells a yielded null apart from the end
+		{"let g = fn*() { yield if (false) { 1 } }; let it = g(); [it.done(), it.next(), it.done()]", "[false, null, true]"},
+		// for loops, ending early through return
+		{"let g = fn*(n) { let i = 0; for (x in [1, 2, 3, 4]) { yield x * n } }; let s = 0; for (v in g(10)) { s = s + v }; s", "100"},
+		{`
+let naturals = fn*(n) { yield n; for (x in naturals(n + 1)) { yield x } };
+let firstOver = fn(limit) { for (n in naturals(0)) { if (n * n > limit) { return n } } };
+firstOver(50)`, "8"},
+		// a for loop picks up where next left off
+		{"let g = fn*() { yield 1; yield 2; yield 3 }; let it = g(); it.next(); let s = 0; for (v in it) { s = s + v }; s", "5"},
+		// generators as methods, and generators consuming generators
+		{`
+struct Range { from, to }
+impl Range {
+  fn* items(self) { let i = self.from; for (x in [1, 2, 3, 4, 5]) { if (i < self.to) { yield i; i = i + 1 } } }
+}
+let double = fn*(items) { for (x in items) { yield x * 2 } };
+let out = [];
+for (x in double(Range(3, 6).items())) { push(out, x) }
+out`, "[6, 8, 10]"},
+		// return ends the generator
+		{"let g = fn*() { yield 1; return 5; yield 2 }; let it = g(); [it.next(), it.next(), it.done()]", "[1, null, true]"},
+		{"let g = fn*() { yield 1; yield 2 }; let it = g(); it.next(); it.close(); [it.next(), it.done()]", "[null, true]"},
+		{"let g = fn*() { yield 1 }; let it = g(); it.close(); it.next()", "null"},
+
+		{"let g = fn*() { yield 1; yield 1 + true; yield 3 }; let it = g(); it.next(); it.next()", "ERROR type mismatch: INTEGER + BOOLEAN"},
+		{"let g = fn*() { yield 1; yield 1 + true; yield 3 }; let it = g(); it.next(); it.next(); it.next(); it.done()", "ERROR type mismatch: INTEGER + BOOLEAN"},
+		{"let g = fn*() { yield 1; yield 1 + true }; let s = 0; for (x in g()) { s = s + x }; s", "ERROR type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tc := range tests {
+		if got := testEval(tc.input).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestAbandonedGeneratorsDoNotLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// each generator stops at its first yield inside a loop inside a function call and is never finished
+	input := `
+let naturals = fn*(n) { yield n; for (x in naturals(n + 1)) { yield x } };
+let firstOver = fn(limit) { for (n in naturals(0)) { if (n * n > limit) { return n } } };
+let s = 0;
+for (i in [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]) { s = s + firstOver(i * 10) }
+s`
+	testIntegerObject(t, testEval(input), 77)
+
+	for attempt := 0; attempt < 100 && runtime.NumGoroutine() > before; attempt++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines leaked. before=%d, after=%d", before, after)
+	}
+}
+
+func TestCollectionBuiltins(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{"map([1, 2, 3], fn(x) { x * 2 })", "[2, 4, 6]"},
+		{"map([], fn(x) { x * 2 })", "[]"},
+		{"map([[1], [2, 3]], len)", "[1, 2]"},
+		{"filter([1, 2, 3, 4], fn(x) { x % 2 == 0 })", "[2, 4]"},
+		{"reduce([1, 2, 3], fn(acc, x) { acc + x }, 10)", "16"},
+		{"reduce([1, 2, 3], fn(acc, x) { acc * x })", "6"},
+		{"reduce([], fn(acc, x) { acc + x }, 0)", "0"},
+		{"find([1, 2, 3, 4], fn(x) { x > 2 })", "3"},
+		{"find([1, 2], fn(x) { x > 2 })", "null"},
+		{"findIndex([1, 2, 3, 4], fn(x) { x > 2 })", "2"},
+		{"findIndex([1, 2], fn(x) { x > 2 })", "-1"},
+		{"any([1, 2, 3], fn(x) { x > 2 })", "true"},
+		{"any([], fn(x) { true })", "false"},
+		{"all([1, 2, 3], fn(x) { x > 0 })", "true"},
+		{"all([1, 2, 3], fn(x) { x > 1 })", "false"},
+		{"all([], fn(x) { false })", "true"},
+		{"sort([3, 1.5, 2, -1])", "[-1, 1.5, 2, 3]"},
+		{`sort(["pear", "apple", "fig"])`, "[apple, fig, pear]"},
+		{"sort([3, 1, 2], fn(a, b) { b - a })", "[3, 2, 1]"},
+		// stable, elements the comparator considers equal keep their order
+		{`sort(["bb", "a", "cc", "d"], fn(a, b) { len(a) - len(b) })`, "[a, d, bb, cc]"},
+		{"let xs = [3, 1, 2]; sort(xs); xs", "[3, 1, 2]"},
+		{"reverse([1, 2, 3])", "[3, 2, 1]"},
+		{"let xs = [1, 2]; reverse(xs); xs", "[1, 2]"},
+		{"slice([1, 2, 3, 4], 1)", "[2, 3, 4]"},
+		{"slice([1, 2, 3, 4], 1, 3)", "[2, 3]"},
+		{"slice([1, 2, 3, 4], -2)", "[3, 4]"},
+		{"slice([1, 2, 3, 4], 3, 1)", "[]"},
+		{"slice([1, 2, 3, 4], 0, 10)", "[1, 2, 3, 4]"},
+		{"concat([1], [2, 3], [], [4])", "[1, 2, 3, 4]"},
+		{"concat([1])", "[1]"},
+		{"flatten([1, [2, [3]], []])", "[1, 2, [3]]"},
+		{"flatten([1, [2, [3]], []], 2)", "[1, 2, 3]"},
+		// stops as soon as there is nothing left to flatten
+		{"flatten([1, [2, [3]]], 100000000000)", "[1, 2, 3]"},
+		{"flatten([1], 9223372036854775807)", "[1]"},
+		{"zip([1, 2, 3], [\"a\", \"b\"])", "[[1, a], [2, b]]"},
+		{"zip([1, 2], [3, 4], [5, 6])", "[[1, 3, 5], [2, 4, 6]]"},
+		{"uniq([1, 2, 1, 3, 2.0, [1], [1]])", "[1, 2, 3, [1]]"},
+		{"let g = groupBy([1, 2, 3, 4, 5], fn(x) { x % 2 }); [g[0], g[1]]", "[[2, 4], [1, 3, 5]]"},
+		{"chunk([1, 2, 3, 4, 5], 2)", "[[1, 2], [3, 4], [5]]"},
+		{"chunk([], 2)", "[]"},
+
+		// as methods
+		{"[1, 2, 3].find(fn(x) { x > 1 })", "2"},
+		{"[[1, 2], [3]].flatten().reverse()", "[3, 2, 1]"},
+		{"[3, 1, 2].sort().slice(1)", "[2, 3]"},
+		{"[1, 2, 3].reduce(fn(acc, x) { acc + x })", "6"},
+
+		// over generators, stopping as soon as the answer is known
+		{"let squares = fn*() { for (x in [1, 2, 3]) { yield x * x } }; map(squares(), fn(x) { x + 1 })", "[2, 5, 10]"},
+		{"let naturals = fn*(n) { yield n; for (x in naturals(n + 1)) { yield x } }; naturals(0).find(fn(n) { n * n > 50 })", "8"},
+		{"let naturals = fn*(n) { yield n; for (x in naturals(n + 1)) { yield x } }; any(naturals(0), fn(n) { n > 10 })", "true"},
+		{"let g = fn*() { yield 2; yield 1 }; zip([1, 2], g())", "[[1, 2], [2, 1]]"},
+		{"let g = fn*() { yield 1; yield 1 + true }; map(g(), fn(x) { x })", "ERROR type mismatch: INTEGER + BOOLEAN"},
+
+		{"map([1, 2], fn(x) { x + true })", "ERROR type mismatch: INTEGER + BOOLEAN"},
+		{"map([1], 1)", "ERROR not a function: INTEGER"},
+		{`map("abc", fn(x) { x })`, "ERROR argument to \"map\" must be ARRAY or iterable.\ngot STRING"},
+		{"map([1])", "ERROR wrong number of arguments.\nexpected=2, got=1"},
+		{"[1].map()", "ERROR wrong number of arguments.\nexpected=1, got=0"},
+		{"reduce([1], fn(a, b) { a }, 1, 2)", "ERROR wrong number of arguments.\nexpected=2 to 3, got=4"},
+		{"concat()", "ERROR wrong number of arguments.\nexpected at least 1, got=0"},
+		{"reduce([], fn(acc, x) { acc + x })", "ERROR reduce of an empty collection with no initial value"},
+		{`sort([1, "a"])`, "ERROR cannot compare STRING and INTEGER"},
+		{"sort([1, 2], fn(a, b) { true })", "ERROR comparator must return INTEGER or FLOAT.\ngot BOOLEAN"},
+		{"slice([1], 0.5)", "ERROR argument to \"slice\" must be INTEGER.\ngot FLOAT"},
+		{"chunk([1], 0)", "ERROR chunk size must be positive.\ngot 0"},
+		{"groupBy([1], fn(x) { [x] })", "ERROR unusable as hash key: ARRAY"},
+		{"concat([1], 2)", "ERROR argument to \"concat\" must be ARRAY or iterable.\ngot INTEGER"},
+
+		// callbacks taking the wrong number of parameters
+		{"map([1, 2, 3], fn(x, i) { x + i })", "ERROR wrong number of arguments: want=2, got=1"},
+		{"filter([1], fn() { true })", "ERROR wrong number of arguments: want=0, got=1"},
+		{"reduce([1, 2], fn(acc) { acc }, 0)", "ERROR wrong number of arguments: want=1, got=2"},
+		{"sort([2, 1], fn(a) { a })", "ERROR wrong number of arguments: want=1, got=2"},
+		{"find([1], fn(x, y) { true })", "ERROR wrong number of arguments: want=2, got=1"},
+		{"any([1], fn(x, y) { true })", "ERROR wrong number of arguments: want=2, got=1"},
+		{"groupBy([1], fn() { 1 })", "ERROR wrong number of arguments: want=0, got=1"},
+		{"[1].map(fn(a, b, c) { a })", "ERROR wrong number of arguments: want=3, got=1"},
+	}
+
+	for _, tc := range tests {
+		if got := testEval(tc.input).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestCollectionBuiltinsScale(t *testing.T) {
+	// linear in the length of the array, building it with push and reducing it with rest would take minutes
+	input := `
+let build = fn(n, acc) { if (n == 0) { acc } else { build(n - 1, push(acc, n)) } };
+let big = build(100000, []);
+reduce(filter(map(big, fn(x) { x * 2 }), fn(x) { x % 3 == 0 }), fn(acc, x) { acc + x }, 0)`
+
+	testIntegerObject(t, testEval(input), 3333366666)
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{`split("a,b,,c", ",")`, "[a, b, , c]"},
+		{`split("héj", "")`, "[h, é, j]"},
+		{`join(["a", "b", "c"], ", ")`, "a, b, c"},
+		{`join([1, "b", [2]])`, "1b[2]"},
+		{`join([], "-")`, ""},
+		{`let g = fn*() { yield "x"; yield "y" }; g().join("+")`, "x+y"},
+		{`trim("  a b   ")`, "a b"},
+		{`trim("xxaxx", "x")`, "a"},
+		{`trimLeft("  a  ")`, "a  "},
+		{`trimRight("  a  ")`, "  a"},
+		{`trimLeft("..a..", ".")`, "a.."},
+		{`upper("héllo")`, "HÉLLO"},
+		{`lower("HÉLLO")`, "héllo"},
+		{`contains("hello", "ell")`, "true"},
+		{`contains("hello", "x")`, "false"},
+		{`startsWith("hello", "he")`, "true"},
+		{`endsWith("hello", "he")`, "false"},
+		{`indexOf("héllo", "l")`, "2"},
+		{`indexOf("hello", "x")`, "-1"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", 0)`, ""},
+		{`substr("héllo", 1, 3)`, "éll"},
+		{`substr("héllo", 1)`, "éllo"},
+		{`substr("héllo", -2)`, "lo"},
+		{`substr("héllo", 3, 10)`, "lo"},
+		{`substr("héllo", 10)`, ""},
+		{`chars("héj")`, "[h, é, j]"},
+		{`chars("")`, "[]"},
+		{`padLeft("7", 3, "0")`, "007"},
+		{`padLeft("é", 3)`, "  é"},
+		{`padRight("ab", 7, "xy")`, "abxyxyx"},
+		{`padRight("long", 2)`, "long"},
+		{`padLeft("a", 4, "xyz")`, "xyza"},
+		{`repeat("", 9223372036854775807)`, ""},
+		{`format("%d + %d = %s", 1, 2, "three")`, "1 + 2 = three"},
+		{`format("%v and %v", [1, "a"], {"k": true})`, "[1, a] and {k: true}"},
+		{`format("%5.2f|%-4s|%03d|100%%", 3.14159, "ab", 7)`, " 3.14|ab  |007|100%"},
+		{`format("%f", 2)`, "2.000000"},
+		{`format("no verbs")`, "no verbs"},
+
+		// every one of them is a method of strings as well
+		{`"a,b".split(",")`, "[a, b]"},
+		{`"  x ".trim().upper()`, "X"},
+		{`"héllo".indexOf("llo")`, "2"},
+		{`"%s=%d".format("x", 1)`, "x=1"},
+		{`"ab".padLeft(4).repeat(2)`, "  ab  ab"},
+		{`"héllo".len()`, "5"},
+		{"[1, 2, 3].join()", "123"},
+
+		// indexing and slicing count characters
+		{`"héllo"[1]`, "é"},
+		{`"héllo"[4]`, "o"},
+		{`"héllo"[5]`, "null"},
+		{`"héllo"[-1]`, "null"},
+		{`"héllo"[1:3]`, "él"},
+		{`"héllo"[:2]`, "hé"},
+		{`"héllo"[3:]`, "lo"},
+		{`"héllo"[-3:]`, "llo"},
+		{`"héllo"[:]`, "héllo"},
+		{`"héllo"[4:2]`, ""},
+		{`"héllo"[1:100]`, "éllo"},
+		{"[1, 2, 3, 4][1:3]", "[2, 3]"},
+		{"[1, 2, 3, 4][:-1]", "[1, 2, 3]"},
+		{"let xs = [1, 2, 3]; let ys = xs[:]; push(ys, 4); xs", "[1, 2, 3]"},
+
+		{`upper(1)`, "ERROR argument to \"upper\" must be STRING.\ngot INTEGER"},
+		{`upper()`, "ERROR wrong number of arguments.\nexpected=1, got=0"},
+		{`substr("abc")`, "ERROR wrong number of arguments.\nexpected=2 to 3, got=1"},
+		{`"abc".substr()`, "ERROR wrong number of arguments.\nexpected=1 to 2, got=0"},
+		{`split("abc", 1)`, "ERROR argument to \"split\" must be STRING.\ngot INTEGER"},
+		{`repeat("a", -1)`, "ERROR repeat count must not be negative.\ngot -1"},
+		{`substr("abc", 0, -1)`, "ERROR substr length must not be negative.\ngot -1"},
+		{`padLeft("a", 3, "")`, "ERROR pad must not be empty"},
+		{`repeat("ab", 9223372036854775807)`, "ERROR repeat would make a string longer than 268435456 bytes.\ngot 9223372036854775807 copies of 2 bytes"},
+		{`"abcd".repeat(67108865)`, "ERROR repeat would make a string longer than 268435456 bytes.\ngot 67108865 copies of 4 bytes"},
+		{`padLeft("a", 9223372036854775807)`, "ERROR padLeft width must be at most 268435456.\ngot 9223372036854775807"},
+		{`"a".padRight(268435457, "xy")`, "ERROR padRight width must be at most 268435456.\ngot 268435457"},
+		{`format("%d", "x")`, "ERROR %d in format needs INTEGER.\ngot STRING"},
+		{`format("%f", "x")`, "ERROR %f in format needs INTEGER or FLOAT.\ngot STRING"},
+		{`format("%d %d", 1)`, `ERROR not enough arguments for format "%d %d"`},
+		{`format("%d", 1, 2)`, `ERROR too many arguments for format "%d"`},
+		{`format("%x", 1)`, "ERROR unknown verb %x in format"},
+		{`format("50%")`, `ERROR format ends in the middle of "%"`},
+		{`join(["a"], 1)`, "ERROR argument to \"join\" must be STRING.\ngot INTEGER"},
+		{`"abc"["a":]`, "ERROR slice bound must be INTEGER.\ngot STRING"},
+		{`5[1:2]`, "ERROR slice operator not supported: INTEGER"},
+	}
+
+	for _, tc := range tests {
+		if got := testEval(tc.input).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []ExpectedTest[interface{}]{
+		// accumulator in the else arm
+		{`
+let sum = fn(n, acc) { if (n == 0) { acc } else { sum(n - 1, acc + n) } };
+sum(100000, 0);`, 5000050000},
+		// explicit return from an if in the middle of the body
+		{`
+let count = fn(n) {
+  if (n == 0) { return "done"; }
+  return count(n - 1);
+};
+count(100000);`, "done"},
+		// else if arms
+		{`
+let collatz = fn(n, steps) {
+  if (n == 1) { steps } else if (n % 2 == 0) { collatz(n / 2, steps + 1) } else { collatz(3 * n + 1, steps + 1) }
+};
+collatz(27, 0);`, 111},
+		// mutual recursion
+		{`
+let isEven = fn(n) { if (n == 0) { true } else { isOdd(n - 1) } };
+let isOdd = fn(n) { if (n == 0) { false } else { isEven(n - 1) } };
+isEven(100001);`, false},
+		// calls that aren't in tail position still work, they just use the stack
+		{`
+let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };
+fact(10);`, 3628800},
+		{"let f = fn() { len([1, 2]) }; f();", 2},
+		{"let f = fn() { 1(); }; f();", "not a function: INTEGER"},
+	}
+
+	for _, tc := range tests {
+		evaluated := testEval(tc.input)
+
+		switch expected := tc.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch result := evaluated.(type) {
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("String has wrong value. expected=%q, got=%q", expected, result.Value)
+				}
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, result.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestTailCallsRunInConstantStack(t *testing.T) {
+	if testing.Short() {
+		t.Skip("recurses ten million times")
+	}
+
+	// a real stack overflow is fatal rather than a failure, capping the stack makes one happen early
+	defer debug.SetMaxStack(debug.SetMaxStack(1 << 20))
+
+	input := `
+let countdown = fn(n) { if (n == 0) { "done" } else { countdown(n - 1) } };
+countdown(10000000);`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok || str.Value != "done" {
+		t.Fatalf("unexpected result. got=%T (%+v)", evaluated, evaluated)
+	}
+}
+
 func TestFunctionAppLiteral(t *testing.T) {
 	tests := []ExpectedTest[int64]{
 		{"let identity = fn(x) { x; }; identity(5);", 5},
@@ -346,6 +1477,30 @@ func TestErrorHandling(t *testing.T) {
 			`"Hello" - "World"`,
 			"unknown operator: STRING - STRING",
 		},
+		{
+			"5 / 0",
+			"division by zero: 5 / 0",
+		},
+		{
+			"x = 5",
+			"cannot assign to undeclared identifier: x",
+		},
+		{
+			"let f = fn() { y = 1 }; f()",
+			"cannot assign to undeclared identifier: y",
+		},
+		{
+			`{"name": "Monkey"}[fn(x) { x }];`,
+			"unusable as hash key: FUNCTION",
+		},
+		{
+			`{[1]: 2}`,
+			"unusable as hash key: ARRAY",
+		},
+		{
+			"5 % 0",
+			"division by zero: 5 % 0",
+		},
 	}
 
 	for _, tc := range tests {
@@ -486,6 +1641,17 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 == 1.0", true},
+		{"1.0 == 1", true},
+		{"1 != 1.0", false},
+		{"1 == 1.5", false},
+		// exactly, so a key that compares equal is also found in a hash
+		{"9007199254740993 == 9007199254740992.0", false},
+		{"9007199254740993 != 9007199254740992.0", true},
+		{"9007199254740992 == 9007199254740992.0", true},
+		{"1 < 1.5", true},
+		{"1.5 < 1", false},
+		{"2 > 1.5", true},
 	}
 
 	for _, tc := range tests {
@@ -506,6 +1672,11 @@ func TestEvalFloatExpression(t *testing.T) {
 		{"(1.5 + 2) * 4", 14},
 		{"1.5 * 4", 6},
 		{"4 * 1.5", 6},
+		{"1 - 0.5", 0.5},
+		{"0.5 - 1", -0.5},
+		{"1 / 4.0", 0.25},
+		{"4.0 / 1", 4},
+		{"1 % 0.75", 0.25},
 	}
 
 	for _, tc := range tests {
@@ -0,0 +1,61 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+/*
+The body of a generator function finds the yield of the generator running it under this name. yield is a
+keyword, so scripts can neither see nor shadow the binding, and functions nested in the body can't yield
+because the parser doesn't allow it there.
+*/
+const yieldBinding = "yield"
+
+// Carries a generator's yield in the environment of its body, it never escapes to scripts
+type yielder struct {
+	yield func(object.Object) bool
+}
+
+func (y *yielder) Type() object.ObjectType { return "YIELDER" }
+func (y *yielder) Inspect() string         { return "yielder" }
+
+// What a yield returns once the generator has been closed. It unwinds the body like an error would, but is
+// never seen outside the generator
+var errGeneratorClosed = &object.Error{Message: "generator closed"}
+
+/*
+Calling a fn* doesn't run it, it returns a generator that runs the body on demand. A return statement or the end
+of the body finishes the generator, whatever value it has is dropped.
+*/
+func (in *Interpreter) newGenerator(fn *object.Function, args []object.Object) *object.Generator {
+	return object.NewGenerator(func(yield func(object.Object) bool) object.Object {
+		env := extendFunctionEnv(fn, args)
+		env.Set(yieldBinding, &yielder{yield: yield})
+
+		result := in.evalBlockStatement(fn.Body, env)
+		if isError(result) && result != errGeneratorClosed {
+			return result
+		}
+
+		return nil
+	})
+}
+
+func (in *Interpreter) evalYieldStatement(node *ast.YieldStatement, env *object.Environment) object.Object {
+	value := in.eval(node.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	binding, ok := env.Get(yieldBinding)
+	if !ok {
+		return newError("yield outside of a generator function")
+	}
+
+	if !binding.(*yielder).yield(value) {
+		return errGeneratorClosed
+	}
+
+	return nil
+}
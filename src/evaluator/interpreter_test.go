@@ -0,0 +1,438 @@
+package evaluator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+func TestInterpretersDoNotShareGlobals(t *testing.T) {
+	first := New(Options{})
+	second := New(Options{})
+
+	first.Run(parse(t, "let x = 1;"))
+	second.Run(parse(t, "let x = 2;"))
+
+	testIntegerObject(t, first.Run(parse(t, "x")), 1)
+	testIntegerObject(t, second.Run(parse(t, "x")), 2)
+
+	if _, ok := first.Globals().Get("x"); !ok {
+		t.Errorf("x is not in the first interpreter's globals")
+	}
+}
+
+func TestInterpretersDoNotShareBuiltins(t *testing.T) {
+	first := New(Options{})
+	second := New(Options{})
+
+	delete(first.builtins, "len")
+
+	testErrorObject(t, first.Run(parse(t, `len("abc")`)), "identifier not found: len")
+	testIntegerObject(t, second.Run(parse(t, `len("abc")`)), 3)
+}
+
+func TestInterpreterLimitsAreSeparate(t *testing.T) {
+	limited := New(Options{MaxSteps: 10})
+	unlimited := New(Options{})
+
+	input := "let f = fn(n) { if (n == 0) { 0 } else { f(n - 1) } }; f(100);"
+
+	testErrorPrefix(t, limited.Run(parse(t, input)), "maximum evaluation steps 10 exceeded")
+	testIntegerObject(t, unlimited.Run(parse(t, input)), 0)
+
+	// every Run gets the full budget again
+	testIntegerObject(t, limited.Run(parse(t, "1 + 1")), 2)
+}
+
+func TestInterpreterWriters(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	in := New(Options{Stdout: &stdout, Stderr: &stderr})
+
+	if in.Stdout() != &stdout || in.Stderr() != &stderr {
+		t.Errorf("interpreter did not keep the configured writers")
+	}
+}
+
+func TestOutputBuiltins(t *testing.T) {
+	tests := []struct {
+		input  string
+		stdout string
+		stderr string
+	}{
+		{`puts("a", 1, [2, "b"])`, "a\n1\n[2, b]\n", ""},
+		{`puts()`, "", ""},
+		{`print("a", 1); print("b")`, "a 1b", ""},
+		{`println("x =", 1.5, true); println()`, "x = 1.5 true\n\n", ""},
+		{`printf("%s has %d items\n", "cart", 3)`, "cart has 3 items\n", ""},
+		{`let n = 2; println("n is ${n}")`, "n is 2\n", ""},
+		{`eprint("failed:", {"code": 1})`, "", "failed: {code: 1}\n"},
+		// output written before an error stays written
+		{`println("before"); 1 + true; println("after")`, "before\n", ""},
+	}
+
+	for _, tc := range tests {
+		var stdout, stderr bytes.Buffer
+		in := New(Options{Stdout: &stdout, Stderr: &stderr})
+
+		in.Run(parse(t, tc.input))
+
+		if stdout.String() != tc.stdout {
+			t.Errorf("%s: wrong stdout. expected=%q, got=%q", tc.input, tc.stdout, stdout.String())
+		}
+
+		if stderr.String() != tc.stderr {
+			t.Errorf("%s: wrong stderr. expected=%q, got=%q", tc.input, tc.stderr, stderr.String())
+		}
+	}
+
+	in := New(Options{Stdout: failingWriter{}})
+	testErrorObject(t, in.Run(parse(t, `println("x")`)), "cannot write output: disk full")
+	testErrorObject(t, in.Run(parse(t, `printf("%d", "x")`)), "%d in format needs INTEGER.\ngot STRING")
+	testErrorObject(t, in.Run(parse(t, `printf()`)), "wrong number of arguments.\nexpected at least 1, got=0")
+
+	// each interpreter writes to its own writers
+	var first, second bytes.Buffer
+	New(Options{Stdout: &first}).Run(parse(t, `print("one")`))
+	New(Options{Stdout: &second}).Run(parse(t, `print("two")`))
+
+	if first.String() != "one" || second.String() != "two" {
+		t.Errorf("output went to the wrong writer. first=%q, second=%q", first.String(), second.String())
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestMathRandomIsReproducible(t *testing.T) {
+	input := `
+import "math" as math;
+let xs = [math.random(), math.randInt(1, 6), math.shuffle([1, 2, 3, 4, 5])];
+xs`
+
+	run := func(options Options, input string) string {
+		return New(options).Run(parse(t, input)).Inspect()
+	}
+
+	first := run(Options{Random: rand.NewSource(42)}, input)
+	if second := run(Options{Random: rand.NewSource(42)}, input); first != second {
+		t.Errorf("the same source gave different numbers: %s and %s", first, second)
+	}
+
+	// seeding from the script works the same whatever the interpreter started with
+	seeded := `import "math" as math; math.seed(7); ` + input
+	if a, b := run(Options{}, seeded), run(Options{Random: rand.NewSource(1)}, seeded); a != b {
+		t.Errorf("math.seed did not reset the numbers: %s and %s", a, b)
+	}
+
+	in := New(Options{})
+	for i := 0; i < 100; i++ {
+		n, ok := in.Run(parse(t, `import "math" as math; math.randInt(-2, 2)`)).(*object.Integer)
+		if !ok || n.Value < -2 || n.Value > 2 {
+			t.Fatalf("randInt(-2, 2) out of range: %v", n)
+		}
+	}
+
+	shuffled := run(Options{}, `import "math" as math; let xs = [1, 2, 3]; math.shuffle(xs); xs`)
+	if shuffled != "[1, 2, 3]" {
+		t.Errorf("shuffle changed its argument: %s", shuffled)
+	}
+}
+
+func TestInterpreterHooks(t *testing.T) {
+	var evaluated int
+	var calls []string
+
+	in := New(Options{
+		Hooks: Hooks{
+			BeforeEval: func(node ast.Node, env *object.Environment) {
+				evaluated++
+			},
+			OnCall: func(fn object.Object, args []object.Object) {
+				calls = append(calls, string(fn.Type())+"/"+args[0].Inspect())
+			},
+		},
+	})
+
+	in.Run(parse(t, "let double = fn(x) { x * 2 }; len([double(2)]);"))
+
+	if evaluated == 0 {
+		t.Errorf("BeforeEval hook was never called")
+	}
+
+	expected := []string{"FUNCTION/2", "BUILTIN/[4]"}
+	if len(calls) != len(expected) {
+		t.Fatalf("OnCall hook called wrong number of times. expected=%v, got=%v", expected, calls)
+	}
+
+	for i, call := range expected {
+		if calls[i] != call {
+			t.Errorf("calls[%d] wrong. expected=%q, got=%q", i, call, calls[i])
+		}
+	}
+}
+
+// Tail calls are made by the trampoline rather than a new call, the hook still sees each of them
+func TestOnCallSeesTailCalls(t *testing.T) {
+	var calls []string
+
+	in := New(Options{
+		Hooks: Hooks{
+			OnCall: func(fn object.Object, args []object.Object) {
+				calls = append(calls, string(fn.Type())+"/"+args[0].Inspect())
+			},
+		},
+	})
+
+	input := `
+let countdown = fn(n) { if (n == 0) { return 0; } countdown(n - 1) };
+countdown(4);
+struct P { x }
+impl P { fn get(self) { self.x } }
+P(7).get();`
+	in.Run(parse(t, input))
+
+	expected := []string{
+		"FUNCTION/4", "FUNCTION/3", "FUNCTION/2", "FUNCTION/1", "FUNCTION/0",
+		"STRUCT/7", "FUNCTION/P{x: 7}",
+	}
+	if strings.Join(calls, " ") != strings.Join(expected, " ") {
+		t.Errorf("OnCall hook calls wrong. expected=%v, got=%v", expected, calls)
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	in := New(Options{})
+	in.RegisterBuiltin("answer", func(args ...object.Object) object.Object {
+		return &object.Integer{Value: 42}
+	})
+
+	testIntegerObject(t, in.Run(parse(t, "answer()")), 42)
+
+	// only this interpreter has it
+	testErrorObject(t, New(Options{}).Run(parse(t, "answer()")), "identifier not found: answer")
+
+	// scripts can still shadow it
+	testIntegerObject(t, in.Run(parse(t, "let answer = fn() { 1 }; answer()")), 1)
+}
+
+func TestRegisterMethod(t *testing.T) {
+	in := New(Options{})
+	in.RegisterMethod(object.STRING_OBJ, "shout", func(args ...object.Object) object.Object {
+		return &object.String{Value: args[0].(*object.String).Value + "!"}
+	})
+	in.RegisterMethod("VECTOR", "sum", func(args ...object.Object) object.Object {
+		v := args[0].(*vector)
+		return &object.Integer{Value: v.x + v.y}
+	})
+	in.Globals().Set("v", &vector{x: 1, y: 2})
+
+	if got := in.Run(parse(t, `"hi".shout() + v.sum()`)); got.Inspect() != "ERROR type mismatch: STRING + INTEGER" {
+		t.Errorf("wrong result. got=%s", got.Inspect())
+	}
+
+	if got := in.Run(parse(t, `"hi".shout()`)).Inspect(); got != "hi!" {
+		t.Errorf("wrong result. got=%s", got)
+	}
+
+	testIntegerObject(t, in.Run(parse(t, "v.sum()")), 3)
+
+	// methods are per interpreter
+	if got := New(Options{}).Run(parse(t, `"hi".shout()`)).Inspect(); got != "ERROR STRING has no attribute shout" {
+		t.Errorf("method leaked into another interpreter. got=%s", got)
+	}
+}
+
+func TestRegisterFunc(t *testing.T) {
+	in := New(Options{})
+
+	err := in.RegisterFunc("repeat", func(s string, n int) (string, error) {
+		if n < 0 {
+			return "", fmt.Errorf("cannot repeat %d times", n)
+		}
+		return strings.Repeat(s, n), nil
+	})
+	if err != nil {
+		t.Fatalf("RegisterFunc returned error: %s", err)
+	}
+
+	result := in.Run(parse(t, `repeat("ab", 2)`))
+	if str, ok := result.(*object.String); !ok || str.Value != "abab" {
+		t.Errorf("repeat returned wrong value. got=%T (%+v)", result, result)
+	}
+
+	testErrorObject(t, in.Run(parse(t, `repeat("ab", -1)`)), "cannot repeat -1 times")
+	testErrorObject(t, in.Run(parse(t, `repeat(2, 2)`)), "argument 1 to \"repeat\" must be STRING.\ngot INTEGER")
+
+	if err := in.RegisterFunc("bad", "not a function"); err == nil {
+		t.Errorf("RegisterFunc accepted a string")
+	}
+}
+
+func TestInterpreterConvertsFunctions(t *testing.T) {
+	in := New(Options{})
+
+	var add func(a, b int) int
+	if err := in.ToGo(in.Run(parse(t, `fn(a, b) { a + b }`)), &add); err != nil {
+		t.Fatalf("ToGo returned error: %s", err)
+	}
+
+	if got := add(2, 3); got != 5 {
+		t.Errorf("add(2, 3) = %d", got)
+	}
+
+	var fail func() (string, error)
+	if err := in.ToGo(in.Run(parse(t, `fn() { 1 + true }`)), &fail); err != nil {
+		t.Fatalf("ToGo returned error: %s", err)
+	}
+
+	if _, err := fail(); err == nil || err.Error() != "type mismatch: INTEGER + BOOLEAN" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+
+	err := in.RegisterFunc("mapInts", func(xs []int, f func(int) int) []int {
+		for i, x := range xs {
+			xs[i] = f(x)
+		}
+		return xs
+	})
+	if err != nil {
+		t.Fatalf("RegisterFunc returned error: %s", err)
+	}
+
+	testArrayObject(t, in.Run(parse(t, `mapInts([1, 2, 3], fn(x) { x * 10 })`)), []int64{10, 20, 30})
+
+	payload, err := in.FromGo(map[string]interface{}{"items": []int{1, 2, 3}})
+	if err != nil {
+		t.Fatalf("FromGo returned error: %s", err)
+	}
+
+	in.Globals().Set("payload", payload)
+	testIntegerObject(t, in.Run(parse(t, `len(payload["items"])`)), 3)
+}
+
+// A host type implementing every extension interface: a 2D vector with x and y attributes
+type vector struct {
+	x, y int64
+}
+
+func (v *vector) Type() object.ObjectType { return "VECTOR" }
+func (v *vector) Inspect() string         { return fmt.Sprintf("vector(%d, %d)", v.x, v.y) }
+
+func (v *vector) GetAttr(name string) (object.Object, bool) {
+	switch name {
+	case "x":
+		return &object.Integer{Value: v.x}, true
+	case "y":
+		return &object.Integer{Value: v.y}, true
+	case "scale":
+		return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			factor := args[0].(*object.Integer).Value
+			return &vector{x: v.x * factor, y: v.y * factor}
+		}}, true
+	}
+	return nil, false
+}
+
+func (v *vector) SetAttr(name string, value object.Object) error {
+	integer, ok := value.(*object.Integer)
+	if !ok {
+		return fmt.Errorf("vector.%s must be INTEGER, got %s", name, value.Type())
+	}
+
+	switch name {
+	case "x":
+		v.x = integer.Value
+	case "y":
+		v.y = integer.Value
+	default:
+		return fmt.Errorf("vector has no attribute %s", name)
+	}
+	return nil
+}
+
+func (v *vector) Index(index object.Object) object.Object {
+	if i, ok := index.(*object.Integer); ok && (i.Value == 0 || i.Value == 1) {
+		return []object.Object{&object.Integer{Value: v.x}, &object.Integer{Value: v.y}}[i.Value]
+	}
+	return &object.Error{Message: "vector index out of range"}
+}
+
+func (v *vector) Iterate() object.Iterator {
+	obj, _ := object.Iterate(&object.Array{Elements: []object.Object{&object.Integer{Value: v.x}, &object.Integer{Value: v.y}}})
+	return obj
+}
+
+func (v *vector) Call(args ...object.Object) object.Object {
+	return &object.Integer{Value: v.x*v.x + v.y*v.y}
+}
+
+func (v *vector) BinaryOp(operator string, other object.Object, reflected bool) (object.Object, bool) {
+	switch other := other.(type) {
+	case *vector:
+		if operator == "+" {
+			return &vector{x: v.x + other.x, y: v.y + other.y}, true
+		}
+	case *object.Integer:
+		if operator == "*" {
+			return &vector{x: v.x * other.Value, y: v.y * other.Value}, true
+		}
+		if operator == "-" && !reflected {
+			return &vector{x: v.x - other.Value, y: v.y - other.Value}, true
+		}
+	}
+	return nil, false
+}
+
+func TestHostObjects(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{"v.x + v.y", "3"},
+		{"v.scale(10)", "vector(10, 20)"},
+		{"v.x = 5; v", "vector(5, 2)"},
+		{"v[0] * 100 + v[1]", "102"},
+		{"let sum = 0; for (c in v) { sum = sum + c; } sum", "3"},
+		{"reduce(v, fn(sum, c) { sum + c }, 0)", "3"},
+		{"v()", "5"},
+		{"v + v", "vector(2, 4)"},
+		{"v * 3", "vector(3, 6)"},
+		{"3 * v", "vector(3, 6)"},
+		{"v - 1", "vector(0, 1)"},
+		{"(v + v).scale(2).y", "8"},
+		{"v == v", "true"},
+
+		{"v.z", "ERROR VECTOR has no attribute z"},
+		{`v.x = "a"`, "ERROR vector.x must be INTEGER, got STRING"},
+		{"v[2]", "ERROR vector index out of range"},
+		{"1 - v", "ERROR type mismatch: INTEGER - VECTOR"},
+		{"v / 2", "ERROR type mismatch: VECTOR / INTEGER"},
+		{"let a = [1]; a.length", "ERROR ARRAY has no attribute length"},
+		{"let a = [1]; a.length = 2", "ERROR cannot set attribute length on ARRAY"},
+	}
+
+	for _, tc := range tests {
+		in := New(Options{})
+		in.Globals().Set("v", &vector{x: 1, y: 2})
+
+		if got := in.Run(parse(t, tc.input)).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	return program
+}
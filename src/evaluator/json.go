@@ -0,0 +1,263 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"monkey/object"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+The members of `import "json" as json;`.
+
+parse keeps numbers as they are written: ones with a fraction or an exponent become FLOAT, the rest INTEGER
+unless they are too big for one. stringify writes floats so they read back as floats, 1.0 rather than 1, and
+object keys in sorted order so the same value always gives the same text.
+*/
+func (in *Interpreter) jsonModule() map[string]object.Object {
+	return map[string]object.Object{
+		"parse": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArgumentCount(1, 1, len(args))
+			}
+
+			source, err := stringArgument("parse", args[0])
+			if err != nil {
+				return err
+			}
+
+			return parseJSON(source)
+		}},
+		// stringify(value, indent), indent is a number of spaces or the string to indent with
+		"stringify": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return wrongArgumentCount(1, 2, len(args))
+			}
+
+			indent := ""
+			if len(args) == 2 {
+				switch arg := args[1].(type) {
+				case *object.Integer:
+					indent = strings.Repeat(" ", int(max(arg.Value, 0)))
+				case *object.String:
+					indent = arg.Value
+				default:
+					return newError("argument to \"stringify\" must be INTEGER or STRING.\ngot %s", args[1].Type())
+				}
+			}
+
+			return stringifyJSON(args[0], indent)
+		}},
+	}
+}
+
+func parseJSON(source string) object.Object {
+	decoder := json.NewDecoder(strings.NewReader(source))
+	decoder.UseNumber()
+
+	value, err := decodeJSON(decoder)
+	if err == nil {
+		// nothing but white space may follow the value
+		if _, err = decoder.Token(); err == io.EOF {
+			return value
+		} else if err == nil {
+			err = errors.New("unexpected data after the value")
+		}
+	}
+
+	if err == io.EOF {
+		err = errors.New("unexpected end of JSON input")
+	}
+
+	return newError("invalid JSON: %s", err)
+}
+
+// Decodes the value starting at the next token. Going token by token keeps the keys of objects in order
+func decodeJSON(decoder *json.Decoder) (object.Object, error) {
+	tok, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			elements := []object.Object{}
+			for decoder.More() {
+				element, err := decodeJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+
+				elements = append(elements, element)
+			}
+
+			_, err := decoder.Token()
+			return &object.Array{Elements: elements}, err
+		}
+
+		hash := object.NewHash()
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+
+			hash.Set(&object.String{Value: key.(string)}, value)
+		}
+
+		_, err := decoder.Token()
+		return hash, err
+
+	case json.Number:
+		return jsonNumber(tok)
+
+	case string:
+		return &object.String{Value: tok}, nil
+
+	case bool:
+		return nativeToBooleanObject(tok), nil
+
+	default:
+		return NULL, nil
+	}
+}
+
+func jsonNumber(number json.Number) (object.Object, error) {
+	if !strings.ContainsAny(string(number), ".eE") {
+		if value, err := number.Int64(); err == nil {
+			return &object.Integer{Value: value}, nil
+		}
+	}
+
+	value, err := number.Float64()
+	if err != nil {
+		return nil, err
+	}
+
+	return &object.Float{Value: value}, nil
+}
+
+func stringifyJSON(value object.Object, indent string) object.Object {
+	encoder := &jsonEncoder{visiting: make(map[object.Object]bool)}
+	if err := encoder.encode(value); err != nil {
+		return err
+	}
+
+	if indent == "" {
+		return &object.String{Value: encoder.out.String()}
+	}
+
+	var indented bytes.Buffer
+	json.Indent(&indented, encoder.out.Bytes(), "", indent)
+	return &object.String{Value: indented.String()}
+}
+
+type jsonEncoder struct {
+	out bytes.Buffer
+
+	// the arrays, hashes and instances value is inside of, seeing one again means it contains itself
+	visiting map[object.Object]bool
+}
+
+// Struct instances are written as objects of their fields, in declaration order
+func (e *jsonEncoder) encode(value object.Object) object.Object {
+	switch value := value.(type) {
+	case *object.Null:
+		e.out.WriteString("null")
+
+	case *object.Boolean:
+		e.out.WriteString(strconv.FormatBool(value.Value))
+
+	case *object.Integer:
+		e.out.WriteString(strconv.FormatInt(value.Value, 10))
+
+	case *object.Float:
+		if math.IsNaN(value.Value) || math.IsInf(value.Value, 0) {
+			return newError("cannot encode %s as JSON", value.Inspect())
+		}
+		e.out.WriteString(object.FormatFloat(value.Value))
+
+	case *object.String:
+		e.writeString(value.Value)
+
+	case *object.Array:
+		return e.container(value, '[', ']', len(value.Elements), func(i int) object.Object {
+			return e.encode(value.Elements[i])
+		})
+
+	case *object.Hash:
+		pairs := make([]object.HashPair, 0, value.Len())
+		keys := make(map[object.Object]string, value.Len())
+		for _, pair := range value.Pairs() {
+			pairs = append(pairs, pair)
+			keys[pair.Key] = stringValue(pair.Key)
+		}
+
+		sort.Slice(pairs, func(i, j int) bool { return keys[pairs[i].Key] < keys[pairs[j].Key] })
+
+		return e.container(value, '{', '}', len(pairs), func(i int) object.Object {
+			return e.member(keys[pairs[i].Key], pairs[i].Value)
+		})
+
+	case *object.Instance:
+		return e.container(value, '{', '}', len(value.Fields), func(i int) object.Object {
+			return e.member(value.Struct.Fields[i], value.Fields[i])
+		})
+
+	default:
+		return newError("cannot encode %s as JSON", value.Type())
+	}
+
+	return nil
+}
+
+// Writes the elements of a container between open and close, failing if the container is already being written
+func (e *jsonEncoder) container(value object.Object, open, close byte, length int, element func(int) object.Object) object.Object {
+	if e.visiting[value] {
+		return newError("cannot encode %s containing itself as JSON", value.Type())
+	}
+
+	e.visiting[value] = true
+	defer delete(e.visiting, value)
+
+	e.out.WriteByte(open)
+	for i := 0; i < length; i++ {
+		if i > 0 {
+			e.out.WriteByte(',')
+		}
+
+		if err := element(i); err != nil {
+			return err
+		}
+	}
+	e.out.WriteByte(close)
+
+	return nil
+}
+
+func (e *jsonEncoder) member(key string, value object.Object) object.Object {
+	e.writeString(key)
+	e.out.WriteByte(':')
+	return e.encode(value)
+}
+
+// Quotes s without the HTML escaping json.Marshal does, "<" stays "<"
+func (e *jsonEncoder) writeString(s string) {
+	var quoted bytes.Buffer
+	encoder := json.NewEncoder(&quoted)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+
+	e.out.Write(bytes.TrimSuffix(quoted.Bytes(), []byte("\n")))
+}
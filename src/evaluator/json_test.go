@@ -0,0 +1,171 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+)
+
+func TestJSONParse(t *testing.T) {
+	hash := func(pairs ...object.Object) *object.Hash {
+		h := object.NewHash()
+		for i := 0; i < len(pairs); i += 2 {
+			h.Set(pairs[i], pairs[i+1])
+		}
+		return h
+	}
+	str := func(s string) object.Object { return &object.String{Value: s} }
+	integer := func(i int64) object.Object { return &object.Integer{Value: i} }
+	float := func(f float64) object.Object { return &object.Float{Value: f} }
+	array := func(elements ...object.Object) object.Object { return &object.Array{Elements: elements} }
+
+	tests := []struct {
+		input    string
+		expected object.Object
+	}{
+		{`1`, integer(1)},
+		{`-12`, integer(-12)},
+		{`1.0`, float(1)},
+		{`1e3`, float(1000)},
+		{`2.5E-1`, float(0.25)},
+		// too big for an INTEGER
+		{`12345678901234567890`, float(12345678901234567890)},
+		{`"a \"b\" é \n"`, str("a \"b\" é \n")},
+		{`true`, TRUE},
+		{`null`, NULL},
+		{` [1, 2.0, "x", [], {}] `, array(integer(1), float(2), str("x"), array(), hash())},
+		{`{"a": {"b": [true, null]}, "n": 3}`,
+			hash(str("a"), hash(str("b"), array(TRUE, NULL)), str("n"), integer(3))},
+		// the last of repeated keys wins
+		{`{"a": 1, "a": 2}`, hash(str("a"), integer(2))},
+	}
+
+	for _, tc := range tests {
+		got := parseJSON(tc.input)
+		if !object.Equal(got, tc.expected) {
+			t.Errorf("parse(%s): expected=%s, got=%s", tc.input, tc.expected.Inspect(), got.Inspect())
+			continue
+		}
+
+		// Equal considers 1 and 1.0 the same, the types must match as well
+		if got.Type() != tc.expected.Type() {
+			t.Errorf("parse(%s): expected a %s, got a %s", tc.input, tc.expected.Type(), got.Type())
+		}
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{``, "invalid JSON: unexpected end of JSON input"},
+		{`[1, 2`, "invalid JSON: unexpected end of JSON input"},
+		{`{"a" 1}`, "invalid JSON: invalid character '1' after object key"},
+		{`]`, "invalid JSON: invalid character ']' looking for beginning of value"},
+		{`1 2`, "invalid JSON: unexpected data after the value"},
+		{`[1,]`, "invalid JSON: invalid character ',' looking for beginning of value"},
+	}
+
+	for _, tc := range errorTests {
+		testErrorObject(t, parseJSON(tc.input), tc.expected)
+	}
+}
+
+func TestJSONStringify(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{`let null = if (false) { 1 }; json.stringify({"b": [1, 2.0, null], "a": "x<y", "c": {"z": true, "y": false}})`,
+			`{"a":"x<y","b":[1,2.0,null],"c":{"y":false,"z":true}}`},
+		{`json.stringify("say \"hi\"\n")`, `"say \"hi\"\n"`},
+		{`json.stringify(100000000000000000000.0)`, "1e+20"},
+		{`json.stringify([])`, "[]"},
+		{`json.stringify({1: "one"})`, `{"1":"one"}`},
+		{`struct P { x, y }; json.stringify(P(1, [2]))`, `{"x":1,"y":[2]}`},
+		{`json.stringify({"a": [1, 2]}, 2)`, "{\n  \"a\": [\n    1,\n    2\n  ]\n}"},
+		{`json.stringify([1], "\t")`, "[\n\t1\n]"},
+		// the same array twice isn't a cycle
+		{`let xs = [1]; json.stringify([xs, xs])`, "[[1],[1]]"},
+
+		{`json.stringify(fn(x) { x })`, "ERROR cannot encode FUNCTION as JSON"},
+		{`json.stringify({"f": len})`, "ERROR cannot encode BUILTIN as JSON"},
+		{`import "math" as math; json.stringify(math.NaN)`, "ERROR cannot encode NaN as JSON"},
+		{`let xs = [1]; push(xs, xs); json.stringify(xs)`, "ERROR cannot encode ARRAY containing itself as JSON"},
+		{`struct Node { next }; let n = Node(1); n.next = {"n": n}; json.stringify(n)`,
+			"ERROR cannot encode INSTANCE containing itself as JSON"},
+		{`json.stringify(1, true)`, "ERROR argument to \"stringify\" must be INTEGER or STRING.\ngot BOOLEAN"},
+		{`json.parse(1)`, "ERROR argument to \"parse\" must be STRING.\ngot INTEGER"},
+	}
+
+	for _, tc := range tests {
+		in := New(Options{})
+		input := `import "json" as json; ` + tc.input
+
+		if got := in.Run(parse(t, input)).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+// Whatever stringify writes, parse reads back as an equal value of the same types
+func TestJSONRoundTrip(t *testing.T) {
+	inputs := []string{
+		`{"name": "ann", "tags": ["a", "b"], "score": 9.5, "count": 3, "ok": true, "none": if (false) { 1 }}`,
+		`[1, 1.0, -0.5, 0.0000001, 123456789012, "", "é ${1}", [[[]]], {}]`,
+		`{"nested": {"deeper": {"list": [1, {"x": 2.0}]}}}`,
+	}
+
+	for _, input := range inputs {
+		in := New(Options{})
+
+		original := in.Run(parse(t, input))
+		encoded := stringifyJSON(original, "")
+		if isError(encoded) {
+			t.Fatalf("stringify(%s) failed: %s", input, encoded.Inspect())
+		}
+
+		decoded := parseJSON(encoded.(*object.String).Value)
+		if !sameJSONValue(original, decoded) {
+			t.Errorf("round trip of %s gave %s", input, decoded.Inspect())
+		}
+
+		// and the text is stable
+		if again := stringifyJSON(decoded, ""); again.Inspect() != encoded.Inspect() {
+			t.Errorf("stringify not stable. first=%s, second=%s", encoded.Inspect(), again.Inspect())
+		}
+	}
+}
+
+// Like object.Equal, except that an INTEGER and a FLOAT are never the same
+func sameJSONValue(a, b object.Object) bool {
+	if a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *object.Array:
+		b := b.(*object.Array)
+		if len(a.Elements) != len(b.Elements) {
+			return false
+		}
+
+		for i := range a.Elements {
+			if !sameJSONValue(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+
+	case *object.Hash:
+		b := b.(*object.Hash)
+		if a.Len() != b.Len() {
+			return false
+		}
+
+		for _, pair := range a.Pairs() {
+			other, ok := b.Get(pair.Key.(object.Hashable))
+			if !ok || !sameJSONValue(pair.Value, other) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return object.Equal(a, b)
+}
@@ -0,0 +1,45 @@
+package evaluator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"monkey/object"
+	"monkey/token"
+)
+
+// How deep calls may nest when Options.MaxDepth is left at zero. Deep enough for any sensible recursion that
+// isn't in tail position, shallow enough that runaway recursion errors long before the Go stack runs out
+const DefaultMaxDepth = 10000
+
+// How often, in evaluated nodes, the context is checked for cancellation
+const contextCheckInterval = 1024
+
+// Counts one more evaluated node, returning an error once the step limit is used up or the context is done
+func (in *Interpreter) step() *object.Error {
+	in.steps++
+
+	if in.maxSteps > 0 && in.steps > in.maxSteps {
+		return newError("maximum evaluation steps %d exceeded at %s", in.maxSteps, position(in.position))
+	}
+
+	if in.context != nil && in.steps%contextCheckInterval == 0 {
+		if err := in.context.Err(); err != nil {
+			return contextError(err, in.position)
+		}
+	}
+
+	return nil
+}
+
+func contextError(err error, at token.Token) *object.Error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return newError("evaluation deadline exceeded at %s", position(at))
+	}
+
+	return newError("evaluation cancelled at %s", position(at))
+}
+
+func position(tok token.Token) string {
+	return fmt.Sprintf("line %d, column %d", tok.Line, tok.Column)
+}
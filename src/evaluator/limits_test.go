@@ -0,0 +1,133 @@
+package evaluator
+
+import (
+	"context"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxDepth(t *testing.T) {
+	input := `
+let down = fn(n) {
+  1 + down(n + 1);
+};
+down(0);`
+
+	evaluated := testEvalWithOptions(input, Options{MaxDepth: 100})
+	testErrorObject(t, evaluated, "maximum recursion depth 100 exceeded at line 3, column 11")
+}
+
+func TestDefaultMaxDepth(t *testing.T) {
+	input := "let down = fn(n) { 1 + down(n + 1) }; down(0);"
+
+	evaluated := testEval(input)
+	testErrorObject(t, evaluated, "maximum recursion depth 10000 exceeded at line 1, column 28")
+
+	// right up to the limit is fine
+	input = "let down = fn(n) { if (n == 1) { 0 } else { 1 + down(n - 1) } }; down(10000);"
+	testIntegerObject(t, testEval(input), 9999)
+}
+
+func TestMaxDepthIgnoresTailCalls(t *testing.T) {
+	input := "let down = fn(n) { if (n == 0) { 0 } else { down(n - 1) } }; down(1000);"
+
+	testIntegerObject(t, testEvalWithOptions(input, Options{MaxDepth: 10}), 0)
+}
+
+func TestMaxDepthCanBeDisabled(t *testing.T) {
+	input := "let down = fn(n) { if (n == 0) { 0 } else { 1 + down(n - 1) } }; down(20000);"
+
+	testIntegerObject(t, testEvalWithOptions(input, Options{MaxDepth: -1}), 20000)
+}
+
+func TestMaxSteps(t *testing.T) {
+	input := `
+let forever = fn() {
+  forever();
+};
+forever();`
+
+	evaluated := testEvalWithOptions(input, Options{MaxSteps: 5000})
+	testErrorObject(t, evaluated, "maximum evaluation steps 5000 exceeded at line 3, column 3")
+
+	testIntegerObject(t, testEvalWithOptions("1 + 2", Options{MaxSteps: 5000}), 3)
+
+	// running out while the methods of an impl are evaluated is an error too, not a struct missing a method
+	impl := "struct P { x }\nimpl P {\n  fn a(self) { 1 }\n  fn b(self) { 2 }\n}"
+	testErrorObject(t, testEvalWithOptions(impl, Options{MaxSteps: 4}), "maximum evaluation steps 4 exceeded at line 2, column 1")
+}
+
+// Calls with the wrong number of arguments are errors the script sees rather than panics in the host
+func TestArityMismatch(t *testing.T) {
+	tests := []ExpectedTest[string]{
+		{"let f = fn(a, b) { a }; f(1)", "wrong number of arguments: want=2, got=1"},
+		{"let f = fn() { 1 }; f(1, 2)", "wrong number of arguments: want=0, got=2"},
+		// a call in tail position is checked as well
+		{"let g = fn(a, b) { a }; let f = fn() { g(1) }; f()", "wrong number of arguments: want=2, got=1"},
+		{"let gen = fn*(a) { yield a }; gen()", "wrong number of arguments: want=1, got=0"},
+		{"struct P { x }; impl P { fn get(self, y) { y } }; P(1).get()", "wrong number of arguments: want=2, got=1"},
+	}
+
+	for _, tc := range tests {
+		testErrorObject(t, testEvalWithOptions(tc.input, Options{MaxSteps: 5000}), tc.expected)
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	evaluated := testEvalWithOptions("let forever = fn() { forever() }; forever();", Options{Context: ctx})
+	testErrorPrefix(t, evaluated, "evaluation deadline exceeded at line 1, column ")
+}
+
+func TestContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	evaluated := testEvalWithOptions("let forever = fn() { forever() }; forever();", Options{Context: ctx})
+	testErrorPrefix(t, evaluated, "evaluation cancelled at line 1, column ")
+}
+
+func testEvalWithOptions(input string, options Options) object.Object {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+
+	return EvalWithOptions(program, env, options)
+}
+
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("object is not Error. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+		return false
+	}
+
+	return true
+}
+
+func testErrorPrefix(t *testing.T, obj object.Object, prefix string) bool {
+	errObj, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("object is not Error. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if !strings.HasPrefix(errObj.Message, prefix) {
+		t.Errorf("wrong error message. expected prefix %q, got=%q", prefix, errObj.Message)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,216 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+/*
+A ModuleLoader finds the modules scripts import. Resolve turns the path an import statement names into the id
+of a module, and Load returns the source of the module with that id. Ids are only compared and handed back to
+the loader, each loader decides what they look like, e.g. absolute file names for DirLoader.
+
+Every module is evaluated once per Interpreter: imports that resolve to the same id share the module.
+*/
+type ModuleLoader interface {
+	// from is the id of the importing module, "" when the import isn't made by a module the loader loaded.
+	// Returns a *ModuleNotFoundError when there is no such module
+	Resolve(name string, from string) (id string, err error)
+
+	Load(id string) (source []byte, err error)
+}
+
+type ModuleNotFoundError struct {
+	Name   string
+	Looked []string // where the loader looked for it
+}
+
+func (e *ModuleNotFoundError) Error() string {
+	return fmt.Sprintf("module %q not found, looked for %s", e.Name, strings.Join(e.Looked, ", "))
+}
+
+/*
+The places a module imported as name from the module from is looked for, as slash separated paths: next to from
+first, then in each of dirs. ModuleExtension is added to names without an extension.
+*/
+func candidates(name string, from string, dirs []string) []string {
+	if path.Ext(name) == "" {
+		name += ModuleExtension
+	}
+
+	if path.IsAbs(name) {
+		return []string{name}
+	}
+
+	found := []string{path.Join(path.Dir(from), name)}
+	for _, dir := range dirs {
+		found = append(found, path.Join(dir, name))
+	}
+
+	return found
+}
+
+// Loads modules from the operating system's file system. Imports are relative to the importing file, or the
+// working directory outside a module, then to each of Dirs. Ids are absolute file names
+type DirLoader struct {
+	Dirs []string
+}
+
+func NewDirLoader(dirs ...string) *DirLoader {
+	return &DirLoader{Dirs: dirs}
+}
+
+func (l *DirLoader) Resolve(name string, from string) (string, error) {
+	dirs := make([]string, len(l.Dirs))
+	for i, dir := range l.Dirs {
+		dirs[i] = filepath.ToSlash(dir)
+	}
+
+	looked := candidates(filepath.ToSlash(name), filepath.ToSlash(from), dirs)
+	for i, candidate := range looked {
+		looked[i] = filepath.FromSlash(candidate)
+
+		if info, err := os.Stat(looked[i]); err == nil && info.Mode().IsRegular() {
+			return absPath(looked[i]), nil
+		}
+	}
+
+	return "", &ModuleNotFoundError{Name: name, Looked: looked}
+}
+
+func (l *DirLoader) Load(id string) ([]byte, error) {
+	return os.ReadFile(id)
+}
+
+// Identifies a file however it was reached, falling back to the cleaned path if there is no working directory
+func absPath(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.Clean(file)
+	}
+
+	return abs
+}
+
+/*
+Loads modules from an fs.FS, e.g. an embed.FS to ship modules inside the binary. Imports are relative to the
+importing module, or the root of the file system outside a module, then to each of Dirs within it. Ids are
+paths in the file system.
+*/
+type FSLoader struct {
+	FS   fs.FS
+	Dirs []string
+}
+
+func NewFSLoader(fsys fs.FS, dirs ...string) *FSLoader {
+	return &FSLoader{FS: fsys, Dirs: dirs}
+}
+
+func (l *FSLoader) Resolve(name string, from string) (string, error) {
+	looked := candidates(name, from, l.Dirs)
+	for _, candidate := range looked {
+		if !fs.ValidPath(candidate) {
+			continue
+		}
+
+		if info, err := fs.Stat(l.FS, candidate); err == nil && info.Mode().IsRegular() {
+			return candidate, nil
+		}
+	}
+
+	return "", &ModuleNotFoundError{Name: name, Looked: looked}
+}
+
+func (l *FSLoader) Load(id string) ([]byte, error) {
+	return fs.ReadFile(l.FS, id)
+}
+
+// Modules held in memory, keyed by slash separated path including the extension, e.g. "lib/strings.monkey".
+// Imports are resolved the way FSLoader resolves them, ids are the keys
+type MapLoader map[string]string
+
+func (l MapLoader) Resolve(name string, from string) (string, error) {
+	looked := candidates(name, from, nil)
+	for _, candidate := range looked {
+		if _, ok := l[candidate]; ok {
+			return candidate, nil
+		}
+	}
+
+	return "", &ModuleNotFoundError{Name: name, Looked: looked}
+}
+
+func (l MapLoader) Load(id string) ([]byte, error) {
+	source, ok := l[id]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+
+	return []byte(source), nil
+}
+
+/*
+Tries each of its loaders in turn, the first one that has the module loads it, e.g. a DirLoader for the
+application's scripts followed by an FSLoader with a bundled standard library. Ids of different loaders must
+not clash.
+*/
+type MultiLoader struct {
+	loaders []ModuleLoader
+	owners  map[string]ModuleLoader // which loader resolved each id
+}
+
+func NewMultiLoader(loaders ...ModuleLoader) *MultiLoader {
+	return &MultiLoader{loaders: loaders, owners: make(map[string]ModuleLoader)}
+}
+
+func (l *MultiLoader) Resolve(name string, from string) (string, error) {
+	looked := []string{}
+
+	for _, loader := range l.loaders {
+		id, err := loader.Resolve(name, from)
+
+		var notFound *ModuleNotFoundError
+		if errors.As(err, &notFound) {
+			for _, place := range notFound.Looked {
+				if !contains(looked, place) {
+					looked = append(looked, place)
+				}
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		l.owners[id] = loader
+		return id, nil
+	}
+
+	return "", &ModuleNotFoundError{Name: name, Looked: looked}
+}
+
+func (l *MultiLoader) Load(id string) ([]byte, error) {
+	loader, ok := l.owners[id]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+
+	return loader.Load(id)
+}
+
+func contains(list []string, s string) bool {
+	for _, element := range list {
+		if element == s {
+			return true
+		}
+	}
+
+	return false
+}
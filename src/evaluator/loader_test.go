@@ -0,0 +1,105 @@
+package evaluator
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestModuleLoaders(t *testing.T) {
+	modules := map[string]string{
+		"lib/strings.monkey": `import "./util" as util; export let shout = fn(s) { util.twice(s.upper()) };`,
+		"lib/util.monkey":    `export let twice = fn(s) { s + s };`,
+		"std/math.monkey":    `export let square = fn(x) { x * x };`,
+	}
+
+	fsys := fstest.MapFS{}
+	for name, source := range modules {
+		fsys[name] = &fstest.MapFile{Data: []byte(source)}
+	}
+
+	loaders := map[string]ModuleLoader{
+		"map":   MapLoader(modules),
+		"fs":    NewFSLoader(fsys),
+		"multi": NewMultiLoader(MapLoader{"other.monkey": ""}, NewFSLoader(fsys)),
+	}
+
+	tests := []ExpectedTest[string]{
+		{`import "lib/strings" as str; str.shout("hi")`, "HIHI"},
+		{`import "lib/strings.monkey" as a; import "./lib/strings" as b; a == b`, "true"},
+		{`import "std/math" as m; m.square(3)`, "9"},
+		{`import "maths" as m;`, `ERROR module "maths" not found, looked for maths.monkey`},
+		{`import "../lib/util" as u;`, `ERROR module "../lib/util" not found, looked for ../lib/util.monkey`},
+	}
+
+	for name, loader := range loaders {
+		for _, tc := range tests {
+			in := New(Options{ModuleLoader: loader})
+
+			if got := in.Run(parse(t, tc.input)).Inspect(); got != tc.expected {
+				t.Errorf("%s loader, %s: expected=%q, got=%q", name, tc.input, tc.expected, got)
+			}
+		}
+	}
+}
+
+func TestModuleLoaderSearchDirs(t *testing.T) {
+	fsys := fstest.MapFS{"std/math.monkey": &fstest.MapFile{Data: []byte("export let pi = 3;")}}
+	in := New(Options{ModuleLoader: NewFSLoader(fsys, "std")})
+
+	testIntegerObject(t, in.Run(parse(t, `import "math" as m; m.pi`)), 3)
+
+	// every loader's places show up when none of them has the module
+	loader := NewMultiLoader(NewFSLoader(fsys, "std"), MapLoader{})
+
+	_, err := loader.Resolve("nope", "")
+
+	var notFound *ModuleNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Fatalf("expected a *ModuleNotFoundError. got=%v", err)
+	}
+
+	expected := `module "nope" not found, looked for nope.monkey, std/nope.monkey`
+	if err.Error() != expected {
+		t.Errorf("wrong error. expected=%q, got=%q", expected, err.Error())
+	}
+}
+
+func TestDirLoader(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"app/main.monkey": "",
+		"app/lib.monkey":  "",
+		"std/math.monkey": "",
+	})
+
+	loader := NewDirLoader(filepath.Join(dir, "std"))
+
+	tests := []struct {
+		name     string
+		from     string
+		expected string
+	}{
+		{"lib", filepath.Join(dir, "app", "main.monkey"), filepath.Join(dir, "app", "lib.monkey")},
+		{"./lib.monkey", filepath.Join(dir, "app", "main.monkey"), filepath.Join(dir, "app", "lib.monkey")},
+		{"math", filepath.Join(dir, "app", "main.monkey"), filepath.Join(dir, "std", "math.monkey")},
+		{filepath.Join(dir, "app", "lib"), "", filepath.Join(dir, "app", "lib.monkey")},
+	}
+
+	for _, tc := range tests {
+		id, err := loader.Resolve(tc.name, tc.from)
+		if err != nil {
+			t.Errorf("Resolve(%q, %q) failed: %s", tc.name, tc.from, err)
+			continue
+		}
+
+		if id != tc.expected {
+			t.Errorf("Resolve(%q, %q) wrong. expected=%q, got=%q", tc.name, tc.from, tc.expected, id)
+		}
+	}
+
+	var notFound *ModuleNotFoundError
+	if _, err := loader.Resolve("nope", ""); !errors.As(err, &notFound) {
+		t.Errorf("expected a *ModuleNotFoundError. got=%v", err)
+	}
+}
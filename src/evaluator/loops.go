@@ -0,0 +1,42 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+// The loop variable and any lets in the body live in a fresh environment per iteration, so a closure created
+// in the body keeps the element of its own iteration
+func (in *Interpreter) evalForStatement(node *ast.ForStatement, env *object.Environment) object.Object {
+	iterable := in.eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	iterator, ok := object.Iterate(iterable)
+	if !ok {
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	for {
+		element, ok := iterator.Next()
+		if !ok {
+			return NULL
+		}
+
+		if isError(element) {
+			return element
+		}
+
+		loopEnv := object.NewEnclosedEnvironment(env)
+		loopEnv.Set(node.Variable.Value, element)
+
+		result := in.evalBlockStatement(node.Body, loopEnv)
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
+	}
+}
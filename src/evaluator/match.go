@@ -0,0 +1,111 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+func evalEnumStatement(node *ast.EnumStatement, env *object.Environment) {
+	enum := object.NewEnumType(node.Name.Value)
+
+	for _, variant := range node.Variants {
+		fields := make([]string, len(variant.Fields))
+		for i, field := range variant.Fields {
+			fields[i] = field.Value
+		}
+
+		enum.AddVariant(variant.Name.Value, fields)
+	}
+
+	env.Set(node.Name.Value, enum)
+}
+
+func (in *Interpreter) evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	body, armEnv, result := in.selectMatchArm(node, env)
+	if body == nil {
+		return result
+	}
+
+	return in.eval(body, armEnv)
+}
+
+// Finds the first arm whose pattern matches the subject. The names its pattern binds are set in the returned
+// environment, which the arm's body is evaluated in
+func (in *Interpreter) selectMatchArm(node *ast.MatchExpression, env *object.Environment) (*ast.BlockStatement, *object.Environment, object.Object) {
+	subject := in.eval(node.Subject, env)
+	if isError(subject) {
+		return nil, nil, subject
+	}
+
+	for _, arm := range node.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+
+		matched, err := in.matchPattern(arm.Pattern, subject, armEnv)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if matched {
+			return arm.Body, armEnv, nil
+		}
+	}
+
+	return nil, nil, newError("no match arm for %s", subject.Inspect())
+}
+
+// Reports whether value matches pattern, binding the names the pattern binds in env as it goes
+func (in *Interpreter) matchPattern(pattern ast.Pattern, value object.Object, env *object.Environment) (bool, *object.Error) {
+	switch pattern := pattern.(type) {
+	case *ast.WildcardPattern:
+		return true, nil
+
+	case *ast.BindingPattern:
+		env.Set(pattern.Name.Value, value)
+		return true, nil
+
+	case *ast.LiteralPattern:
+		literal := in.eval(pattern.Value, env)
+		if err, ok := literal.(*object.Error); ok {
+			return false, err
+		}
+
+		return object.Equal(literal, value), nil
+
+	case *ast.VariantPattern:
+		enumObj := in.evalIdentifier(pattern.Enum, env)
+		if err, ok := enumObj.(*object.Error); ok {
+			return false, err
+		}
+
+		enum, ok := enumObj.(*object.EnumType)
+		if !ok {
+			return false, newError("%s is not an enum", pattern.Enum.Value)
+		}
+
+		variantType := enum.Variant(pattern.Variant.Value)
+		if variantType == nil {
+			return false, newError("%s has no variant %s", enum.Name, pattern.Variant.Value)
+		}
+
+		if pattern.Arguments != nil && len(pattern.Arguments) != len(variantType.Fields) {
+			return false, newError("pattern %s has %d fields, %s.%s has %d",
+				pattern.String(), len(pattern.Arguments), enum.Name, variantType.Name, len(variantType.Fields))
+		}
+
+		variant, ok := value.(*object.Variant)
+		if !ok || variant.Variant != variantType {
+			return false, nil
+		}
+
+		for i, argument := range pattern.Arguments {
+			matched, err := in.matchPattern(argument, variant.Values[i], env)
+			if err != nil || !matched {
+				return false, err
+			}
+		}
+
+		return true, nil
+	}
+
+	return false, newError("unknown pattern: %s", pattern.String())
+}
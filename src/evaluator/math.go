@@ -0,0 +1,318 @@
+package evaluator
+
+import (
+	"math"
+	"math/rand"
+	"monkey/object"
+	"sort"
+)
+
+/*
+The members of `import "math" as math;`. Functions of one number return a FLOAT whatever they are given, the
+ones picking a value out of their arguments, min, max, clamp and median, return it as it is.
+
+random, randInt and shuffle draw from the interpreter's Options.Random, and seed replaces it with a source
+seeded with its argument, so a script or test that seeds first always sees the same numbers.
+*/
+func (in *Interpreter) mathModule() map[string]object.Object {
+	return map[string]object.Object{
+		"PI":  &object.Float{Value: math.Pi},
+		"E":   &object.Float{Value: math.E},
+		"INF": &object.Float{Value: math.Inf(1)},
+		"NaN": &object.Float{Value: math.NaN()},
+
+		"sqrt":  mathFunction("sqrt", math.Sqrt),
+		"exp":   mathFunction("exp", math.Exp),
+		"log2":  mathFunction("log2", math.Log2),
+		"log10": mathFunction("log10", math.Log10),
+		"sin":   mathFunction("sin", math.Sin),
+		"cos":   mathFunction("cos", math.Cos),
+		"tan":   mathFunction("tan", math.Tan),
+		"asin":  mathFunction("asin", math.Asin),
+		"acos":  mathFunction("acos", math.Acos),
+		"atan":  mathFunction("atan", math.Atan),
+
+		"abs":   defaultBuiltins["abs"],
+		"floor": defaultBuiltins["floor"],
+		"ceil":  defaultBuiltins["ceil"],
+		"round": defaultBuiltins["round"],
+
+		// log(x) is the natural logarithm, log(x, base) the logarithm to base
+		"log": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return wrongArgumentCount(1, 2, len(args))
+			}
+
+			values, err := numberArguments("log", args)
+			if err != nil {
+				return err
+			}
+
+			if len(values) == 2 {
+				return &object.Float{Value: math.Log(values[0]) / math.Log(values[1])}
+			}
+
+			return &object.Float{Value: math.Log(values[0])}
+		}},
+		"atan2": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return wrongArgumentCount(2, 2, len(args))
+			}
+
+			values, err := numberArguments("atan2", args)
+			if err != nil {
+				return err
+			}
+
+			return &object.Float{Value: math.Atan2(values[0], values[1])}
+		}},
+		// pow(x, y) is x ** y
+		"pow": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return wrongArgumentCount(2, 2, len(args))
+			}
+
+			if _, err := numberArguments("pow", args); err != nil {
+				return err
+			}
+
+			return evalInfixExpression("**", args[0], args[1])
+		}},
+
+		// min(a, b, c) or min(xs), of numbers or of strings
+		"min": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return extreme("min", args, -1)
+		}},
+		"max": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			return extreme("max", args, 1)
+		}},
+		// clamp(x, low, high) is low when x is below it, high when x is above it and x otherwise
+		"clamp": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return wrongArgumentCount(3, 3, len(args))
+			}
+
+			values, err := numberArguments("clamp", args)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case values[1] > values[2]:
+				return newError("clamp bounds are reversed: %s > %s", args[1].Inspect(), args[2].Inspect())
+			case values[0] < values[1]:
+				return args[1]
+			case values[0] > values[2]:
+				return args[2]
+			}
+
+			return args[0]
+		}},
+
+		// sum(xs) is an INTEGER while every element is one, 0 for an empty collection
+		"sum": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			numbers, err := numberCollection("sum", args)
+			if err != nil {
+				return err
+			}
+
+			var total object.Object = &object.Integer{Value: 0}
+			for _, number := range numbers {
+				total = evalInfixExpression("+", total, number)
+			}
+
+			return total
+		}},
+		"mean": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			numbers, err := numberCollection("mean", args)
+			if err != nil {
+				return err
+			}
+
+			if len(numbers) == 0 {
+				return newError("mean of an empty collection")
+			}
+
+			total := 0.0
+			for _, number := range numbers {
+				value, _ := numberValue(number)
+				total += value
+			}
+
+			return &object.Float{Value: total / float64(len(numbers))}
+		}},
+		// the middle element, or the mean of the middle two when there is an even number of them
+		"median": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			numbers, err := numberCollection("median", args)
+			if err != nil {
+				return err
+			}
+
+			if len(numbers) == 0 {
+				return newError("median of an empty collection")
+			}
+
+			sort.SliceStable(numbers, func(i, j int) bool {
+				left, _ := numberValue(numbers[i])
+				right, _ := numberValue(numbers[j])
+				return left < right
+			})
+
+			middle := len(numbers) / 2
+			if len(numbers)%2 == 1 {
+				return numbers[middle]
+			}
+
+			left, _ := numberValue(numbers[middle-1])
+			right, _ := numberValue(numbers[middle])
+			return &object.Float{Value: (left + right) / 2}
+		}},
+
+		// a FLOAT from 0 up to but not including 1
+		"random": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return wrongArgumentCount(0, 0, len(args))
+			}
+
+			return &object.Float{Value: in.random.Float64()}
+		}},
+		// randInt(low, high) is an INTEGER from low up to and including high
+		"randInt": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return wrongArgumentCount(2, 2, len(args))
+			}
+
+			low, err := integerArgument("randInt", args[0])
+			if err != nil {
+				return err
+			}
+
+			high, err := integerArgument("randInt", args[1])
+			if err != nil {
+				return err
+			}
+
+			if low > high {
+				return newError("randInt bounds are reversed: %d > %d", low, high)
+			}
+
+			return &object.Integer{Value: low + in.random.Int63n(high-low+1)}
+		}},
+		// a new array with the elements of xs in random order
+		"shuffle": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArgumentCount(1, 1, len(args))
+			}
+
+			elements, err := in.collectArgument("shuffle", args[0])
+			if err != nil {
+				return err
+			}
+
+			in.random.Shuffle(len(elements), func(i, j int) {
+				elements[i], elements[j] = elements[j], elements[i]
+			})
+
+			return &object.Array{Elements: elements}
+		}},
+		"seed": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArgumentCount(1, 1, len(args))
+			}
+
+			seed, err := integerArgument("seed", args[0])
+			if err != nil {
+				return err
+			}
+
+			in.random = rand.New(rand.NewSource(seed))
+			return NULL
+		}},
+	}
+}
+
+// Wraps fn as a builtin of one number
+func mathFunction(name string, fn func(float64) float64) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return wrongArgumentCount(1, 1, len(args))
+		}
+
+		values, err := numberArguments(name, args)
+		if err != nil {
+			return err
+		}
+
+		return &object.Float{Value: fn(values[0])}
+	}}
+}
+
+func numberArguments(name string, args []object.Object) ([]float64, object.Object) {
+	values := make([]float64, len(args))
+
+	for i, arg := range args {
+		value, ok := numberValue(arg)
+		if !ok {
+			return nil, newError("argument to \"%s\" must be INTEGER or FLOAT.\ngot %s", name, arg.Type())
+		}
+
+		values[i] = value
+	}
+
+	return values, nil
+}
+
+// The numbers in the one collection args should hold
+func numberCollection(name string, args []object.Object) ([]object.Object, object.Object) {
+	if len(args) != 1 {
+		return nil, wrongArgumentCount(1, 1, len(args))
+	}
+
+	elements, ok := iterateCollection(args[0])
+	if !ok {
+		return nil, newError("argument to \"%s\" must be ARRAY or iterable.\ngot %s", name, args[0].Type())
+	}
+
+	numbers, err := collect(elements)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, number := range numbers {
+		if _, ok := numberValue(number); !ok {
+			return nil, newError("elements of the argument to \"%s\" must be INTEGER or FLOAT.\ngot %s", name, number.Type())
+		}
+	}
+
+	return numbers, nil
+}
+
+// The smallest of the arguments for order -1 and the largest for 1, a single collection stands for its elements
+func extreme(name string, args []object.Object, order int) object.Object {
+	if len(args) == 1 {
+		if elements, ok := iterateCollection(args[0]); ok {
+			var err object.Object
+			if args, err = collect(elements); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(args) == 0 {
+		return newError("%s of no values", name)
+	}
+
+	best := args[0]
+	for _, arg := range args[1:] {
+		comparison, err := compareObjects(arg, best)
+		if err != nil {
+			return err
+		}
+
+		if comparison == order {
+			best = arg
+		}
+	}
+
+	return best
+}
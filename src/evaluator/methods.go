@@ -0,0 +1,140 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"unicode/utf8"
+)
+
+/*
+Methods of the builtin types, looked up by receiver type when a script writes "abc".upper() or xs.map(f).
+Each one is an ordinary builtin taking the receiver as its first argument, and it is only ever called with a
+receiver of the type it is registered for.
+
+They are made per Interpreter rather than shared like defaultBuiltins, because the ones taking a function
+call back into the interpreter that runs them.
+*/
+func (in *Interpreter) defaultMethods() map[object.ObjectType]map[string]object.Object {
+	return map[object.ObjectType]map[string]object.Object{
+		object.STRING_OBJ: {
+			"len": method(0, func(receiver object.Object, args []object.Object) object.Object {
+				return &object.Integer{Value: int64(utf8.RuneCountInString(receiver.(*object.String).Value))}
+			}),
+		},
+
+		object.ARRAY_OBJ: {
+			"len": method(0, func(receiver object.Object, args []object.Object) object.Object {
+				return &object.Integer{Value: int64(len(receiver.(*object.Array).Elements))}
+			}),
+			"push": method(1, func(receiver object.Object, args []object.Object) object.Object {
+				arr := receiver.(*object.Array)
+				arr.Elements = append(arr.Elements, args[0])
+				return arr
+			}),
+		},
+
+		object.HASH_OBJ: {
+			"len": method(0, func(receiver object.Object, args []object.Object) object.Object {
+				return &object.Integer{Value: int64(receiver.(*object.Hash).Len())}
+			}),
+			"keys": method(0, func(receiver object.Object, args []object.Object) object.Object {
+				keys := []object.Object{}
+				for _, pair := range receiver.(*object.Hash).Pairs() {
+					keys = append(keys, pair.Key)
+				}
+
+				return &object.Array{Elements: keys}
+			}),
+			"values": method(0, func(receiver object.Object, args []object.Object) object.Object {
+				values := []object.Object{}
+				for _, pair := range receiver.(*object.Hash).Pairs() {
+					values = append(values, pair.Value)
+				}
+
+				return &object.Array{Elements: values}
+			}),
+			"has": method(1, func(receiver object.Object, args []object.Object) object.Object {
+				if !object.IsHashable(args[0]) {
+					return newError("unusable as hash key: %s", args[0].Type())
+				}
+
+				_, ok := receiver.(*object.Hash).Get(args[0].(object.Hashable))
+				return nativeToBooleanObject(ok)
+			}),
+		},
+
+		object.SET_OBJ: in.setMethods(),
+
+		object.TIME_OBJ:     timeMethods(),
+		object.DURATION_OBJ: durationMethods(),
+
+		object.GENERATOR_OBJ: {
+			// null once the generator has finished, use done to tell that apart from a yielded null
+			"next": method(0, func(receiver object.Object, args []object.Object) object.Object {
+				value, ok := receiver.(*object.Generator).Next()
+				if !ok {
+					return NULL
+				}
+
+				return value
+			}),
+			"done": method(0, func(receiver object.Object, args []object.Object) object.Object {
+				return nativeToBooleanObject(receiver.(*object.Generator).Done())
+			}),
+			"close": method(0, func(receiver object.Object, args []object.Object) object.Object {
+				receiver.(*object.Generator).Close()
+				return NULL
+			}),
+		},
+	}
+}
+
+// Wraps fn as a method taking exactly arity arguments besides the receiver
+func method(arity int, fn func(receiver object.Object, args []object.Object) object.Object) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if len(args)-1 != arity {
+			return newError("wrong number of arguments.\nexpected=%d, got=%d", arity, len(args)-1)
+		}
+
+		return fn(args[0], args[1:])
+	}}
+}
+
+// The method called name of obj's type. Methods of a struct come from its impl blocks
+func (in *Interpreter) lookupMethod(obj object.Object, name string) (object.Object, bool) {
+	if instance, ok := obj.(*object.Instance); ok {
+		method, ok := instance.Struct.Methods[name]
+		return method, ok
+	}
+
+	method, ok := in.methods[obj.Type()][name]
+	return method, ok
+}
+
+// Adds the methods of an impl block to the struct it names
+func (in *Interpreter) evalImplStatement(node *ast.ImplStatement, env *object.Environment) object.Object {
+	target := in.evalIdentifier(node.Name, env)
+	if isError(target) {
+		return target
+	}
+
+	structType, ok := target.(*object.StructType)
+	if !ok {
+		return newError("cannot impl %s: not a struct", node.Name.Value)
+	}
+
+	if structType.Methods == nil {
+		structType.Methods = make(map[string]object.Object, len(node.Methods))
+	}
+
+	for _, method := range node.Methods {
+		function := in.eval(method.Function, env)
+		if isError(function) {
+			return function
+		}
+
+		structType.Methods[method.Name.Value] = function
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package evaluator
+
+import (
+	"errors"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+)
+
+// Added to import paths without an extension, `import "lib/strings"` loads lib/strings.monkey
+const ModuleExtension = ".monkey"
+
+/*
+Evaluates program, the source of the module name resolves to, in the global environment. Imports in it are
+resolved relative to that module, and importing it again from one of its imports is reported as a cycle.
+For the default DirLoader name is simply the script's file name.
+*/
+func (in *Interpreter) RunFile(name string, program *ast.Program) object.Object {
+	id, err := in.loader.Resolve(name, "")
+	if err != nil {
+		id = name
+	}
+
+	in.importing = append(in.importing, id)
+	defer func() { in.importing = in.importing[:len(in.importing)-1] }()
+
+	return in.Run(program)
+}
+
+func (in *Interpreter) evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	module := in.importModule(node.Path.Value)
+	if isError(module) {
+		return module
+	}
+
+	env.Set(node.Name.Value, module)
+	return nil
+}
+
+/*
+Loads the module imported as path. Every module is evaluated once per Interpreter, in an environment of its
+own, and later imports of the same module get the same Module. A module that is imported again while it is
+still being evaluated is part of a cycle, which is an error naming every module in it.
+*/
+func (in *Interpreter) importModule(path string) object.Object {
+	from := ""
+	if len(in.importing) > 0 {
+		from = in.importing[len(in.importing)-1]
+	}
+
+	id, err := in.loader.Resolve(path, from)
+
+	var notFound *ModuleNotFoundError
+	if errors.As(err, &notFound) && nativeModules[path] != nil {
+		return in.nativeModule(path)
+	}
+
+	if err != nil {
+		return newError("%s", err)
+	}
+
+	if module, ok := in.modules[id]; ok {
+		return module
+	}
+
+	for i, importing := range in.importing {
+		if importing == id {
+			chain := append(append([]string{}, in.importing[i:]...), id)
+			return newError("import cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	source, err := in.loader.Load(id)
+	if err != nil {
+		return newError("cannot import %q: %s", path, err)
+	}
+
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return newError("parser errors in %s:\n\t%s", id, strings.Join(p.Errors(), "\n\t"))
+	}
+
+	module := &object.Module{Name: path, ID: id, Env: object.NewEnvironment(), Exports: exports(program)}
+
+	in.importing = append(in.importing, id)
+	result := in.eval(program, module.Env)
+	in.importing = in.importing[:len(in.importing)-1]
+
+	if isError(result) {
+		return result
+	}
+
+	in.modules[id] = module
+	return module
+}
+
+/*
+Modules written in Go, imported by name like any other, e.g. `import "math" as math;`. They are only used when
+the ModuleLoader doesn't find a module of that name, so a script's own math.monkey still wins. Each function
+returns the members of the module for the interpreter importing it.
+*/
+var nativeModules = map[string]func(in *Interpreter) map[string]object.Object{
+	"math": (*Interpreter).mathModule,
+	"json": (*Interpreter).jsonModule,
+	"time": (*Interpreter).timeModule,
+}
+
+// Like importModule, every Interpreter makes a native module once and shares it between its imports
+func (in *Interpreter) nativeModule(name string) *object.Module {
+	if module, ok := in.modules[name]; ok {
+		return module
+	}
+
+	module := &object.Module{Name: name, ID: name, Env: object.NewEnvironment()}
+	for member, value := range nativeModules[name](in) {
+		module.Env.Set(member, value)
+		module.Exports = append(module.Exports, member)
+	}
+
+	in.modules[name] = module
+	return module
+}
+
+// The names the top level export statements of program declare
+func exports(program *ast.Program) []string {
+	names := []string{}
+
+	for _, statement := range program.Statements {
+		if export, ok := statement.(*ast.ExportStatement); ok {
+			names = append(names, export.Name().Value)
+		}
+	}
+
+	return names
+}
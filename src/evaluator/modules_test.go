@@ -0,0 +1,109 @@
+package evaluator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Writes files, relative path to source, under a new temporary directory and returns it
+func writeModules(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	for name, source := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestImports(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"lib/strings.monkey": `
+import "../shared/util" as util;
+let calls = 0;
+export let shout = fn(s) { calls = calls + 1; util.twice(s.upper()) };
+export let count = fn() { calls };
+export struct Pair { a, b }
+export enum Answer { Yes, No }
+let hidden = 1;`,
+		"shared/util.monkey":  `export let twice = fn(s) { s + s };`,
+		"search/extra.monkey": `export let value = 42;`,
+	})
+
+	tests := []ExpectedTest[string]{
+		{`import "lib/strings" as str; str.shout("hi")`, "HIHI"},
+		{`import "lib/strings" as str; str`, "module lib/strings"},
+		{`import "lib/strings" as str; str.Pair(1, 2)`, "Pair{a: 1, b: 2}"},
+		{`import "lib/strings" as str; str.Answer.No`, "Answer.No"},
+		// the module is evaluated once, however it is imported
+		{`import "lib/strings" as a; import "./lib/strings.monkey" as b; a.shout("x"); b.shout("y"); a.count()`, "2"},
+		// found through the module path when it isn't next to the importing file
+		{`import "extra" as extra; extra.value`, "42"},
+		// imports inside functions bind locally
+		{`let f = fn() { import "extra" as e; e.value + 1 }; f()`, "43"},
+
+		{`import "lib/strings" as str; str.hidden`, "ERROR module lib/strings has no export hidden"},
+		{`import "lib/strings" as str; calls`, "ERROR identifier not found: calls"},
+	}
+
+	for _, tc := range tests {
+		in := New(Options{ModulePath: []string{filepath.Join(dir, "search")}})
+
+		got := in.RunFile(filepath.Join(dir, "main.monkey"), parse(t, tc.input)).Inspect()
+		if got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"a.monkey":      `import "b" as b; export let x = 1;`,
+		"b.monkey":      `import "c" as c;`,
+		"c.monkey":      `import "a" as a;`,
+		"self.monkey":   `import "self" as me;`,
+		"broken.monkey": `let = 1;`,
+		"fails.monkey":  `export let x = 1 + true;`,
+	})
+
+	join := func(name string) string { return filepath.Join(dir, name) }
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "a" as a;`, "import cycle: " + join("a.monkey") + " -> " + join("b.monkey") + " -> " +
+			join("c.monkey") + " -> " + join("a.monkey")},
+		{`import "self" as s;`, "import cycle: " + join("self.monkey") + " -> " + join("self.monkey")},
+		{`import "main" as m;`, "import cycle: " + join("main.monkey") + " -> " + join("main.monkey")},
+		{`import "nope" as n;`, `module "nope" not found, looked for ` + join("nope.monkey")},
+		{`import "broken" as b;`, "parser errors in " + join("broken.monkey") +
+			":\n\texpected next token to be IDENT, got = instead\n\tno prefix parse function for = found"},
+		{`import "fails" as f;`, "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tc := range tests {
+		if err := os.WriteFile(join("main.monkey"), []byte(tc.input), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		in := New(Options{})
+		testErrorObject(t, in.RunFile(join("main.monkey"), parse(t, tc.input)), tc.expected)
+	}
+
+	// a module that failed isn't cached, importing it again fails the same way
+	in := New(Options{})
+	for i := 0; i < 2; i++ {
+		testErrorObject(t, in.RunFile(join("main.monkey"), parse(t, `import "fails" as f;`)),
+			"type mismatch: INTEGER + BOOLEAN")
+	}
+}
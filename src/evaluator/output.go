@@ -0,0 +1,77 @@
+package evaluator
+
+import (
+	"io"
+	"monkey/object"
+	"strings"
+)
+
+/*
+Builtins scripts print with. They write to the interpreter's Stdout and Stderr, so whatever embeds the
+interpreter decides where output goes:
+
+	puts(a, b)           each argument on a line of its own
+	print(a, b)          the arguments separated by spaces
+	println(a, b)        the same followed by a newline
+	printf(format, a, b) formatted like format(format, a, b)
+	eprint(a, b)         like println, to Stderr
+
+Strings are written as they are and anything else the way Inspect shows it. They all return null.
+*/
+func (in *Interpreter) addOutputFunctions() {
+	in.builtins["puts"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		var out strings.Builder
+		for _, arg := range args {
+			out.WriteString(stringValue(arg) + "\n")
+		}
+
+		return write(in.stdout, out.String())
+	}}
+
+	in.builtins["print"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return write(in.stdout, joinValues(args))
+	}}
+
+	in.builtins["println"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return write(in.stdout, joinValues(args)+"\n")
+	}}
+
+	in.builtins["printf"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if len(args) == 0 {
+			return wrongArgumentCount(1, -1, 0)
+		}
+
+		format, err := stringArgument("printf", args[0])
+		if err != nil {
+			return err
+		}
+
+		formatted := formatString(format, args[1:])
+		if isError(formatted) {
+			return formatted
+		}
+
+		return write(in.stdout, formatted.(*object.String).Value)
+	}}
+
+	in.builtins["eprint"] = &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		return write(in.stderr, joinValues(args)+"\n")
+	}}
+}
+
+func joinValues(args []object.Object) string {
+	values := make([]string, len(args))
+	for i, arg := range args {
+		values[i] = stringValue(arg)
+	}
+
+	return strings.Join(values, " ")
+}
+
+func write(w io.Writer, s string) object.Object {
+	if _, err := io.WriteString(w, s); err != nil {
+		return newError("cannot write output: %s", err)
+	}
+
+	return NULL
+}
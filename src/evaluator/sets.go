@@ -0,0 +1,207 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+	"strings"
+)
+
+func (in *Interpreter) evalSetLiteral(node *ast.SetLiteral, env *object.Environment) object.Object {
+	set := object.NewSet()
+
+	for _, elementNode := range node.Elements {
+		element := in.eval(elementNode, env)
+		if isError(element) {
+			return element
+		}
+
+		if !object.IsHashable(element) {
+			return newError("unusable as set element: %s", element.Type())
+		}
+
+		set.Add(element)
+	}
+
+	return set
+}
+
+// set(xs) is a set of the elements of a collection, set() an empty one
+func newSet(args []object.Object) object.Object {
+	if len(args) > 1 {
+		return wrongArgumentCount(0, 1, len(args))
+	}
+
+	set := object.NewSet()
+	if len(args) == 0 {
+		return set
+	}
+
+	elements, ok := iterateCollection(args[0])
+	if !ok {
+		return newError("argument to \"set\" must be ARRAY or iterable.\ngot %s", args[0].Type())
+	}
+
+	all, err := collect(elements)
+	if err != nil {
+		return err
+	}
+
+	for _, element := range all {
+		if !object.IsHashable(element) {
+			return newError("unusable as set element: %s", element.Type())
+		}
+
+		set.Add(element)
+	}
+
+	return set
+}
+
+/*
+x in xs: whether a set has x as an element, a hash has it as a key, an array or any other collection has an
+element equal to it, or a string has it as a substring.
+*/
+func evalInExpression(element, container object.Object) object.Object {
+	switch container := container.(type) {
+	case *object.Set:
+		if !object.IsHashable(element) {
+			return newError("unusable as set element: %s", element.Type())
+		}
+
+		return nativeToBooleanObject(container.Has(element.(object.Hashable)))
+
+	case *object.Hash:
+		if !object.IsHashable(element) {
+			return newError("unusable as hash key: %s", element.Type())
+		}
+
+		_, ok := container.Get(element.(object.Hashable))
+		return nativeToBooleanObject(ok)
+
+	case *object.String:
+		substr, ok := element.(*object.String)
+		if !ok {
+			return newError("type mismatch: %s in %s", element.Type(), container.Type())
+		}
+
+		return nativeToBooleanObject(strings.Contains(container.Value, substr.Value))
+	}
+
+	elements, ok := iterateCollection(container)
+	if !ok {
+		return newError("unknown operator: %s in %s", element.Type(), container.Type())
+	}
+
+	for {
+		next, ok := elements.Next()
+		if !ok {
+			return FALSE
+		}
+
+		if isError(next) {
+			return next
+		}
+
+		if object.Equal(next, element) {
+			return TRUE
+		}
+	}
+}
+
+/*
+Set algebra with the bit operators: a | b is the union, a & b the intersection, a - b the difference and
+a ^ b the symmetric difference. Each gives a new set and leaves its operands as they were.
+*/
+func evalSetInfixExpression(operator string, left, right object.Object) object.Object {
+	leftSet := left.(*object.Set)
+	rightSet := right.(*object.Set)
+
+	switch operator {
+	case "|":
+		return leftSet.Union(rightSet)
+	case "&":
+		return leftSet.Intersection(rightSet)
+	case "-":
+		return leftSet.Difference(rightSet)
+	case "^":
+		return leftSet.SymmetricDifference(rightSet)
+	case "==":
+		return nativeToBooleanObject(leftSet.Equals(rightSet))
+	case "!=":
+		return nativeToBooleanObject(!leftSet.Equals(rightSet))
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// The methods of sets. add and remove change the set and return it, the others leave it as it was
+func (in *Interpreter) setMethods() map[string]object.Object {
+	// the methods taking another set take any collection, s.union([1, 2]) works like s | #{1, 2}
+	withSet := func(name string, fn func(s, other *object.Set) object.Object) *object.Builtin {
+		return method(1, func(receiver object.Object, args []object.Object) object.Object {
+			other, ok := args[0].(*object.Set)
+			if !ok {
+				if _, ok := iterateCollection(args[0]); !ok {
+					return newError("argument to \"%s\" must be SET or iterable.\ngot %s", name, args[0].Type())
+				}
+
+				converted := newSet(args)
+				if isError(converted) {
+					return converted
+				}
+				other = converted.(*object.Set)
+			}
+
+			return fn(receiver.(*object.Set), other)
+		})
+	}
+
+	return map[string]object.Object{
+		"len": method(0, func(receiver object.Object, args []object.Object) object.Object {
+			return &object.Integer{Value: int64(receiver.(*object.Set).Len())}
+		}),
+		"has": method(1, func(receiver object.Object, args []object.Object) object.Object {
+			return evalInExpression(args[0], receiver)
+		}),
+		"add": method(1, func(receiver object.Object, args []object.Object) object.Object {
+			if !object.IsHashable(args[0]) {
+				return newError("unusable as set element: %s", args[0].Type())
+			}
+
+			set := receiver.(*object.Set)
+			set.Add(args[0])
+			return set
+		}),
+		// removing an element that isn't there is not an error
+		"remove": method(1, func(receiver object.Object, args []object.Object) object.Object {
+			if !object.IsHashable(args[0]) {
+				return newError("unusable as set element: %s", args[0].Type())
+			}
+
+			set := receiver.(*object.Set)
+			set.Remove(args[0].(object.Hashable))
+			return set
+		}),
+		"union": withSet("union", func(s, other *object.Set) object.Object {
+			return s.Union(other)
+		}),
+		"intersection": withSet("intersection", func(s, other *object.Set) object.Object {
+			return s.Intersection(other)
+		}),
+		"difference": withSet("difference", func(s, other *object.Set) object.Object {
+			return s.Difference(other)
+		}),
+		"symmetricDifference": withSet("symmetricDifference", func(s, other *object.Set) object.Object {
+			return s.SymmetricDifference(other)
+		}),
+		"isSubset": withSet("isSubset", func(s, other *object.Set) object.Object {
+			return nativeToBooleanObject(s.IsSubset(other))
+		}),
+		"isSuperset": withSet("isSuperset", func(s, other *object.Set) object.Object {
+			return nativeToBooleanObject(other.IsSubset(s))
+		}),
+		"copy": method(0, func(receiver object.Object, args []object.Object) object.Object {
+			return receiver.(*object.Set).Copy()
+		}),
+	}
+}
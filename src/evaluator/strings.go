@@ -0,0 +1,358 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey/object"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The longest string repeat, padLeft and padRight will build, so a huge count is an error rather than running out of memory
+const maxStringLength = 1 << 28
+
+/*
+A function of a string. Like the collection functions each one is both a builtin taking the string as its first
+argument, upper(s), and a method of strings, s.upper().
+
+Positions, lengths and widths all count characters rather than bytes, so "héllo".indexOf("l") is 2.
+*/
+type stringFunction struct {
+	// how many arguments it takes besides the string, maxArgs is -1 when there is no limit
+	minArgs, maxArgs int
+
+	fn func(s string, args []object.Object) object.Object
+}
+
+var stringFunctions = map[string]stringFunction{
+	// an empty separator splits s into its characters
+	"split": {1, 1, func(s string, args []object.Object) object.Object {
+		sep, err := stringArgument("split", args[0])
+		if err != nil {
+			return err
+		}
+
+		return stringArray(strings.Split(s, sep))
+	}},
+	// trim(s) removes white space from both ends, trim(s, chars) any of chars instead
+	"trim": {0, 1, func(s string, args []object.Object) object.Object {
+		return trimString("trim", s, args, strings.TrimSpace, strings.Trim)
+	}},
+	"trimLeft": {0, 1, func(s string, args []object.Object) object.Object {
+		return trimString("trimLeft", s, args, func(s string) string {
+			return strings.TrimLeftFunc(s, unicode.IsSpace)
+		}, strings.TrimLeft)
+	}},
+	"trimRight": {0, 1, func(s string, args []object.Object) object.Object {
+		return trimString("trimRight", s, args, func(s string) string {
+			return strings.TrimRightFunc(s, unicode.IsSpace)
+		}, strings.TrimRight)
+	}},
+	"upper": {0, 0, func(s string, args []object.Object) object.Object {
+		return &object.String{Value: strings.ToUpper(s)}
+	}},
+	"lower": {0, 0, func(s string, args []object.Object) object.Object {
+		return &object.String{Value: strings.ToLower(s)}
+	}},
+	"contains": {1, 1, func(s string, args []object.Object) object.Object {
+		return testString("contains", s, args[0], strings.Contains)
+	}},
+	"startsWith": {1, 1, func(s string, args []object.Object) object.Object {
+		return testString("startsWith", s, args[0], strings.HasPrefix)
+	}},
+	"endsWith": {1, 1, func(s string, args []object.Object) object.Object {
+		return testString("endsWith", s, args[0], strings.HasSuffix)
+	}},
+	// the position of the first occurrence of substr, -1 when there is none
+	"indexOf": {1, 1, func(s string, args []object.Object) object.Object {
+		substr, err := stringArgument("indexOf", args[0])
+		if err != nil {
+			return err
+		}
+
+		index := strings.Index(s, substr)
+		if index < 0 {
+			return &object.Integer{Value: -1}
+		}
+
+		return &object.Integer{Value: int64(utf8.RuneCountInString(s[:index]))}
+	}},
+	// replaces every occurrence of old
+	"replace": {2, 2, func(s string, args []object.Object) object.Object {
+		old, err := stringArgument("replace", args[0])
+		if err != nil {
+			return err
+		}
+
+		replacement, err := stringArgument("replace", args[1])
+		if err != nil {
+			return err
+		}
+
+		return &object.String{Value: strings.ReplaceAll(s, old, replacement)}
+	}},
+	"repeat": {1, 1, func(s string, args []object.Object) object.Object {
+		count, err := integerArgument("repeat", args[0])
+		if err != nil {
+			return err
+		}
+
+		if count < 0 {
+			return newError("repeat count must not be negative.\ngot %d", count)
+		}
+
+		if s != "" && count > maxStringLength/int64(len(s)) {
+			return newError("repeat would make a string longer than %d bytes.\ngot %d copies of %d bytes", maxStringLength, count, len(s))
+		}
+
+		return &object.String{Value: strings.Repeat(s, int(count))}
+	}},
+	// substr(s, start, length), without length up to the end. A negative start counts from the end
+	"substr": {1, 2, func(s string, args []object.Object) object.Object {
+		start, err := integerArgument("substr", args[0])
+		if err != nil {
+			return err
+		}
+
+		runes := []rune(s)
+		from := clampIndex(start, len(runes))
+		to := int64(len(runes))
+
+		if len(args) == 2 {
+			length, err := integerArgument("substr", args[1])
+			if err != nil {
+				return err
+			}
+
+			if length < 0 {
+				return newError("substr length must not be negative.\ngot %d", length)
+			}
+
+			if length < to-from {
+				to = from + length
+			}
+		}
+
+		return &object.String{Value: string(runes[from:to])}
+	}},
+	"chars": {0, 0, func(s string, args []object.Object) object.Object {
+		return stringArray(strings.Split(s, ""))
+	}},
+	// padLeft(s, width, pad) pads s to width characters, with spaces when pad is left out
+	"padLeft": {1, 2, func(s string, args []object.Object) object.Object {
+		return padString("padLeft", s, args, func(s, padding string) string { return padding + s })
+	}},
+	"padRight": {1, 2, func(s string, args []object.Object) object.Object {
+		return padString("padRight", s, args, func(s, padding string) string { return s + padding })
+	}},
+	// printf-style formatting of args with s as the format, see formatString
+	"format": {0, -1, func(s string, args []object.Object) object.Object {
+		return formatString(s, args)
+	}},
+}
+
+// Makes every string function a builtin, and a method of strings
+func (in *Interpreter) addStringFunctions() {
+	for name, function := range stringFunctions {
+		in.builtins[name] = stringBuiltin(name, function)
+		in.methods[object.STRING_OBJ][name] = stringMethod(function)
+	}
+}
+
+func stringBuiltin(name string, function stringFunction) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if len(args) == 0 || !function.takes(len(args)-1) {
+			// the counts include the string
+			maxArgs := function.maxArgs
+			if maxArgs >= 0 {
+				maxArgs++
+			}
+
+			return wrongArgumentCount(function.minArgs+1, maxArgs, len(args))
+		}
+
+		s, err := stringArgument(name, args[0])
+		if err != nil {
+			return err
+		}
+
+		return function.fn(s, args[1:])
+	}}
+}
+
+func stringMethod(function stringFunction) *object.Builtin {
+	return &object.Builtin{Fn: func(args ...object.Object) object.Object {
+		if !function.takes(len(args) - 1) {
+			return wrongArgumentCount(function.minArgs, function.maxArgs, len(args)-1)
+		}
+
+		return function.fn(args[0].(*object.String).Value, args[1:])
+	}}
+}
+
+func (function stringFunction) takes(count int) bool {
+	return count >= function.minArgs && (function.maxArgs < 0 || count <= function.maxArgs)
+}
+
+func stringArgument(name string, arg object.Object) (string, object.Object) {
+	str, ok := arg.(*object.String)
+	if !ok {
+		return "", newError("argument to \"%s\" must be STRING.\ngot %s", name, arg.Type())
+	}
+
+	return str.Value, nil
+}
+
+func integerArgument(name string, arg object.Object) (int64, object.Object) {
+	integer, ok := arg.(*object.Integer)
+	if !ok {
+		return 0, newError("argument to \"%s\" must be INTEGER.\ngot %s", name, arg.Type())
+	}
+
+	return integer.Value, nil
+}
+
+func stringArray(values []string) *object.Array {
+	elements := make([]object.Object, len(values))
+	for i, value := range values {
+		elements[i] = &object.String{Value: value}
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// What join and the %s and %v verbs make of obj: strings as they are, anything else as it is inspected
+func stringValue(obj object.Object) string {
+	if str, ok := obj.(*object.String); ok {
+		return str.Value
+	}
+
+	return obj.Inspect()
+}
+
+func trimString(name string, s string, args []object.Object, space func(string) string, cutset func(string, string) string) object.Object {
+	if len(args) == 0 {
+		return &object.String{Value: space(s)}
+	}
+
+	chars, err := stringArgument(name, args[0])
+	if err != nil {
+		return err
+	}
+
+	return &object.String{Value: cutset(s, chars)}
+}
+
+func testString(name string, s string, arg object.Object, test func(string, string) bool) object.Object {
+	substr, err := stringArgument(name, arg)
+	if err != nil {
+		return err
+	}
+
+	return nativeToBooleanObject(test(s, substr))
+}
+
+// A pad longer than one character is repeated and cut off where the padding reaches the width
+func padString(name string, s string, args []object.Object, join func(s, padding string) string) object.Object {
+	width, err := integerArgument(name, args[0])
+	if err != nil {
+		return err
+	}
+
+	pad := " "
+	if len(args) == 2 {
+		if pad, err = stringArgument(name, args[1]); err != nil {
+			return err
+		}
+
+		if pad == "" {
+			return newError("pad must not be empty")
+		}
+	}
+
+	if width > maxStringLength {
+		return newError("%s width must be at most %d.\ngot %d", name, maxStringLength, width)
+	}
+
+	missing := int(width) - utf8.RuneCountInString(s)
+	if missing <= 0 {
+		return &object.String{Value: s}
+	}
+
+	// only as many copies of the pad as it takes to cover the missing characters
+	padRunes := utf8.RuneCountInString(pad)
+	padding := []rune(strings.Repeat(pad, (missing+padRunes-1)/padRunes))[:missing]
+	return &object.String{Value: join(s, string(padding))}
+}
+
+/*
+Formats args the way format says, like Go's fmt.Sprintf with fewer verbs: %d for integers, %f for numbers, %s
+and %v for anything and %% for a percent sign. %s and %v show strings without quotes and anything else the way
+the REPL shows it. Flags, width and precision work as they do in Go, e.g. %5.2f or %-8s.
+*/
+func formatString(format string, args []object.Object) object.Object {
+	var out strings.Builder
+	used := 0
+
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			out.WriteByte(format[i])
+			continue
+		}
+
+		// flags, width and precision run up to the verb
+		end := i + 1
+		for end < len(format) && strings.IndexByte("+-# 0123456789.", format[end]) >= 0 {
+			end++
+		}
+
+		if end == len(format) {
+			return newError("format ends in the middle of %q", format[i:])
+		}
+
+		spec, verb := format[i:end], format[end]
+		i = end
+
+		if verb == '%' {
+			out.WriteByte('%')
+			continue
+		}
+
+		if used == len(args) {
+			return newError("not enough arguments for format %q", format)
+		}
+		arg := args[used]
+		used++
+
+		var value interface{}
+		switch verb {
+		case 'd':
+			integer, ok := arg.(*object.Integer)
+			if !ok {
+				return newError("%%d in format needs INTEGER.\ngot %s", arg.Type())
+			}
+			value = integer.Value
+
+		case 'f':
+			number, ok := numberValue(arg)
+			if !ok {
+				return newError("%%f in format needs INTEGER or FLOAT.\ngot %s", arg.Type())
+			}
+			value = number
+
+		case 's', 'v':
+			verb, value = 's', stringValue(arg)
+
+		default:
+			return newError("unknown verb %%%c in format", verb)
+		}
+
+		out.WriteString(fmt.Sprintf(spec+string(verb), value))
+	}
+
+	if used < len(args) {
+		return newError("too many arguments for format %q", format)
+	}
+
+	return &object.String{Value: out.String()}
+}
@@ -0,0 +1,447 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"time"
+
+	// zones are looked up in the tzdata compiled into the binary, not whatever the host has installed
+	_ "time/tzdata"
+)
+
+/*
+The members of `import "time" as time;`. Times are TIME objects and the spans between them DURATION objects,
+which work with the arithmetic and comparison operators:
+
+	t + d, d + t, t - d   a TIME moved by a DURATION
+	t - t                 the DURATION between two times
+	d + d, d - d, d % d   DURATION
+	d * n, n * d, d / n   a DURATION scaled by a number
+	d / d                 the FLOAT ratio of two durations
+	<, >, ==, !=          between two times or two durations
+
+now, since and until read the interpreter's Options.Clock, so a host that sets it decides what time it is.
+Layouts are Go's, written for the reference time Mon Jan 2 15:04:05 MST 2006, and zones are IANA names
+such as "Europe/Paris" or "UTC".
+*/
+func (in *Interpreter) timeModule() map[string]object.Object {
+	return map[string]object.Object{
+		"NANOSECOND":  &object.Duration{Value: time.Nanosecond},
+		"MICROSECOND": &object.Duration{Value: time.Microsecond},
+		"MILLISECOND": &object.Duration{Value: time.Millisecond},
+		"SECOND":      &object.Duration{Value: time.Second},
+		"MINUTE":      &object.Duration{Value: time.Minute},
+		"HOUR":        &object.Duration{Value: time.Hour},
+
+		"RFC3339":   &object.String{Value: time.RFC3339},
+		"RFC1123":   &object.String{Value: time.RFC1123},
+		"DATETIME":  &object.String{Value: time.DateTime},
+		"DATE_ONLY": &object.String{Value: time.DateOnly},
+		"TIME_ONLY": &object.String{Value: time.TimeOnly},
+		"KITCHEN":   &object.String{Value: time.Kitchen},
+
+		"now": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return wrongArgumentCount(0, 0, len(args))
+			}
+
+			return &object.Time{Value: in.clock()}
+		}},
+		// the DURATION from t to now
+		"since": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArgumentCount(1, 1, len(args))
+			}
+
+			t, err := timeArgument("since", args[0])
+			if err != nil {
+				return err
+			}
+
+			return &object.Duration{Value: in.clock().Sub(t)}
+		}},
+		// the DURATION from now to t
+		"until": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArgumentCount(1, 1, len(args))
+			}
+
+			t, err := timeArgument("until", args[0])
+			if err != nil {
+				return err
+			}
+
+			return &object.Duration{Value: t.Sub(in.clock())}
+		}},
+		// unix(seconds) is the time that many seconds after 1970-01-01 UTC, in UTC
+		"unix": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArgumentCount(1, 1, len(args))
+			}
+
+			seconds, err := integerArgument("unix", args[0])
+			if err != nil {
+				return err
+			}
+
+			return &object.Time{Value: time.Unix(seconds, 0).UTC()}
+		}},
+		// date(year, month, day) or date(year, month, day, hour, minute, second), either with a zone at the end
+		"date": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			zone := time.UTC
+			if len(args) > 0 {
+				if name, ok := args[len(args)-1].(*object.String); ok {
+					var err object.Object
+					if zone, err = loadZone(name.Value); err != nil {
+						return err
+					}
+					args = args[:len(args)-1]
+				}
+			}
+
+			if len(args) != 3 && len(args) != 6 {
+				return newError("wrong number of arguments.\nexpected=3 or 6 and a zone, got=%d", len(args))
+			}
+
+			fields := make([]int, 6)
+			for i, arg := range args {
+				value, err := integerArgument("date", arg)
+				if err != nil {
+					return err
+				}
+				fields[i] = int(value)
+			}
+
+			return &object.Time{Value: time.Date(fields[0], time.Month(fields[1]), fields[2], fields[3], fields[4], fields[5], 0, zone)}
+		}},
+		// parse(s, layout, zone), RFC3339 without a layout. The zone, UTC by default, is for text without an offset
+		"parse": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) < 1 || len(args) > 3 {
+				return wrongArgumentCount(1, 3, len(args))
+			}
+
+			strs := make([]string, len(args))
+			for i, arg := range args {
+				value, err := stringArgument("parse", arg)
+				if err != nil {
+					return err
+				}
+				strs[i] = value
+			}
+
+			layout, zone := time.RFC3339, time.UTC
+			if len(strs) > 1 {
+				layout = strs[1]
+			}
+
+			if len(strs) > 2 {
+				var err object.Object
+				if zone, err = loadZone(strs[2]); err != nil {
+					return err
+				}
+			}
+
+			t, err := time.ParseInLocation(layout, strs[0], zone)
+			if err != nil {
+				return newError("cannot parse time: %s", err)
+			}
+
+			return &object.Time{Value: t}
+		}},
+		// duration("1h30m"), with the units h, m, s, ms, us and ns
+		"duration": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return wrongArgumentCount(1, 1, len(args))
+			}
+
+			s, err := stringArgument("duration", args[0])
+			if err != nil {
+				return err
+			}
+
+			d, parseErr := time.ParseDuration(s)
+			if parseErr != nil {
+				return newError("invalid duration: %q", s)
+			}
+
+			return &object.Duration{Value: d}
+		}},
+	}
+}
+
+// The methods of times. Those that give another time leave the zone as it was, except inZone and utc
+func timeMethods() map[string]object.Object {
+	field := func(get func(t time.Time) int) *object.Builtin {
+		return method(0, func(receiver object.Object, args []object.Object) object.Object {
+			return &object.Integer{Value: int64(get(receiver.(*object.Time).Value))}
+		})
+	}
+
+	withTime := func(name string, fn func(t, other time.Time) object.Object) *object.Builtin {
+		return method(1, func(receiver object.Object, args []object.Object) object.Object {
+			other, err := timeArgument(name, args[0])
+			if err != nil {
+				return err
+			}
+
+			return fn(receiver.(*object.Time).Value, other)
+		})
+	}
+
+	withDuration := func(name string, fn func(t time.Time, d time.Duration) time.Time) *object.Builtin {
+		return method(1, func(receiver object.Object, args []object.Object) object.Object {
+			d, err := durationArgument(name, args[0])
+			if err != nil {
+				return err
+			}
+
+			return &object.Time{Value: fn(receiver.(*object.Time).Value, d)}
+		})
+	}
+
+	return map[string]object.Object{
+		"year":       field(time.Time.Year),
+		"month":      field(func(t time.Time) int { return int(t.Month()) }),
+		"day":        field(time.Time.Day),
+		"hour":       field(time.Time.Hour),
+		"minute":     field(time.Time.Minute),
+		"second":     field(time.Time.Second),
+		"nanosecond": field(time.Time.Nanosecond),
+		"yearDay":    field(time.Time.YearDay),
+		"unix": method(0, func(receiver object.Object, args []object.Object) object.Object {
+			return &object.Integer{Value: receiver.(*object.Time).Value.Unix()}
+		}),
+		"unixMilli": method(0, func(receiver object.Object, args []object.Object) object.Object {
+			return &object.Integer{Value: receiver.(*object.Time).Value.UnixMilli()}
+		}),
+		// the name of the day, e.g. Monday
+		"weekday": method(0, func(receiver object.Object, args []object.Object) object.Object {
+			return &object.String{Value: receiver.(*object.Time).Value.Weekday().String()}
+		}),
+		// the abbreviated name of the zone the time is in, e.g. CET
+		"zone": method(0, func(receiver object.Object, args []object.Object) object.Object {
+			name, _ := receiver.(*object.Time).Value.Zone()
+			return &object.String{Value: name}
+		}),
+		// format(layout), RFC3339 without a layout
+		"format": &object.Builtin{Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return wrongArgumentCount(0, 1, len(args)-1)
+			}
+
+			layout := time.RFC3339
+			if len(args) == 2 {
+				var err object.Object
+				if layout, err = stringArgument("format", args[1]); err != nil {
+					return err
+				}
+			}
+
+			return &object.String{Value: args[0].(*object.Time).Value.Format(layout)}
+		}},
+		// the same instant in another zone
+		"inZone": method(1, func(receiver object.Object, args []object.Object) object.Object {
+			name, err := stringArgument("inZone", args[0])
+			if err != nil {
+				return err
+			}
+
+			zone, err := loadZone(name)
+			if err != nil {
+				return err
+			}
+
+			return &object.Time{Value: receiver.(*object.Time).Value.In(zone)}
+		}),
+		"utc": method(0, func(receiver object.Object, args []object.Object) object.Object {
+			return &object.Time{Value: receiver.(*object.Time).Value.UTC()}
+		}),
+		"add":      withDuration("add", time.Time.Add),
+		"truncate": withDuration("truncate", time.Time.Truncate),
+		// addDate(years, months, days), going by the calendar rather than a fixed length of time
+		"addDate": method(3, func(receiver object.Object, args []object.Object) object.Object {
+			parts := make([]int, 3)
+			for i, arg := range args {
+				value, err := integerArgument("addDate", arg)
+				if err != nil {
+					return err
+				}
+				parts[i] = int(value)
+			}
+
+			return &object.Time{Value: receiver.(*object.Time).Value.AddDate(parts[0], parts[1], parts[2])}
+		}),
+		"sub": withTime("sub", func(t, other time.Time) object.Object {
+			return &object.Duration{Value: t.Sub(other)}
+		}),
+		"before": withTime("before", func(t, other time.Time) object.Object {
+			return nativeToBooleanObject(t.Before(other))
+		}),
+		"after": withTime("after", func(t, other time.Time) object.Object {
+			return nativeToBooleanObject(t.After(other))
+		}),
+	}
+}
+
+func durationMethods() map[string]object.Object {
+	float := func(get func(d time.Duration) float64) *object.Builtin {
+		return method(0, func(receiver object.Object, args []object.Object) object.Object {
+			return &object.Float{Value: get(receiver.(*object.Duration).Value)}
+		})
+	}
+
+	rounding := func(name string, fn func(d, m time.Duration) time.Duration) *object.Builtin {
+		return method(1, func(receiver object.Object, args []object.Object) object.Object {
+			m, err := durationArgument(name, args[0])
+			if err != nil {
+				return err
+			}
+
+			return &object.Duration{Value: fn(receiver.(*object.Duration).Value, m)}
+		})
+	}
+
+	return map[string]object.Object{
+		"hours":   float(time.Duration.Hours),
+		"minutes": float(time.Duration.Minutes),
+		"seconds": float(time.Duration.Seconds),
+		"milliseconds": method(0, func(receiver object.Object, args []object.Object) object.Object {
+			return &object.Integer{Value: receiver.(*object.Duration).Value.Milliseconds()}
+		}),
+		"nanoseconds": method(0, func(receiver object.Object, args []object.Object) object.Object {
+			return &object.Integer{Value: receiver.(*object.Duration).Value.Nanoseconds()}
+		}),
+		"abs": method(0, func(receiver object.Object, args []object.Object) object.Object {
+			return &object.Duration{Value: receiver.(*object.Duration).Value.Abs()}
+		}),
+		"truncate": rounding("truncate", time.Duration.Truncate),
+		"round":    rounding("round", time.Duration.Round),
+	}
+}
+
+/*
+Arithmetic and comparisons with times and durations, see timeModule. Called when either operand is a TIME or a
+DURATION.
+*/
+func evalTimeInfixExpression(operator string, left, right object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Time:
+		switch right := right.(type) {
+		case *object.Time:
+			switch operator {
+			case "-":
+				return &object.Duration{Value: left.Value.Sub(right.Value)}
+			case "<":
+				return nativeToBooleanObject(left.Value.Before(right.Value))
+			case ">":
+				return nativeToBooleanObject(left.Value.After(right.Value))
+			case "==":
+				return nativeToBooleanObject(left.Value.Equal(right.Value))
+			case "!=":
+				return nativeToBooleanObject(!left.Value.Equal(right.Value))
+			}
+
+		case *object.Duration:
+			switch operator {
+			case "+":
+				return &object.Time{Value: left.Value.Add(right.Value)}
+			case "-":
+				return &object.Time{Value: left.Value.Add(-right.Value)}
+			}
+		}
+
+	case *object.Duration:
+		switch right := right.(type) {
+		case *object.Duration:
+			switch operator {
+			case "+":
+				return &object.Duration{Value: left.Value + right.Value}
+			case "-":
+				return &object.Duration{Value: left.Value - right.Value}
+			case "/":
+				if right.Value == 0 {
+					return newError("division by zero: %s / %s", left.Inspect(), right.Inspect())
+				}
+				return &object.Float{Value: float64(left.Value) / float64(right.Value)}
+			case "%":
+				if right.Value == 0 {
+					return newError("division by zero: %s %% %s", left.Inspect(), right.Inspect())
+				}
+				return &object.Duration{Value: left.Value % right.Value}
+			case "<":
+				return nativeToBooleanObject(left.Value < right.Value)
+			case ">":
+				return nativeToBooleanObject(left.Value > right.Value)
+			case "==":
+				return nativeToBooleanObject(left.Value == right.Value)
+			case "!=":
+				return nativeToBooleanObject(left.Value != right.Value)
+			}
+
+		case *object.Time:
+			if operator == "+" {
+				return &object.Time{Value: right.Value.Add(left.Value)}
+			}
+
+		case *object.Integer, *object.Float:
+			factor, _ := numberValue(right)
+			switch operator {
+			case "*":
+				return &object.Duration{Value: time.Duration(float64(left.Value) * factor)}
+			case "/":
+				if factor == 0 {
+					return newError("division by zero: %s / %s", left.Inspect(), right.Inspect())
+				}
+				return &object.Duration{Value: time.Duration(float64(left.Value) / factor)}
+			}
+		}
+
+	case *object.Integer, *object.Float:
+		if right, ok := right.(*object.Duration); ok && operator == "*" {
+			return evalTimeInfixExpression(operator, right, left)
+		}
+	}
+
+	switch operator {
+	case "==":
+		return FALSE
+	case "!=":
+		return TRUE
+	}
+
+	if left.Type() != right.Type() {
+		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+}
+
+func isTimeOrDuration(obj object.Object) bool {
+	return obj.Type() == object.TIME_OBJ || obj.Type() == object.DURATION_OBJ
+}
+
+func timeArgument(name string, arg object.Object) (time.Time, object.Object) {
+	t, ok := arg.(*object.Time)
+	if !ok {
+		return time.Time{}, newError("argument to \"%s\" must be TIME.\ngot %s", name, arg.Type())
+	}
+
+	return t.Value, nil
+}
+
+func durationArgument(name string, arg object.Object) (time.Duration, object.Object) {
+	d, ok := arg.(*object.Duration)
+	if !ok {
+		return 0, newError("argument to \"%s\" must be DURATION.\ngot %s", name, arg.Type())
+	}
+
+	return d.Value, nil
+}
+
+func loadZone(name string) (*time.Location, object.Object) {
+	zone, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, newError("unknown time zone %q", name)
+	}
+
+	return zone, nil
+}
@@ -0,0 +1,122 @@
+package evaluator
+
+import (
+	"monkey/object"
+	"testing"
+	"time"
+)
+
+func TestTimeModule(t *testing.T) {
+	// every test runs at 2024-03-10 12:30:00 UTC
+	frozen := time.Date(2024, 3, 10, 12, 30, 0, 0, time.UTC)
+
+	tests := []ExpectedTest[string]{
+		{`time.now()`, "2024-03-10T12:30:00Z"},
+		{`time.now() == time.now()`, "true"},
+		{`time.date(2024, 3, 10)`, "2024-03-10T00:00:00Z"},
+		{`time.date(2024, 3, 10, 9, 5, 7, "Europe/Paris")`, "2024-03-10T09:05:07+01:00"},
+		{`time.date(2024, 7, 1, "America/New_York")`, "2024-07-01T00:00:00-04:00"},
+		{`time.unix(0)`, "1970-01-01T00:00:00Z"},
+		{`time.unix(1710073800) == time.now()`, "true"},
+
+		// parsing and formatting
+		{`time.parse("2024-01-02T03:04:05+02:00")`, "2024-01-02T03:04:05+02:00"},
+		{`time.parse("02/01/2024 15:04", "02/01/2006 15:04")`, "2024-01-02T15:04:00Z"},
+		{`time.parse("2024-06-01 08:00:00", time.DATETIME, "Asia/Tokyo")`, "2024-06-01T08:00:00+09:00"},
+		{`time.now().format(time.DATE_ONLY)`, "2024-03-10"},
+		{`time.now().format("Mon Jan 2 3:04PM")`, "Sun Mar 10 12:30PM"},
+		{`time.now().format()`, "2024-03-10T12:30:00Z"},
+
+		// zones
+		{`time.now().inZone("Asia/Kolkata")`, "2024-03-10T18:00:00+05:30"},
+		{`time.now().inZone("America/Los_Angeles").format("15:04 MST")`, "05:30 PDT"},
+		{`time.now().inZone("Asia/Kolkata") == time.now()`, "true"},
+		{`time.now().inZone("Asia/Tokyo").utc()`, "2024-03-10T12:30:00Z"},
+		{`time.now().inZone("Europe/Paris").zone()`, "CET"},
+
+		// fields
+		{`let t = time.now(); [t.year(), t.month(), t.day(), t.hour(), t.minute(), t.second(), t.yearDay()]`,
+			"[2024, 3, 10, 12, 30, 0, 70]"},
+		{`time.now().weekday()`, "Sunday"},
+		{`time.now().unix()`, "1710073800"},
+		{`time.now().unixMilli()`, "1710073800000"},
+
+		// durations
+		{`time.duration("1h30m")`, "1h30m0s"},
+		{`2 * time.HOUR + 15 * time.MINUTE`, "2h15m0s"},
+		{`time.HOUR * 1.5`, "1h30m0s"},
+		{`time.HOUR / 4`, "15m0s"},
+		{`time.HOUR / time.MINUTE`, "60.0"},
+		{`time.duration("100m") % time.HOUR`, "40m0s"},
+		{`-time.SECOND`, "-1s"},
+		{`time.duration("90m").hours()`, "1.5"},
+		{`time.duration("90s").minutes()`, "1.5"},
+		{`time.duration("1.5s").milliseconds()`, "1500"},
+		{`(-time.MINUTE).abs()`, "1m0s"},
+		{`time.duration("1h29m").round(time.HOUR)`, "1h0m0s"},
+		{`time.duration("1h29m").truncate(time.HOUR)`, "1h0m0s"},
+		{`time.MINUTE > time.SECOND`, "true"},
+		{`time.MINUTE == 60 * time.SECOND`, "true"},
+
+		// arithmetic and comparison
+		{`time.now() + time.HOUR`, "2024-03-10T13:30:00Z"},
+		{`time.MINUTE + time.now()`, "2024-03-10T12:31:00Z"},
+		{`time.now() - 2 * time.HOUR`, "2024-03-10T10:30:00Z"},
+		{`time.now() - time.date(2024, 3, 9)`, "36h30m0s"},
+		{`time.date(2024, 1, 31).addDate(0, 1, 0)`, "2024-03-02T00:00:00Z"},
+		{`time.now().add(time.SECOND)`, "2024-03-10T12:30:01Z"},
+		{`time.now().sub(time.date(2024, 3, 10))`, "12h30m0s"},
+		{`time.now().truncate(time.HOUR)`, "2024-03-10T12:00:00Z"},
+		{`time.date(2024, 1, 1) < time.now()`, "true"},
+		{`time.date(2024, 1, 1) > time.now()`, "false"},
+		{`time.now().before(time.now() + time.SECOND)`, "true"},
+		{`time.now().after(time.now())`, "false"},
+		{`time.since(time.date(2024, 3, 10))`, "12h30m0s"},
+		{`time.until(time.date(2024, 3, 11))`, "11h30m0s"},
+		{`{time.date(2024, 3, 10): "today"}[time.date(2024, 3, 10, 1, 0, 0, "Europe/Paris")]`, "today"},
+		{`time.now() == 1`, "false"},
+
+		{`time.now() + time.now()`, "ERROR unknown operator: TIME + TIME"},
+		{`time.now() + 1`, "ERROR type mismatch: TIME + INTEGER"},
+		{`time.HOUR * time.HOUR`, "ERROR unknown operator: DURATION * DURATION"},
+		{`time.HOUR / 0`, "ERROR division by zero: 1h0m0s / 0"},
+		{`time.HOUR / (time.HOUR - time.HOUR)`, "ERROR division by zero: 1h0m0s / 0s"},
+		{`time.parse("yesterday")`, `ERROR cannot parse time: parsing time "yesterday" as "2006-01-02T15:04:05Z07:00": cannot parse "yesterday" as "2006"`},
+		{`time.now().inZone("Mars/Olympus")`, `ERROR unknown time zone "Mars/Olympus"`},
+		{`time.date(2024, 3)`, "ERROR wrong number of arguments.\nexpected=3 or 6 and a zone, got=2"},
+		{`time.date(2024, "3", 1)`, "ERROR argument to \"date\" must be INTEGER.\ngot STRING"},
+		{`time.duration("soon")`, `ERROR invalid duration: "soon"`},
+		{`time.since(1)`, "ERROR argument to \"since\" must be TIME.\ngot INTEGER"},
+		{`time.now().add(1)`, "ERROR argument to \"add\" must be DURATION.\ngot INTEGER"},
+	}
+
+	for _, tc := range tests {
+		in := New(Options{Clock: func() time.Time { return frozen }})
+		input := `import "time" as time; ` + tc.input
+
+		if got := in.Run(parse(t, input)).Inspect(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+// Without a Clock the time module sees the real time, and a Clock is asked again on every call
+func TestClockOption(t *testing.T) {
+	before := time.Now()
+	got := New(Options{}).Run(parse(t, `import "time" as time; time.now()`))
+	now, ok := got.(*object.Time)
+	if !ok || now.Value.Before(before) || now.Value.After(time.Now()) {
+		t.Fatalf("time.now() isn't the current time. got=%s", got.Inspect())
+	}
+
+	calls := 0
+	clock := func() time.Time {
+		calls++
+		return time.Unix(int64(calls)*60, 0).UTC()
+	}
+
+	in := New(Options{Clock: clock})
+	if got := in.Run(parse(t, `import "time" as time; let a = time.now(); time.now() - a`)).Inspect(); got != "1m0s" {
+		t.Errorf("the clock isn't read on every call. got=%s", got)
+	}
+}
@@ -1,7 +1,11 @@
 package lexer
 
 import (
+	"errors"
+	"fmt"
 	"monkey/token"
+	"strconv"
+	"strings"
 )
 
 /*
@@ -13,15 +17,28 @@ type Lexer struct {
 	position     int  // current position in input - points to current char
 	readPosition int  // current reading position in input - after current char
 	ch           byte // current char under examination
+	line         int  // line of the current char, from 1
+	column       int  // column of the current char, from 1
 }
 
 func New(input string) *Lexer {
-	lexer := &Lexer{input: input}
+	return NewAt(input, 1, 1)
+}
+
+// A lexer for input that starts at line and column of some larger source, so its tokens carry positions in that
+func NewAt(input string, line int, column int) *Lexer {
+	lexer := &Lexer{input: input, line: line, column: column - 1}
 	lexer.readChar()
 	return lexer
 }
 
 func (lexer *Lexer) readChar() {
+	if lexer.ch == '\n' {
+		lexer.line += 1
+		lexer.column = 0
+	}
+	lexer.column += 1
+
 	if lexer.readPosition >= len(lexer.input) {
 		lexer.ch = 0 // ASCII code for NUL - so EOF or nothing read in
 	} else {
@@ -52,13 +69,14 @@ func (l *Lexer) peekAheadChar() byte {
 // Read each character in the variable identifier moving along and return the entire identifier (we do an range on the slice, starting (position), ending (l.position) where the pointer has gotten up to)
 func (l *Lexer) readIdentifier() string {
 	var position = l.position
-	for isLetter(l.ch) {
+	// digits can follow the first letter, log10 is one identifier
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 
 	// check if the next char is whitespace, we want check if the keyword 'else if' has been passed
 	// if the next part starts with i, assume this is an else if, read ahead 2 times
-	if l.ch == ' ' && l.peekChar() == 'i' && l.peekAheadChar() == 'f' {
+	if l.input[position:l.position] == "else" && l.ch == ' ' && l.peekChar() == 'i' && l.peekAheadChar() == 'f' {
 		l.readChar()
 		l.readChar()
 		l.readChar()
@@ -90,23 +108,214 @@ func (l *Lexer) readNumber() (string, token.TokenType) {
 	return l.input[position:l.position], token.TokenType(tokenType)
 }
 
-func (l *Lexer) readString() string {
+/*
+Reads a string up to its closing quote. Escape sequences are replaced in the literal of a plain string. A string
+with ${...} placeholders is a TEMPLATE instead, its literal is left as written for the parser to split with
+SplitTemplate.
+*/
+func (l *Lexer) readString() (string, token.TokenType) {
 	position := l.position + 1
+	tokenType := token.TokenType(token.STRING)
+
 	for {
 		l.readChar()
 
+		switch {
+		case l.ch == '\\':
+			// whatever follows is part of the escape sequence, a quote doesn't end the string
+			if l.peekChar() != 0 {
+				l.readChar()
+			}
+			continue
+
+		case l.ch == '$' && l.peekChar() == '{':
+			tokenType = token.TEMPLATE
+			l.readChar()
+			l.skipPlaceholder()
+		}
+
 		if l.ch == '"' || l.ch == 0 {
 			break
 		}
 	}
-	return l.input[position:l.position]
+
+	if tokenType == token.TEMPLATE {
+		return l.input[position:l.position], tokenType
+	}
+
+	return unescape(l.input[position:l.position]), tokenType
 }
 
-func (l *Lexer) NextToken() token.Token {
-	var _token token.Token
+// Reads a bytes literal up to its closing quote. Its literal is left as written, see DecodeBytes
+func (l *Lexer) readBytes() string {
+	position := l.position + 1
+
+	for {
+		l.readChar()
+
+		if l.ch == '\\' && l.peekChar() != 0 {
+			l.readChar()
+			continue
+		}
+
+		if l.ch == '"' || l.ch == 0 {
+			return l.input[position:l.position]
+		}
+	}
+}
+
+// Moves from the { of a placeholder to its closing }, past nested braces and strings
+func (l *Lexer) skipPlaceholder() {
+	depth := 1
+
+	for depth > 0 {
+		l.readChar()
+
+		switch l.ch {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '"':
+			if l.readString(); l.ch == 0 {
+				return
+			}
+		case 0:
+			return
+		}
+	}
+}
+
+var escapes = map[byte]string{'n': "\n", 't': "\t", 'r': "\r", '"': "\"", '\\': "\\", '$': "$"}
+
+// Replaces the escape sequences in the source of a string, unknown ones are kept as they are
+func unescape(source string) string {
+	if !strings.Contains(source, "\\") {
+		return source
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(source); i++ {
+		if source[i] == '\\' && i+1 < len(source) {
+			if replacement, ok := escapes[source[i+1]]; ok {
+				out.WriteString(replacement)
+				i++
+				continue
+			}
+		}
+
+		out.WriteByte(source[i])
+	}
+
+	return out.String()
+}
+
+/*
+Turns the source of a bytes literal into its bytes. Besides the escapes strings have, \xff is the byte 0xff and
+\0 a zero byte. Anything else after a backslash is an error, as is a \x without two hex digits.
+*/
+func DecodeBytes(source string) ([]byte, error) {
+	out := make([]byte, 0, len(source))
+
+	for i := 0; i < len(source); i++ {
+		if source[i] != '\\' {
+			out = append(out, source[i])
+			continue
+		}
+
+		if i+1 == len(source) {
+			return nil, errors.New("bytes literal ends in a backslash")
+		}
+
+		i++
+		switch escape := source[i]; {
+		case escape == 'x':
+			if i+2 >= len(source) {
+				return nil, fmt.Errorf("invalid escape %q in bytes literal", source[i-1:])
+			}
 
+			value, err := strconv.ParseUint(source[i+1:i+3], 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid escape %q in bytes literal", source[i-1:i+3])
+			}
+
+			out = append(out, byte(value))
+			i += 2
+
+		case escape == '0':
+			out = append(out, 0)
+
+		case escapes[escape] != "":
+			out = append(out, escapes[escape]...)
+
+		default:
+			return nil, fmt.Errorf("invalid escape %q in bytes literal", source[i-1:i+1])
+		}
+	}
+
+	return out, nil
+}
+
+// A piece of a template string, either text or the source of a placeholder's expression
+type TemplatePart struct {
+	Text        string
+	Placeholder bool
+
+	// where the expression of a placeholder starts
+	Line   int
+	Column int
+}
+
+/*
+Splits a TEMPLATE token into its text, with escape sequences replaced, and the source of its placeholders. ok
+is false when the last placeholder isn't closed before the end of the string.
+*/
+func SplitTemplate(template token.Token) (parts []TemplatePart, ok bool) {
+	// the literal starts after the opening quote
+	l := NewAt(template.Literal, template.Line, template.Column+1)
+	text := 0
+
+	for l.ch != 0 {
+		if l.ch == '\\' && l.peekChar() != 0 {
+			l.readChar()
+		} else if l.ch == '$' && l.peekChar() == '{' {
+			parts = append(parts, TemplatePart{Text: unescape(l.input[text:l.position])})
+
+			l.readChar()
+			start, line, column := l.readPosition, l.line, l.column+1
+
+			l.skipPlaceholder()
+			if l.ch == 0 {
+				return parts, false
+			}
+
+			parts = append(parts, TemplatePart{Text: l.input[start:l.position], Placeholder: true, Line: line, Column: column})
+			text = l.readPosition
+		}
+
+		l.readChar()
+	}
+
+	parts = append(parts, TemplatePart{Text: unescape(l.input[text:])})
+	return parts, true
+}
+
+func (l *Lexer) NextToken() token.Token {
 	l.skipWhiteSpace()
 
+	// every token is stamped with where it started, whichever branch of readToken produces it
+	line, column := l.line, l.column
+
+	_token := l.readToken()
+	_token.Line = line
+	_token.Column = column
+
+	return _token
+}
+
+func (l *Lexer) readToken() token.Token {
+	var _token token.Token
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -115,6 +324,9 @@ func (l *Lexer) NextToken() token.Token {
 
 			var literal = string(currentCharacter) + string(l.ch)
 			_token = token.Token{Type: token.EQ, Literal: literal}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			_token = token.Token{Type: token.ARROW, Literal: "=>"}
 		} else {
 			_token = newToken(token.ASSIGN, l.ch)
 		}
@@ -122,6 +334,8 @@ func (l *Lexer) NextToken() token.Token {
 		_token = newToken(token.SEMICOLON, l.ch)
 	case ':':
 		_token = newToken(token.COLON, l.ch)
+	case '.':
+		_token = newToken(token.DOT, l.ch)
 	case '(':
 		_token = newToken(token.LPAREN, l.ch)
 	case ')':
@@ -152,14 +366,43 @@ func (l *Lexer) NextToken() token.Token {
 	case '%':
 		_token = newToken(token.MOD, l.ch)
 	case '*':
-		_token = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '*' {
+			l.readChar()
+			_token = token.Token{Type: token.POWER, Literal: "**"}
+		} else {
+			_token = newToken(token.ASTERISK, l.ch)
+		}
 	case '<':
-		_token = newToken(token.LT, l.ch)
+		if l.peekChar() == '<' {
+			l.readChar()
+			_token = token.Token{Type: token.SHIFT_LEFT, Literal: "<<"}
+		} else {
+			_token = newToken(token.LT, l.ch)
+		}
 	case '>':
-		_token = newToken(token.GT, l.ch)
+		if l.peekChar() == '>' {
+			l.readChar()
+			_token = token.Token{Type: token.SHIFT_RIGHT, Literal: ">>"}
+		} else {
+			_token = newToken(token.GT, l.ch)
+		}
+	case '&':
+		_token = newToken(token.BIT_AND, l.ch)
+	case '|':
+		_token = newToken(token.BIT_OR, l.ch)
+	case '^':
+		_token = newToken(token.BIT_XOR, l.ch)
+	case '~':
+		_token = newToken(token.BIT_NOT, l.ch)
 	case '"':
-		_token.Type = token.STRING
-		_token.Literal = l.readString()
+		_token.Literal, _token.Type = l.readString()
+	case '#':
+		if l.peekChar() == '{' {
+			l.readChar()
+			_token = token.Token{Type: token.SET_OPEN, Literal: "#{"}
+		} else {
+			_token = newToken(token.ILLEGAL, l.ch)
+		}
 	case '[':
 		_token = newToken(token.LBRACKET, l.ch)
 	case ']':
@@ -169,6 +412,12 @@ func (l *Lexer) NextToken() token.Token {
 		_token.Type = token.EOF
 
 	default:
+		if l.ch == 'b' && l.peekChar() == '"' {
+			l.readChar()
+			_token.Literal, _token.Type = l.readBytes(), token.BYTES
+			break
+		}
+
 		if isLetter(l.ch) {
 			_token.Literal = l.readIdentifier()
 			_token.Type = token.LookupIdentifier(_token.Literal)
@@ -98,6 +98,100 @@ func TestStringToken(t *testing.T) {
 	testLexedToken(t, lexedToken, tests)
 }
 
+func TestStringEscapesAndTemplates(t *testing.T) {
+	input := `"a\"b\\c\n\t\q" "cost: \${x}" "hi ${name}!" "${ {"k": "}"}["k"] }" "${"${1}"}" "end\\"`
+
+	tests := []TokenTest{
+		{token.STRING, "a\"b\\c\n\t\\q"},
+		{token.STRING, "cost: ${x}"},
+		{token.TEMPLATE, "hi ${name}!"},
+		// braces and quotes inside a placeholder don't end it
+		{token.TEMPLATE, `${ {"k": "}"}["k"] }`},
+		{token.TEMPLATE, `${"${1}"}`},
+		{token.STRING, "end\\"},
+		{token.EOF, ""},
+	}
+
+	testLexedToken(t, New(input), tests)
+}
+
+func TestBytesTokens(t *testing.T) {
+	input := `b"\x00\"${x}" b bx b "a"`
+
+	tests := []TokenTest{
+		{token.BYTES, `\x00\"${x}`},
+		{token.IDENT, "b"},
+		{token.IDENT, "bx"},
+		{token.IDENT, "b"},
+		{token.STRING, "a"},
+		{token.EOF, ""},
+	}
+
+	testLexedToken(t, New(input), tests)
+}
+
+func TestDecodeBytes(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{`abc`, "abc"},
+		{`\x00\xFF\x7f`, "\x00\xff\x7f"},
+		{`\0\n\t\r\"\\\$`, "\x00\n\t\r\"\\$"},
+		{`é`, "é"},
+	}
+
+	for _, tc := range tests {
+		got, err := DecodeBytes(tc.source)
+		if err != nil || string(got) != tc.expected {
+			t.Errorf("DecodeBytes(%q) wrong. expected=%q, got=%q (%v)", tc.source, tc.expected, got, err)
+		}
+	}
+
+	for _, source := range []string{`\a`, `\x1`, `\xg0`, `a\`} {
+		if _, err := DecodeBytes(source); err == nil {
+			t.Errorf("DecodeBytes(%q) didn't fail", source)
+		}
+	}
+}
+
+func TestSplitTemplate(t *testing.T) {
+	input := `let s = "a \${b} ${x + 1}
+c${ f("}") }";`
+
+	l := New(input)
+	var template token.Token
+	for template = l.NextToken(); template.Type != token.TEMPLATE; template = l.NextToken() {
+	}
+
+	parts, ok := SplitTemplate(template)
+	if !ok {
+		t.Fatalf("SplitTemplate(%q) failed", template.Literal)
+	}
+
+	expected := []TemplatePart{
+		{Text: "a ${b} "},
+		{Text: "x + 1", Placeholder: true, Line: 1, Column: 20},
+		{Text: "\nc"},
+		{Text: ` f("}") `, Placeholder: true, Line: 2, Column: 4},
+		{Text: ""},
+	}
+
+	if len(parts) != len(expected) {
+		t.Fatalf("wrong number of parts. expected=%d, got=%d (%+v)", len(expected), len(parts), parts)
+	}
+
+	for i, part := range parts {
+		if part != expected[i] {
+			t.Errorf("parts[%d] wrong. expected=%+v, got=%+v", i, expected[i], part)
+		}
+	}
+
+	if _, ok := SplitTemplate(token.Token{Type: token.TEMPLATE, Literal: "a ${b", Line: 1, Column: 1}); ok {
+		t.Errorf("expected an unterminated placeholder to fail")
+	}
+}
+
 func TestNextTokenSimple(t *testing.T) {
 	var input = "=+(){},*/%;"
 
@@ -121,6 +215,144 @@ func TestNextTokenSimple(t *testing.T) {
 	testLexedToken(t, lexedToken, tests)
 }
 
+func TestMemberAndLoopTokens(t *testing.T) {
+	input := `for (x in db.rows) { 1.5 }`
+
+	tests := []TokenTest{
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.IN, "in"},
+		{token.IDENT, "db"},
+		{token.DOT, "."},
+		{token.IDENT, "rows"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.FLOAT, "1.5"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	testLexedToken(t, New(input), tests)
+}
+
+func TestEnumAndMatchTokens(t *testing.T) {
+	input := `enum R { A(x) } match (r) { R.A(v) => v, _ => 0 } a == b`
+
+	tests := []TokenTest{
+		{token.ENUM, "enum"},
+		{token.IDENT, "R"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "A"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.RBRACE, "}"},
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "r"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "R"},
+		{token.DOT, "."},
+		{token.IDENT, "A"},
+		{token.LPAREN, "("},
+		{token.IDENT, "v"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "v"},
+		{token.COMMA, ","},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.INT, "0"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "a"},
+		{token.EQ, "=="},
+		{token.IDENT, "b"},
+		{token.EOF, ""},
+	}
+
+	testLexedToken(t, New(input), tests)
+}
+
+func TestPowerAndBitTokens(t *testing.T) {
+	input := `2 ** 3 * a & b | c ^ ~d << 1 >> 2 < 3 > 4 log10(x2)`
+
+	tests := []TokenTest{
+		{token.INT, "2"},
+		{token.POWER, "**"},
+		{token.INT, "3"},
+		{token.ASTERISK, "*"},
+		{token.IDENT, "a"},
+		{token.BIT_AND, "&"},
+		{token.IDENT, "b"},
+		{token.BIT_OR, "|"},
+		{token.IDENT, "c"},
+		{token.BIT_XOR, "^"},
+		{token.BIT_NOT, "~"},
+		{token.IDENT, "d"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.INT, "1"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.INT, "2"},
+		{token.LT, "<"},
+		{token.INT, "3"},
+		{token.GT, ">"},
+		{token.INT, "4"},
+		{token.IDENT, "log10"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x2"},
+		{token.RPAREN, ")"},
+		{token.EOF, ""},
+	}
+
+	testLexedToken(t, New(input), tests)
+}
+
+func TestSetTokens(t *testing.T) {
+	input := `#{1, x} x in s #`
+
+	tests := []TokenTest{
+		{token.SET_OPEN, "#{"},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.IDENT, "x"},
+		{token.RBRACE, "}"},
+		{token.IDENT, "x"},
+		{token.IN, "in"},
+		{token.IDENT, "s"},
+		{token.ILLEGAL, "#"},
+		{token.EOF, ""},
+	}
+
+	testLexedToken(t, New(input), tests)
+}
+
+func TestIfAfterOtherWords(t *testing.T) {
+	input := `yield if (x) { 1 } else if (y) { 2 }`
+
+	tests := []TokenTest{
+		{token.YIELD, "yield"},
+		{token.IF, "if"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.ELSEIF, "else if"},
+		{token.LPAREN, "("},
+		{token.IDENT, "y"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.INT, "2"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	testLexedToken(t, New(input), tests)
+}
+
 func TestNextTokenComplex(t *testing.T) {
 	var input = `let five = 5;
 let ten = 10;
@@ -267,3 +499,45 @@ func testLexedToken(t *testing.T, l *Lexer, tests []TokenTest) {
 		}
 	}
 }
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  if (x) {
+	"two words" else if
+}`
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"if", 2, 3},
+		{"(", 2, 6},
+		{"x", 2, 7},
+		{")", 2, 8},
+		{"{", 2, 10},
+		{"two words", 3, 2},
+		{"else if", 3, 14},
+		{"}", 4, 1},
+		{"", 4, 2},
+	}
+
+	l := New(input)
+
+	for i, tc := range tests {
+		tok := l.NextToken()
+
+		if tok.Literal != tc.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q", i, tc.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Line != tc.expectedLine || tok.Column != tc.expectedColumn {
+			t.Errorf("tests[%d] - position of %q wrong. expected=%d:%d, got=%d:%d", i, tok.Literal, tc.expectedLine, tc.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
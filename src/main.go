@@ -5,9 +5,20 @@ import (
 	"monkey/repl"
 	"os"
 	"os/user"
+	"path/filepath"
 )
 
+// MONKEYPATH lists directories to search for imports, separated like PATH
 func main() {
+	if len(os.Args) > 1 {
+		modulePath := filepath.SplitList(os.Getenv("MONKEYPATH"))
+		if !repl.RunFile(os.Args[1], modulePath, os.Stdout, os.Stderr) {
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	user, err := user.Current()
 
 	if err != nil {
@@ -0,0 +1,56 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+const BYTES_OBJ = "BYTES"
+
+// Binary data, written b"\x00\xff". Nothing changes a Bytes once it is made, so they can be hash keys
+type Bytes struct {
+	Value []byte
+}
+
+func (b *Bytes) Type() ObjectType { return BYTES_OBJ }
+
+// Printable ASCII is shown as it is, other bytes as \n, \t, \r or \xff, so the result reads back as a literal
+func (b *Bytes) Inspect() string {
+	var out strings.Builder
+
+	out.WriteString(`b"`)
+	for _, c := range b.Value {
+		switch {
+		case c == '"' || c == '\\':
+			out.WriteByte('\\')
+			out.WriteByte(c)
+		case c == '\n':
+			out.WriteString(`\n`)
+		case c == '\t':
+			out.WriteString(`\t`)
+		case c == '\r':
+			out.WriteString(`\r`)
+		case c >= ' ' && c <= '~':
+			out.WriteByte(c)
+		default:
+			fmt.Fprintf(&out, `\x%02x`, c)
+		}
+	}
+	out.WriteString(`"`)
+
+	return out.String()
+}
+
+func (b *Bytes) HashKey() HashKey {
+	h := fnv.New64()
+	h.Write(b.Value)
+
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
+func (b *Bytes) Equals(other Object) bool {
+	otherBytes, ok := other.(*Bytes)
+	return ok && bytes.Equal(b.Value, otherBytes.Value)
+}
@@ -0,0 +1,666 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+/*
+Converts between Go values and objects, so host code can hand request payloads to a script and read its
+results back without building object trees by hand.
+
+Going from Go: nil is NULL, strings, bools and every integer and float type map to STRING, BOOLEAN, INTEGER
+and FLOAT, slices and arrays to ARRAY, maps to HASH and structs to a HASH with a string key per exported
+field. The key is the field name unless a `monkey:"name"` tag says otherwise, and `monkey:"-"` leaves a field
+out. Pointers and interfaces are followed (a nil one is NULL), funcs become builtins via NewGoBuiltin and
+anything that already is an Object is passed through as is.
+
+Going to Go is the same mapping in reverse, into whatever type the target has. Integers are range checked,
+struct fields without a matching key keep their zero value and NULL converts to a nil pointer, slice, map,
+func or interface. When the target is an empty interface the value gets its natural Go type: int64,
+float64, string, bool, nil, []interface{}, and map[string]interface{} (map[interface{}]interface{} if a
+key isn't a string). Values with no Go counterpart, like Monkey functions, come out as the Object itself.
+*/
+type Converter struct {
+	// Calls a Monkey function, it is what lets a Monkey function be converted to a Go func. Without it only
+	// builtins that wrap a Go func of the right type can be
+	Call func(fn Object, args ...Object) Object
+}
+
+// Converts value into an object, see Converter
+func FromGo(value interface{}) (Object, error) {
+	return Converter{}.FromGo(value)
+}
+
+// Stores obj converted to the type target points to in *target, see Converter
+func ToGo(obj Object, target interface{}) error {
+	return Converter{}.ToGo(obj, target)
+}
+
+func (c Converter) FromGo(value interface{}) (Object, error) {
+	if value == nil {
+		return NULL, nil
+	}
+
+	return c.fromGoValue(reflect.ValueOf(value))
+}
+
+func (c Converter) ToGo(obj Object, target interface{}) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+
+	value, err := c.toGoValue(obj, ptr.Elem().Type())
+	if err != nil {
+		return err
+	}
+
+	ptr.Elem().Set(value)
+	return nil
+}
+
+// Reports whether values of Go type t can be converted to and from objects
+func convertible(t reflect.Type) bool {
+	if t.Implements(objectType) || t == objectType {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64,
+		reflect.Struct, reflect.Func:
+		return true
+	case reflect.Slice, reflect.Array, reflect.Ptr:
+		return convertible(t.Elem())
+	case reflect.Map:
+		return convertible(t.Key()) && convertible(t.Elem())
+	case reflect.Interface:
+		return t.NumMethod() == 0
+	default:
+		return false
+	}
+}
+
+// The Monkey type name of what a Go type accepts, for error messages
+func describeType(t reflect.Type) string {
+	if t == objectType || (t.Kind() == reflect.Interface && t.NumMethod() == 0) {
+		return "any value"
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return STRING_OBJ
+	case reflect.Bool:
+		return BOOLEAN_OBJ
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return INTEGER_OBJ
+	case reflect.Float32, reflect.Float64:
+		return FLOAT_OBJ
+	case reflect.Slice:
+		return fmt.Sprintf("ARRAY of %s", describeType(t.Elem()))
+	case reflect.Array:
+		return fmt.Sprintf("ARRAY of %d %s", t.Len(), describeType(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("HASH of %s to %s", describeType(t.Key()), describeType(t.Elem()))
+	case reflect.Struct:
+		return fmt.Sprintf("HASH for %s", t)
+	case reflect.Func:
+		return "a function"
+	}
+
+	if t.Implements(objectType) {
+		return string(reflect.Zero(t).Interface().(Object).Type())
+	}
+
+	if t.Kind() == reflect.Ptr {
+		return describeType(t.Elem())
+	}
+
+	return t.String()
+}
+
+// An integer that doesn't fit the Go integer type it is converted to
+type rangeError struct {
+	value  int64
+	target reflect.Type
+}
+
+func (e *rangeError) Error() string {
+	return fmt.Sprintf("%d overflows %s", e.value, e.target)
+}
+
+// An exported struct field and the hash key it is stored under
+type structField struct {
+	key   string
+	index int
+}
+
+func structFields(t reflect.Type) []structField {
+	var fields []structField
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key := field.Name
+		if tag, ok := field.Tag.Lookup("monkey"); ok {
+			if tag == "-" {
+				continue
+			}
+
+			if tag != "" {
+				key = tag
+			}
+		}
+
+		fields = append(fields, structField{key: key, index: i})
+	}
+
+	return fields
+}
+
+// Converts obj into a Go value of type t
+func (c Converter) toGoValue(obj Object, t reflect.Type) (reflect.Value, error) {
+	if t == objectType {
+		return reflect.ValueOf(&obj).Elem(), nil
+	}
+
+	if t.Implements(objectType) && reflect.TypeOf(obj).AssignableTo(t) {
+		value := reflect.New(t).Elem()
+		value.Set(reflect.ValueOf(obj))
+		return value, nil
+	}
+
+	mismatch := fmt.Errorf("cannot convert %s to %s", obj.Type(), t)
+
+	if obj == NULL {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Func:
+			return reflect.Zero(t), nil
+		}
+
+		return reflect.Value{}, mismatch
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		if str, ok := obj.(*String); ok {
+			return reflect.ValueOf(str.Value).Convert(t), nil
+		}
+
+	case reflect.Bool:
+		if boolean, ok := obj.(*Boolean); ok {
+			return reflect.ValueOf(boolean.Value).Convert(t), nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if integer, ok := obj.(*Integer); ok {
+			value := reflect.New(t).Elem()
+			if value.OverflowInt(integer.Value) {
+				return reflect.Value{}, &rangeError{value: integer.Value, target: t}
+			}
+
+			value.SetInt(integer.Value)
+			return value, nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if integer, ok := obj.(*Integer); ok {
+			value := reflect.New(t).Elem()
+			if integer.Value < 0 || value.OverflowUint(uint64(integer.Value)) {
+				return reflect.Value{}, &rangeError{value: integer.Value, target: t}
+			}
+
+			value.SetUint(uint64(integer.Value))
+			return value, nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch number := obj.(type) {
+		case *Float:
+			return reflect.ValueOf(number.Value).Convert(t), nil
+		case *Integer:
+			return reflect.ValueOf(float64(number.Value)).Convert(t), nil
+		}
+
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			natural, err := c.naturalGoValue(obj)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+
+			value := reflect.New(t).Elem()
+			if natural != nil {
+				value.Set(reflect.ValueOf(natural))
+			}
+
+			return value, nil
+		}
+
+	case reflect.Ptr:
+		elem, err := c.toGoValue(obj, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+
+	case reflect.Slice:
+		if array, ok := obj.(*Array); ok {
+			slice := reflect.MakeSlice(t, len(array.Elements), len(array.Elements))
+			if err := c.fillElements(slice, array.Elements); err != nil {
+				return reflect.Value{}, err
+			}
+
+			return slice, nil
+		}
+
+	case reflect.Array:
+		if array, ok := obj.(*Array); ok {
+			if len(array.Elements) != t.Len() {
+				return reflect.Value{}, fmt.Errorf("cannot convert ARRAY of length %d to %s", len(array.Elements), t)
+			}
+
+			value := reflect.New(t).Elem()
+			if err := c.fillElements(value, array.Elements); err != nil {
+				return reflect.Value{}, err
+			}
+
+			return value, nil
+		}
+
+	case reflect.Map:
+		if hash, ok := obj.(*Hash); ok {
+			m := reflect.MakeMapWithSize(t, hash.Len())
+
+			for _, pair := range hash.Pairs() {
+				key, err := c.toGoValue(pair.Key, t.Key())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+
+				value, err := c.toGoValue(pair.Value, t.Elem())
+				if err != nil {
+					return reflect.Value{}, err
+				}
+
+				m.SetMapIndex(key, value)
+			}
+
+			return m, nil
+		}
+
+	case reflect.Struct:
+		if hash, ok := obj.(*Hash); ok {
+			value := reflect.New(t).Elem()
+
+			for _, field := range structFields(t) {
+				element, ok := hash.Get(&String{Value: field.key})
+				if !ok {
+					continue
+				}
+
+				converted, err := c.toGoValue(element, t.Field(field.index).Type)
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("field %q: %w", field.key, err)
+				}
+
+				value.Field(field.index).Set(converted)
+			}
+
+			return value, nil
+		}
+
+	case reflect.Func:
+		return c.goFunc(obj, t)
+	}
+
+	return reflect.Value{}, mismatch
+}
+
+func (c Converter) fillElements(list reflect.Value, elements []Object) error {
+	for i, element := range elements {
+		value, err := c.toGoValue(element, list.Type().Elem())
+		if err != nil {
+			return err
+		}
+
+		list.Index(i).Set(value)
+	}
+
+	return nil
+}
+
+// The value obj converts to when the target doesn't say what type it wants
+func (c Converter) naturalGoValue(obj Object) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return obj.Value, nil
+	case *Float:
+		return obj.Value, nil
+	case *String:
+		return obj.Value, nil
+	case *Boolean:
+		return obj.Value, nil
+	case *Null:
+		return nil, nil
+
+	case *Array:
+		elements := make([]interface{}, len(obj.Elements))
+		for i, element := range obj.Elements {
+			value, err := c.naturalGoValue(element)
+			if err != nil {
+				return nil, err
+			}
+
+			elements[i] = value
+		}
+
+		return elements, nil
+
+	case *Hash:
+		stringKeys := true
+		for _, pair := range obj.Pairs() {
+			if _, ok := pair.Key.(*String); !ok {
+				stringKeys = false
+				break
+			}
+		}
+
+		t := reflect.TypeOf(map[interface{}]interface{}{})
+		if stringKeys {
+			t = reflect.TypeOf(map[string]interface{}{})
+		}
+
+		m, err := c.toGoValue(obj, t)
+		if err != nil {
+			return nil, err
+		}
+
+		return m.Interface(), nil
+
+	case *Builtin:
+		if obj.goFunc.IsValid() {
+			return obj.goFunc.Interface(), nil
+		}
+	}
+
+	return obj, nil
+}
+
+// Converts a callable object into a Go func of type t
+func (c Converter) goFunc(obj Object, t reflect.Type) (reflect.Value, error) {
+	if builtin, ok := obj.(*Builtin); ok && builtin.goFunc.IsValid() && builtin.goFunc.Type().AssignableTo(t) {
+		return builtin.goFunc, nil
+	}
+
+	switch obj.(type) {
+	case *Function, *Builtin, *BoundMethod, Callable:
+	default:
+		return reflect.Value{}, fmt.Errorf("cannot convert %s to %s", obj.Type(), t)
+	}
+
+	if c.Call == nil {
+		return reflect.Value{}, fmt.Errorf("cannot convert %s to %s without an interpreter to call it", obj.Type(), t)
+	}
+
+	returnsError := t.NumOut() > 0 && t.Out(t.NumOut()-1) == errorType
+	if t.NumOut() > 2 || (t.NumOut() == 2 && !returnsError) {
+		return reflect.Value{}, fmt.Errorf("cannot convert %s to %s, results must be (T, error)", obj.Type(), t)
+	}
+
+	// a func without an error result has no other way to report that the call failed than panicking
+	fail := func(err error) []reflect.Value {
+		if !returnsError {
+			panic(err)
+		}
+
+		results := make([]reflect.Value, t.NumOut())
+		for i := range results {
+			results[i] = reflect.Zero(t.Out(i))
+		}
+
+		results[len(results)-1] = reflect.ValueOf(&err).Elem()
+		return results
+	}
+
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		if t.IsVariadic() {
+			variadic := in[len(in)-1]
+			in = in[:len(in)-1]
+
+			for i := 0; i < variadic.Len(); i++ {
+				in = append(in, variadic.Index(i))
+			}
+		}
+
+		args := make([]Object, len(in))
+		for i, value := range in {
+			arg, err := c.fromGoValue(value)
+			if err != nil {
+				return fail(err)
+			}
+
+			args[i] = arg
+		}
+
+		result := c.Call(obj, args...)
+		if err, ok := result.(*Error); ok {
+			return fail(errors.New(err.Message))
+		}
+
+		results := make([]reflect.Value, t.NumOut())
+		if returnsError {
+			results[len(results)-1] = reflect.Zero(errorType)
+		}
+
+		if t.NumOut() > 0 && t.Out(0) != errorType {
+			value, err := c.toGoValue(result, t.Out(0))
+			if err != nil {
+				return fail(err)
+			}
+
+			results[0] = value
+		}
+
+		return results
+	}), nil
+}
+
+// Converts a Go value into an object
+func (c Converter) fromGoValue(value reflect.Value) (Object, error) {
+	return c.fromGoValueIn(value, nil)
+}
+
+/*
+A pointer, map or slice that a conversion is inside of. Like encoding/json, meeting one again while still inside
+it means the value is cyclic, a linked list or a child pointing at its parent, which is an error rather than a
+conversion that never ends. Slices also key on their length, since a slice and its prefix share a pointer.
+*/
+type goPointer struct {
+	ptr    uintptr
+	length int
+	typ    reflect.Type
+}
+
+func (c Converter) fromGoValueIn(value reflect.Value, seen map[goPointer]bool) (Object, error) {
+	if !value.IsValid() {
+		return NULL, nil
+	}
+
+	if value.Type().Implements(objectType) {
+		switch value.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func:
+			if value.IsNil() {
+				return NULL, nil
+			}
+		}
+
+		return value.Interface().(Object), nil
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if !value.IsNil() {
+			pointer := goPointer{ptr: value.Pointer(), typ: value.Type()}
+			if value.Kind() == reflect.Slice {
+				pointer.length = value.Len()
+			}
+
+			if seen[pointer] {
+				return nil, fmt.Errorf("cannot convert cyclic value of type %s", value.Type())
+			}
+
+			if seen == nil {
+				seen = map[goPointer]bool{}
+			}
+			seen[pointer] = true
+			defer delete(seen, pointer)
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		return &String{Value: value.String()}, nil
+
+	case reflect.Bool:
+		if value.Bool() {
+			return TRUE, nil
+		}
+		return FALSE, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: value.Int()}, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%d overflows INTEGER", value.Uint())
+		}
+		return &Integer{Value: int64(value.Uint())}, nil
+
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: value.Float()}, nil
+
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			return NULL, nil
+		}
+
+		return c.fromGoValueIn(value.Elem(), seen)
+
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return NULL, nil
+		}
+
+		elements := make([]Object, value.Len())
+		for i := range elements {
+			element, err := c.fromGoValueIn(value.Index(i), seen)
+			if err != nil {
+				return nil, err
+			}
+
+			elements[i] = element
+		}
+
+		return &Array{Elements: elements}, nil
+
+	case reflect.Map:
+		if value.IsNil() {
+			return NULL, nil
+		}
+
+		// Go maps have no order, sorting the keys gives the hash the same order every time
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return lessMapKey(keys[i], keys[j]) })
+
+		hash := NewHash()
+		for _, mapKey := range keys {
+			key, err := c.fromGoValueIn(mapKey, seen)
+			if err != nil {
+				return nil, err
+			}
+
+			if !IsHashable(key) {
+				return nil, fmt.Errorf("unusable as hash key: %s", key.Type())
+			}
+
+			element, err := c.fromGoValueIn(value.MapIndex(mapKey), seen)
+			if err != nil {
+				return nil, err
+			}
+
+			hash.Set(key, element)
+		}
+
+		return hash, nil
+
+	case reflect.Struct:
+		hash := NewHash()
+		for _, field := range structFields(value.Type()) {
+			element, err := c.fromGoValueIn(value.Field(field.index), seen)
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %w", field.key, err)
+			}
+
+			hash.Set(&String{Value: field.key}, element)
+		}
+
+		return hash, nil
+
+	case reflect.Func:
+		if value.IsNil() {
+			return NULL, nil
+		}
+
+		return c.NewGoBuiltin(funcName(value), value.Interface())
+	}
+
+	return nil, fmt.Errorf("cannot convert %s to an object", value.Type())
+}
+
+// The unqualified name of a Go func, for the error messages of the builtin wrapping it
+func funcName(fn reflect.Value) string {
+	name := "function"
+	if f := runtime.FuncForPC(fn.Pointer()); f != nil {
+		name = f.Name()
+	}
+
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return name
+}
+
+// Orders numbers by value and strings and booleans the way Go compares them, any other keys by how fmt prints them
+func lessMapKey(a, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	}
+
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
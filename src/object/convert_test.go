@@ -0,0 +1,308 @@
+package object
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type address struct {
+	Street string `monkey:"street"`
+	Zip    *int   `monkey:"zip"`
+}
+
+type person struct {
+	Name     string         `monkey:"name"`
+	Age      uint8          `monkey:"age"`
+	Score    float64        `monkey:"score"`
+	Admin    bool           `monkey:"admin"`
+	Tags     []string       `monkey:"tags"`
+	Labels   map[string]int `monkey:"labels"`
+	Address  *address       `monkey:"address"`
+	Extra    interface{}    `monkey:"extra"`
+	Grid     [2][2]int      `monkey:"grid"`
+	Untagged string
+	Secret   string `monkey:"-"`
+	private  string
+}
+
+type node struct {
+	Value int   `monkey:"value"`
+	Next  *node `monkey:"next"`
+}
+
+func TestRoundTrip(t *testing.T) {
+	zip := 12345
+
+	tests := []interface{}{
+		int64(42),
+		-7,
+		uint16(9),
+		3.5,
+		"héllo",
+		true,
+		[]string{"a", "b"},
+		[]interface{}{int64(1), "two", 3.0, nil, []interface{}{false}},
+		map[string]int{"a": 1, "b": 2},
+		map[int64]string{1: "one", 2: "two"},
+		map[string]interface{}{"nested": map[string]interface{}{"list": []interface{}{int64(1)}}},
+		person{
+			Name:     "Ada",
+			Age:      36,
+			Score:    99.5,
+			Admin:    true,
+			Tags:     []string{"math"},
+			Labels:   map[string]int{"x": 1},
+			Address:  &address{Street: "Main", Zip: &zip},
+			Extra:    map[string]interface{}{"k": "v"},
+			Grid:     [2][2]int{{1, 2}, {3, 4}},
+			Untagged: "kept",
+		},
+		&address{Street: "Side"},
+	}
+
+	for _, tt := range tests {
+		obj, err := FromGo(tt)
+		if err != nil {
+			t.Errorf("FromGo(%#v) returned error: %s", tt, err)
+			continue
+		}
+
+		target := reflect.New(reflect.TypeOf(tt))
+		if err := ToGo(obj, target.Interface()); err != nil {
+			t.Errorf("ToGo(%s) returned error: %s", obj.Inspect(), err)
+			continue
+		}
+
+		if got := target.Elem().Interface(); !reflect.DeepEqual(got, tt) {
+			t.Errorf("round trip changed the value.\nwant=%#v\ngot=%#v", tt, got)
+		}
+	}
+}
+
+func TestFromGo(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{nil, "null"},
+		{(*int)(nil), "null"},
+		{[]int(nil), "null"},
+		{(*Array)(nil), "null"},
+		{int8(-3), "-3"},
+		{float32(0.5), "0.5"},
+		{[3]bool{true, false, true}, "[true, false, true]"},
+		{&Integer{Value: 5}, "5"},
+		{struct {
+			A int `monkey:"a"`
+			B string
+			c int
+			D int `monkey:"-"`
+		}{A: 1, B: "x", c: 2, D: 3}, "{B: x, a: 1}"},
+	}
+
+	for _, tt := range tests {
+		obj, err := FromGo(tt.input)
+		if err != nil {
+			t.Errorf("FromGo(%#v) returned error: %s", tt.input, err)
+			continue
+		}
+
+		if got := sortedInspect(obj); got != tt.expected {
+			t.Errorf("FromGo(%#v) wrong. want=%s, got=%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestFromGoErrors(t *testing.T) {
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{uint64(1 << 63), "9223372036854775808 overflows INTEGER"},
+		{make(chan int), "cannot convert chan int to an object"},
+		{map[[1]int]int{{1}: 1}, "unusable as hash key: ARRAY"},
+		{struct{ C chan int }{}, `field "C": cannot convert chan int to an object`},
+	}
+
+	for _, tt := range tests {
+		_, err := FromGo(tt.input)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("FromGo(%#v) wrong error. want=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+}
+
+// Cyclic Go values are an error instead of a conversion that overflows the stack
+func TestFromGoCyclic(t *testing.T) {
+	list := &node{Value: 1}
+	list.Next = &node{Value: 2, Next: list}
+
+	slice := []interface{}{1, nil}
+	slice[1] = slice
+
+	hash := map[string]interface{}{}
+	hash["self"] = hash
+
+	tests := []struct {
+		input    interface{}
+		expected string
+	}{
+		{list, `field "next": field "next": cannot convert cyclic value of type *object.node`},
+		{slice, "cannot convert cyclic value of type []interface {}"},
+		{hash, "cannot convert cyclic value of type map[string]interface {}"},
+	}
+
+	for _, tt := range tests {
+		_, err := FromGo(tt.input)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("FromGo(%T) wrong error. want=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+
+	// the same pointer twice side by side isn't a cycle
+	shared := &node{Value: 3}
+	obj, err := FromGo([]*node{shared, shared})
+	if err != nil {
+		t.Fatalf("FromGo of a shared pointer returned error: %s", err)
+	}
+
+	if got := sortedInspect(obj); got != "[{value: 3, next: null}, {value: 3, next: null}]" {
+		t.Errorf("FromGo of a shared pointer wrong. got=%s", got)
+	}
+}
+
+func TestToGoNatural(t *testing.T) {
+	var got interface{}
+
+	hash := hashOf("a", &Integer{Value: 1})
+	hash.Set(&String{Value: "b"}, &Array{Elements: []Object{&Float{Value: 2}, NULL}})
+
+	if err := ToGo(hash, &got); err != nil {
+		t.Fatalf("ToGo returned error: %s", err)
+	}
+
+	expected := map[string]interface{}{"a": int64(1), "b": []interface{}{2.0, nil}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("wrong value. want=%#v, got=%#v", expected, got)
+	}
+
+	mixed := NewHash()
+	mixed.Set(&Integer{Value: 1}, TRUE)
+
+	if err := ToGo(mixed, &got); err != nil {
+		t.Fatalf("ToGo returned error: %s", err)
+	}
+
+	if !reflect.DeepEqual(got, map[interface{}]interface{}{int64(1): true}) {
+		t.Errorf("wrong value for a non-string key. got=%#v", got)
+	}
+
+	fn := &Function{}
+	if err := ToGo(fn, &got); err != nil || got != Object(fn) {
+		t.Errorf("a function didn't come back as itself. got=%#v (%v)", got, err)
+	}
+}
+
+func TestToGoErrors(t *testing.T) {
+	var n int8
+	var s string
+	var list [2]int
+	var p struct {
+		Age int `monkey:"age"`
+	}
+	var f func(int) int
+
+	tests := []struct {
+		obj      Object
+		target   interface{}
+		expected string
+	}{
+		{&Integer{Value: 300}, &n, "300 overflows int8"},
+		{&Integer{Value: 1}, &s, "cannot convert INTEGER to string"},
+		{NULL, &s, "cannot convert NULL to string"},
+		{&Array{Elements: []Object{&Integer{Value: 1}}}, &list, "cannot convert ARRAY of length 1 to [2]int"},
+		{hashOf("age", &String{Value: "old"}), &p, `field "age": cannot convert STRING to int`},
+		{&Function{}, &f, "cannot convert FUNCTION to func(int) int without an interpreter to call it"},
+		{&Integer{Value: 1}, s, "target must be a non-nil pointer, got string"},
+	}
+
+	for _, tt := range tests {
+		err := ToGo(tt.obj, tt.target)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("ToGo(%s) wrong error. want=%q, got=%v", tt.obj.Inspect(), tt.expected, err)
+		}
+	}
+}
+
+func TestFuncRoundTrip(t *testing.T) {
+	upper := func(s string) string { return strings.ToUpper(s) }
+
+	obj, err := FromGo(upper)
+	if err != nil {
+		t.Fatalf("FromGo returned error: %s", err)
+	}
+
+	result := obj.(*Builtin).Fn(&String{Value: "abc"})
+	if str, ok := result.(*String); !ok || str.Value != "ABC" {
+		t.Errorf("builtin returned wrong value. got=%#v", result)
+	}
+
+	var back func(string) string
+	if err := ToGo(obj, &back); err != nil {
+		t.Fatalf("ToGo returned error: %s", err)
+	}
+
+	if got := back("xyz"); got != "XYZ" {
+		t.Errorf("converted func returned %q", got)
+	}
+}
+
+func TestConverterCall(t *testing.T) {
+	double := &Builtin{Fn: func(args ...Object) Object {
+		if len(args) != 1 {
+			return &Error{Message: "expected one argument"}
+		}
+		return &Integer{Value: args[0].(*Integer).Value * 2}
+	}}
+
+	c := Converter{Call: func(fn Object, args ...Object) Object {
+		return fn.(*Builtin).Fn(args...)
+	}}
+
+	var f func(int) int
+	if err := c.ToGo(double, &f); err != nil {
+		t.Fatalf("ToGo returned error: %s", err)
+	}
+
+	if got := f(21); got != 42 {
+		t.Errorf("f(21) = %d", got)
+	}
+
+	var g func(...int) (int, error)
+	if err := c.ToGo(double, &g); err != nil {
+		t.Fatalf("ToGo returned error: %s", err)
+	}
+
+	if _, err := g(1, 2); err == nil || err.Error() != "expected one argument" {
+		t.Errorf("wrong error. got=%v", err)
+	}
+}
+
+// Inspect with the keys of a hash in order, so tests don't depend on map iteration order
+func sortedInspect(obj Object) string {
+	hash, ok := obj.(*Hash)
+	if !ok {
+		return obj.Inspect()
+	}
+
+	pairs := []string{}
+	for _, pair := range hash.Pairs() {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+
+	sort.Strings(pairs)
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
@@ -0,0 +1,187 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+const (
+	ENUM_OBJ         = "ENUM"
+	VARIANT_TYPE_OBJ = "VARIANT_TYPE"
+	VARIANT_OBJ      = "VARIANT"
+)
+
+// Declared by `enum Result { Ok(value), Err(message), Pending }`. The variants are reached through it, e.g.
+// Result.Ok(1) or Result.Pending
+type EnumType struct {
+	Name     string
+	Variants []*VariantType
+}
+
+func (e *EnumType) Type() ObjectType { return ENUM_OBJ }
+func (e *EnumType) Inspect() string {
+	variants := make([]string, len(e.Variants))
+	for i, variant := range e.Variants {
+		variants[i] = variant.declaration()
+	}
+
+	return fmt.Sprintf("enum %s { %s }", e.Name, strings.Join(variants, ", "))
+}
+
+func NewEnumType(name string) *EnumType {
+	return &EnumType{Name: name}
+}
+
+// The variant called name, nil when the enum has none
+func (e *EnumType) Variant(name string) *VariantType {
+	for _, variant := range e.Variants {
+		if variant.Name == name {
+			return variant
+		}
+	}
+
+	return nil
+}
+
+// Adds a variant with the given fields, none for a variant that carries no data
+func (e *EnumType) AddVariant(name string, fields []string) *VariantType {
+	variant := &VariantType{Enum: e, Name: name, Fields: fields}
+	if len(fields) == 0 {
+		variant.unit = &Variant{Variant: variant}
+	}
+
+	e.Variants = append(e.Variants, variant)
+	return variant
+}
+
+// A variant with fields is a constructor, one without is a value of its own
+func (e *EnumType) GetAttr(name string) (Object, bool) {
+	variant := e.Variant(name)
+	if variant == nil {
+		return &Error{Message: fmt.Sprintf("%s has no variant %s", e.Name, name)}, true
+	}
+
+	if len(variant.Fields) == 0 {
+		return variant.unit, true
+	}
+
+	return variant, true
+}
+
+// One of the variants of an enum. Calling it with one argument per field creates a Variant
+type VariantType struct {
+	Enum   *EnumType
+	Name   string
+	Fields []string
+
+	unit *Variant // the only value of a variant without fields
+}
+
+func (v *VariantType) Type() ObjectType { return VARIANT_TYPE_OBJ }
+func (v *VariantType) Inspect() string  { return v.Enum.Name + "." + v.declaration() }
+
+func (v *VariantType) declaration() string {
+	if len(v.Fields) == 0 {
+		return v.Name
+	}
+
+	return fmt.Sprintf("%s(%s)", v.Name, strings.Join(v.Fields, ", "))
+}
+
+// Implements Callable, the constructor takes the field values in declaration order
+func (v *VariantType) Call(args ...Object) Object {
+	if len(v.Fields) == 0 {
+		return &Error{Message: fmt.Sprintf("%s.%s takes no arguments", v.Enum.Name, v.Name)}
+	}
+
+	if len(args) != len(v.Fields) {
+		return &Error{Message: fmt.Sprintf("wrong number of arguments.\nexpected=%d, got=%d", len(v.Fields), len(args))}
+	}
+
+	values := make([]Object, len(args))
+	copy(values, args)
+
+	return &Variant{Variant: v, Values: values}
+}
+
+// A value of an enum: which variant it is and the values of the variant's fields
+type Variant struct {
+	Variant *VariantType
+	Values  []Object
+}
+
+func (v *Variant) Type() ObjectType { return VARIANT_OBJ }
+func (v *Variant) Inspect() string {
+	return inspect(v, nil)
+}
+
+func (v *Variant) inspect(seen visiting) string {
+	var out bytes.Buffer
+
+	out.WriteString(v.Variant.Enum.Name)
+	out.WriteString(".")
+	out.WriteString(v.Variant.Name)
+
+	if len(v.Variant.Fields) > 0 {
+		values := make([]string, len(v.Values))
+		for i, value := range v.Values {
+			values[i] = inspect(value, seen)
+		}
+
+		out.WriteString("(")
+		out.WriteString(strings.Join(values, ", "))
+		out.WriteString(")")
+	}
+
+	return out.String()
+}
+
+// The fields of a variant can be read by name, r.value for Result.Ok(value)
+func (v *Variant) GetAttr(name string) (Object, bool) {
+	for i, field := range v.Variant.Fields {
+		if field == name {
+			return v.Values[i], true
+		}
+	}
+
+	return &Error{Message: fmt.Sprintf("%s.%s has no field %s", v.Variant.Enum.Name, v.Variant.Name, name)}, true
+}
+
+// Variants are equal when they are the same variant of the same enum and their values are equal
+func (v *Variant) Equals(other Object) bool {
+	return v.equals(other, nil)
+}
+
+func (v *Variant) equals(other Object, seen comparing) bool {
+	otherVariant, ok := other.(*Variant)
+	if !ok || otherVariant.Variant != v.Variant {
+		return false
+	}
+
+	for i, value := range v.Values {
+		if !equal(value, otherVariant.Values[i], seen) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Only meaningful when every value is hashable, see IsHashable
+func (v *Variant) HashKey() HashKey {
+	return hashKey(v, nil)
+}
+
+func (v *Variant) hashKey(seen visiting) HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(v.Variant.Enum.Name + "." + v.Variant.Name))
+
+	for _, value := range v.Values {
+		key := hashKey(value.(Hashable), seen)
+		fmt.Fprintf(h, "\x00%s:%d", key.Type, key.Value)
+	}
+
+	return HashKey{Type: v.Type(), Value: h.Sum64()}
+}
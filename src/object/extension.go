@@ -0,0 +1,50 @@
+package object
+
+/*
+Interfaces a host type can implement to behave like a built-in Monkey value. The evaluator checks for them
+wherever the operation comes up, e.g. a database handle implementing AttrGetter and Callable can be used as
+
+	let rows = db.query("select 1");
+
+Any Object can implement as many of them as it likes. Methods that return an Object may return an *Error to
+fail the operation with that message.
+*/
+
+// Looked up for obj.name. ok is false when there is no such attribute
+type AttrGetter interface {
+	GetAttr(name string) (value Object, ok bool)
+}
+
+// Used for obj.name = value
+type AttrSetter interface {
+	SetAttr(name string, value Object) error
+}
+
+// Lets the object be called like a function
+type Callable interface {
+	Call(args ...Object) Object
+}
+
+// Used for obj[index]
+type Indexable interface {
+	Index(index Object) Object
+}
+
+// Used by for loops, and anything else walking over the object's elements
+type Iterable interface {
+	Iterate() Iterator
+}
+
+type Iterator interface {
+	// Returns the next element, ok is false once there are none left
+	Next() (value Object, ok bool)
+}
+
+/*
+Implements infix operators. The evaluator asks the left operand first, then the right one with reflected set,
+so a host type can support both `v * 2` and `2 * v`. ok is false for operators the type doesn't handle, which
+then fall back to the built-in behaviour (== and != compare with Equal, everything else is an error).
+*/
+type BinaryOperand interface {
+	BinaryOp(operator string, other Object, reflected bool) (result Object, ok bool)
+}
@@ -0,0 +1,151 @@
+package object
+
+import "runtime"
+
+const GENERATOR_OBJ = "GENERATOR"
+
+/*
+A Generator produces its values lazily, one each time Next is called. It is what calling a `fn*` function
+returns, and host code can make its own with NewGenerator.
+
+The function producing the values runs in a goroutine of its own, so it can stop in the middle of whatever it
+is doing when it yields a value. It only ever runs while Next waits for it though, never at the same time as the
+code consuming the values. A generator that is dropped before it finished is closed when it is garbage
+collected, which ends its goroutine.
+*/
+type Generator struct {
+	state *generatorState
+}
+
+// The goroutine only refers to this part, never to the Generator, so the Generator can be collected while the
+// goroutine is blocked in yield
+type generatorState struct {
+	run func(yield func(Object) bool) Object
+
+	started bool
+	done    bool
+	peeked  Object // a value Done had to fetch, handed out by the next call to Next
+
+	resume chan bool   // true to produce the next value, false to stop
+	values chan Object // closed once run returned
+
+	stopped bool // only touched by the goroutine
+}
+
+/*
+Makes a generator whose values are produced by run. run is called on the first Next, and each call to yield
+hands a value to Next and waits for the next one. yield returns false once the generator has been closed,
+run should then return as soon as it can.
+
+When run returns an *Error it becomes the last value of the generator, whatever else it returns is ignored.
+*/
+func NewGenerator(run func(yield func(Object) bool) Object) *Generator {
+	generator := &Generator{state: &generatorState{
+		run:    run,
+		resume: make(chan bool),
+		values: make(chan Object),
+	}}
+
+	runtime.SetFinalizer(generator, func(g *Generator) { g.state.close() })
+	return generator
+}
+
+func (g *Generator) Type() ObjectType { return GENERATOR_OBJ }
+func (g *Generator) Inspect() string  { return "generator" }
+
+// A generator is its own iterator, iterating continues from wherever earlier calls to Next left it
+func (g *Generator) Iterate() Iterator { return g }
+
+// Returns the next value, ok is false once the generator has finished or was closed
+func (g *Generator) Next() (Object, bool) {
+	s := g.state
+
+	if s.peeked != nil {
+		value := s.peeked
+		s.peeked = nil
+		return value, true
+	}
+
+	return s.next()
+}
+
+// Reports whether the generator has finished. This may have to run it up to its next value
+func (g *Generator) Done() bool {
+	s := g.state
+
+	if s.peeked == nil && !s.done {
+		if value, ok := s.next(); ok {
+			s.peeked = value
+		}
+	}
+
+	return s.peeked == nil
+}
+
+// Stops the generator, Next returns no more values after this
+func (g *Generator) Close() {
+	g.state.peeked = nil
+	g.state.close()
+}
+
+func (s *generatorState) next() (Object, bool) {
+	if s.done {
+		return nil, false
+	}
+
+	if s.started {
+		s.resume <- true
+	} else {
+		s.started = true
+		go s.produce()
+	}
+
+	value, ok := <-s.values
+	if !ok {
+		s.done = true
+		return nil, false
+	}
+
+	if _, isError := value.(*Error); isError {
+		// the error came from run returning, nothing follows it
+		<-s.values
+		s.done = true
+	}
+
+	return value, true
+}
+
+func (s *generatorState) close() {
+	if s.done {
+		return
+	}
+
+	s.done = true
+
+	if !s.started {
+		return
+	}
+
+	// the goroutine is waiting in yield, tell it to stop and wait until run has returned
+	s.resume <- false
+	for range s.values {
+	}
+}
+
+func (s *generatorState) produce() {
+	result := s.run(func(value Object) bool {
+		if s.stopped {
+			return false
+		}
+
+		s.values <- value
+		s.stopped = !<-s.resume
+		return !s.stopped
+	})
+
+	if err, ok := result.(*Error); ok && !s.stopped {
+		s.values <- err
+	}
+
+	close(s.values)
+}
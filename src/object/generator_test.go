@@ -0,0 +1,110 @@
+package object
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Yields the integers from 0 until it is closed or reaches limit, counting how many it yielded
+func counter(limit int64, yielded *int64) *Generator {
+	return NewGenerator(func(yield func(Object) bool) Object {
+		for i := int64(0); i < limit; i++ {
+			if !yield(&Integer{Value: i}) {
+				return nil
+			}
+
+			*yielded++
+		}
+
+		return nil
+	})
+}
+
+func TestGenerator(t *testing.T) {
+	var yielded int64
+	g := counter(3, &yielded)
+
+	for i := int64(0); i < 3; i++ {
+		value, ok := g.Next()
+		if !ok || value.(*Integer).Value != i {
+			t.Fatalf("Next() wrong. expected=%d, got=%v (%t)", i, value, ok)
+		}
+	}
+
+	if !g.Done() {
+		t.Errorf("expected the generator to be done")
+	}
+
+	if _, ok := g.Next(); ok {
+		t.Errorf("expected no more values")
+	}
+
+	// Done fetches a value ahead, Next still hands it out
+	g = counter(3, &yielded)
+	if g.Done() {
+		t.Errorf("expected the generator not to be done")
+	}
+
+	if value, ok := g.Next(); !ok || value.(*Integer).Value != 0 {
+		t.Errorf("Next() after Done() wrong. got=%v (%t)", value, ok)
+	}
+
+	// closing makes yield return false, nothing more is produced
+	yielded = 0
+	g = counter(100, &yielded)
+	g.Next()
+	g.Next()
+	g.Close()
+
+	if _, ok := g.Next(); ok {
+		t.Errorf("expected no values after Close")
+	}
+
+	if yielded != 1 {
+		t.Errorf("run went on after Close. yielded=%d", yielded)
+	}
+}
+
+func TestGeneratorError(t *testing.T) {
+	g := NewGenerator(func(yield func(Object) bool) Object {
+		yield(&Integer{Value: 1})
+		return &Error{Message: "failed"}
+	})
+
+	if value, ok := g.Next(); !ok || value.Inspect() != "1" {
+		t.Fatalf("first value wrong. got=%v (%t)", value, ok)
+	}
+
+	if value, ok := g.Next(); !ok || value.Inspect() != "ERROR failed" {
+		t.Fatalf("expected the error. got=%v (%t)", value, ok)
+	}
+
+	if _, ok := g.Next(); ok {
+		t.Errorf("expected no values after the error")
+	}
+}
+
+func TestAbandonedGeneratorsDoNotLeak(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	var yielded int64
+	for i := 0; i < 100; i++ {
+		g := counter(1000, &yielded)
+		g.Next()
+	}
+
+	if runtime.NumGoroutine() < before+100 {
+		t.Fatalf("expected a goroutine per started generator")
+	}
+
+	// finalizers run some time after a collection, give them a moment
+	for attempt := 0; attempt < 100 && runtime.NumGoroutine() > before; attempt++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines leaked. before=%d, after=%d", before, after)
+	}
+}
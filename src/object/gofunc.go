@@ -0,0 +1,144 @@
+package object
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+/*
+Wraps an ordinary Go function as a builtin, e.g.
+
+	repeat, err := object.NewGoBuiltin("repeat", func(s string, n int) (string, error) { ... })
+
+Arguments are checked against the parameter types and converted before the call: STRING to string, INTEGER
+to any integer type (if it fits), INTEGER or FLOAT to a float, BOOLEAN to bool, ARRAY to a slice and HASH to
+a map or struct, element by element, see Converter for the details. A parameter typed as Object, or as a
+concrete object type like *Array, receives the Monkey value untouched. Variadic functions take any number of trailing arguments.
+
+The function may return nothing, a value, an error, or a value and an error. A non-nil error becomes an
+Error object with the error's message, and a panic inside the function is turned into one as well rather
+than taking the host down.
+
+name is only used in error messages. An error is returned when fn isn't a function or has a parameter or
+result type that can't be converted.
+*/
+func NewGoBuiltin(name string, fn interface{}) (*Builtin, error) {
+	return Converter{}.NewGoBuiltin(name, fn)
+}
+
+// Like NewGoBuiltin, but arguments are converted with c, so a func parameter can take a Monkey function
+// when c can call one
+func (c Converter) NewGoBuiltin(name string, fn interface{}) (*Builtin, error) {
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return nil, fmt.Errorf("%s: expected a function, got %T", name, fn)
+	}
+
+	fnType := value.Type()
+
+	for i := 0; i < fnType.NumIn(); i++ {
+		param := fnType.In(i)
+		if fnType.IsVariadic() && i == fnType.NumIn()-1 {
+			param = param.Elem()
+		}
+
+		if !convertible(param) {
+			return nil, fmt.Errorf("%s: unsupported parameter type %s", name, param)
+		}
+	}
+
+	switch fnType.NumOut() {
+	case 0:
+	case 1:
+		if fnType.Out(0) != errorType && !convertible(fnType.Out(0)) {
+			return nil, fmt.Errorf("%s: unsupported result type %s", name, fnType.Out(0))
+		}
+	case 2:
+		if fnType.Out(1) != errorType || !convertible(fnType.Out(0)) {
+			return nil, fmt.Errorf("%s: results must be (T, error), got (%s, %s)", name, fnType.Out(0), fnType.Out(1))
+		}
+	default:
+		return nil, fmt.Errorf("%s: too many results", name)
+	}
+
+	return &Builtin{
+		Fn: func(args ...Object) Object {
+			return c.callGoFunction(name, value, args)
+		},
+		goFunc: value,
+	}, nil
+}
+
+func (c Converter) callGoFunction(name string, fn reflect.Value, args []Object) (result Object) {
+	fnType := fn.Type()
+	fixed := fnType.NumIn()
+	if fnType.IsVariadic() {
+		fixed--
+	}
+
+	if len(args) < fixed || (!fnType.IsVariadic() && len(args) > fixed) {
+		expected := fmt.Sprintf("%d", fixed)
+		if fnType.IsVariadic() {
+			expected = fmt.Sprintf("at least %d", fixed)
+		}
+
+		return &Error{Message: fmt.Sprintf("wrong number of arguments.\nexpected=%s, got=%d", expected, len(args))}
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		param := variadicParamType(fnType, i)
+
+		converted, err := c.toGoValue(arg, param)
+		if rangeErr, ok := err.(*rangeError); ok {
+			return &Error{Message: fmt.Sprintf("argument %d to %q is out of range for %s.\ngot %d", i+1, name, rangeErr.target, rangeErr.value)}
+		}
+		if err != nil {
+			return &Error{Message: fmt.Sprintf("argument %d to %q must be %s.\ngot %s", i+1, name, describeType(param), arg.Type())}
+		}
+
+		in[i] = converted
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			result = &Error{Message: fmt.Sprintf("%s panicked: %v", name, recovered)}
+		}
+	}()
+
+	out := fn.Call(in)
+
+	// a trailing error result wins over any value
+	if len(out) > 0 && fnType.Out(len(out)-1) == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return &Error{Message: err.Error()}
+		}
+
+		out = out[:len(out)-1]
+	}
+
+	if len(out) == 0 {
+		return NULL
+	}
+
+	converted, err := c.fromGoValue(out[0])
+	if err != nil {
+		return &Error{Message: fmt.Sprintf("%s: %s", name, err)}
+	}
+
+	return converted
+}
+
+// The type argument i is converted to, the element type for the variadic tail
+func variadicParamType(fnType reflect.Type, i int) reflect.Type {
+	if fnType.IsVariadic() && i >= fnType.NumIn()-1 {
+		return fnType.In(fnType.NumIn() - 1).Elem()
+	}
+
+	return fnType.In(i)
+}
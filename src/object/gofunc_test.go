@@ -0,0 +1,195 @@
+package object
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNewGoBuiltin(t *testing.T) {
+	tests := []struct {
+		fn       interface{}
+		args     []Object
+		expected interface{}
+	}{
+		{
+			func(s string, n int) string { return strings.Repeat(s, n) },
+			[]Object{&String{Value: "ab"}, &Integer{Value: 3}},
+			"ababab",
+		},
+		{
+			func(a, b float64) float64 { return a / b },
+			[]Object{&Integer{Value: 1}, &Float{Value: 4}},
+			0.25,
+		},
+		{
+			func(b bool) bool { return !b },
+			[]Object{TRUE},
+			false,
+		},
+		{
+			func(xs []int) int { return len(xs) },
+			[]Object{&Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}},
+			int64(2),
+		},
+		{
+			func(m map[string]int) int { return m["a"] },
+			[]Object{hashOf("a", &Integer{Value: 7})},
+			int64(7),
+		},
+		{
+			func(parts ...string) string { return strings.Join(parts, "-") },
+			[]Object{&String{Value: "a"}, &String{Value: "b"}, &String{Value: "c"}},
+			"a-b-c",
+		},
+		{
+			func(prefix string, parts ...string) int { return len(parts) },
+			[]Object{&String{Value: "a"}},
+			int64(0),
+		},
+		{
+			func(obj Object) string { return string(obj.Type()) },
+			[]Object{&Array{}},
+			"ARRAY",
+		},
+		{
+			func(array *Array) int { return len(array.Elements) },
+			[]Object{&Array{Elements: []Object{NULL}}},
+			int64(1),
+		},
+		{
+			func() {},
+			[]Object{},
+			nil,
+		},
+		{
+			func() error { return nil },
+			[]Object{},
+			nil,
+		},
+		{
+			func() []string { return []string{"x"} },
+			[]Object{},
+			[]Object{&String{Value: "x"}},
+		},
+
+		// errors
+		{
+			func(s string, n int) string { return "" },
+			[]Object{&String{Value: "a"}},
+			errors.New("wrong number of arguments.\nexpected=2, got=1"),
+		},
+		{
+			func(prefix string, parts ...string) string { return "" },
+			[]Object{},
+			errors.New("wrong number of arguments.\nexpected=at least 1, got=0"),
+		},
+		{
+			func(s string, n int) string { return "" },
+			[]Object{&String{Value: "a"}, &String{Value: "b"}},
+			errors.New("argument 2 to \"test\" must be INTEGER.\ngot STRING"),
+		},
+		{
+			func(xs []int) int { return 0 },
+			[]Object{&Array{Elements: []Object{&String{Value: "b"}}}},
+			errors.New("argument 1 to \"test\" must be ARRAY of INTEGER.\ngot ARRAY"),
+		},
+		{
+			func(n int8) int8 { return n },
+			[]Object{&Integer{Value: 300}},
+			errors.New("argument 1 to \"test\" is out of range for int8.\ngot 300"),
+		},
+		{
+			func(n uint) uint { return n },
+			[]Object{&Integer{Value: -1}},
+			errors.New("argument 1 to \"test\" is out of range for uint.\ngot -1"),
+		},
+		{
+			func(array *Array) int { return 0 },
+			[]Object{&Integer{Value: 1}},
+			errors.New("argument 1 to \"test\" must be ARRAY.\ngot INTEGER"),
+		},
+		{
+			func() (string, error) { return "ignored", errors.New("it broke") },
+			[]Object{},
+			errors.New("it broke"),
+		},
+		{
+			func() int { panic("oh no") },
+			[]Object{},
+			errors.New("test panicked: oh no"),
+		},
+	}
+
+	for i, tc := range tests {
+		builtin, err := NewGoBuiltin("test", tc.fn)
+		if err != nil {
+			t.Fatalf("tests[%d] - NewGoBuiltin returned error: %s", i, err)
+		}
+
+		result := builtin.Fn(tc.args...)
+
+		switch expected := tc.expected.(type) {
+		case nil:
+			if result != NULL {
+				t.Errorf("tests[%d] - expected NULL. got=%T (%+v)", i, result, result)
+			}
+		case error:
+			errObj, ok := result.(*Error)
+			if !ok {
+				t.Errorf("tests[%d] - object is not Error. got=%T (%+v)", i, result, result)
+				continue
+			}
+
+			if errObj.Message != expected.Error() {
+				t.Errorf("tests[%d] - wrong error message. expected=%q, got=%q", i, expected.Error(), errObj.Message)
+			}
+		case string:
+			if str, ok := result.(*String); !ok || str.Value != expected {
+				t.Errorf("tests[%d] - expected String %q. got=%T (%+v)", i, expected, result, result)
+			}
+		case int64:
+			if integer, ok := result.(*Integer); !ok || integer.Value != expected {
+				t.Errorf("tests[%d] - expected Integer %d. got=%T (%+v)", i, expected, result, result)
+			}
+		case float64:
+			if float, ok := result.(*Float); !ok || float.Value != expected {
+				t.Errorf("tests[%d] - expected Float %f. got=%T (%+v)", i, expected, result, result)
+			}
+		case bool:
+			if result != FALSE && result != TRUE || result.(*Boolean).Value != expected {
+				t.Errorf("tests[%d] - expected Boolean %t. got=%T (%+v)", i, expected, result, result)
+			}
+		case []Object:
+			if !Equal(result, &Array{Elements: expected}) {
+				t.Errorf("tests[%d] - expected Array %v. got=%T (%+v)", i, expected, result, result)
+			}
+		}
+	}
+}
+
+func TestNewGoBuiltinRejectsUnsupportedFunctions(t *testing.T) {
+	tests := []struct {
+		fn       interface{}
+		expected string
+	}{
+		{42, "test: expected a function, got int"},
+		{func(c chan int) {}, "test: unsupported parameter type chan int"},
+		{func() chan int { return nil }, "test: unsupported result type chan int"},
+		{func() (int, int) { return 0, 0 }, "test: results must be (T, error), got (int, int)"},
+		{func() (int, int, error) { return 0, 0, nil }, "test: too many results"},
+	}
+
+	for i, tc := range tests {
+		_, err := NewGoBuiltin("test", tc.fn)
+		if err == nil || err.Error() != tc.expected {
+			t.Errorf("tests[%d] - wrong error. expected=%q, got=%v", i, tc.expected, err)
+		}
+	}
+}
+
+func hashOf(key string, value Object) *Hash {
+	hash := NewHash()
+	hash.Set(&String{Value: key}, value)
+	return hash
+}
@@ -0,0 +1,56 @@
+package object
+
+// Returns an Iterator for arrays, strings (by character), hashes (by key), sets, bytes (as integers) and Iterable
+// objects, false for anything else
+func Iterate(obj Object) (Iterator, bool) {
+	switch obj := obj.(type) {
+	case Iterable:
+		return obj.Iterate(), true
+
+	case *Array:
+		return &sliceIterator{elements: obj.Elements}, true
+
+	case *String:
+		elements := []Object{}
+		for _, char := range obj.Value {
+			elements = append(elements, &String{Value: string(char)})
+		}
+
+		return &sliceIterator{elements: elements}, true
+
+	case *Hash:
+		elements := make([]Object, 0, obj.Len())
+		for _, pair := range obj.Pairs() {
+			elements = append(elements, pair.Key)
+		}
+
+		return &sliceIterator{elements: elements}, true
+
+	case *Set:
+		return &sliceIterator{elements: obj.Elements()}, true
+
+	case *Bytes:
+		elements := make([]Object, len(obj.Value))
+		for i, b := range obj.Value {
+			elements[i] = &Integer{Value: int64(b)}
+		}
+
+		return &sliceIterator{elements: elements}, true
+	}
+
+	return nil, false
+}
+
+type sliceIterator struct {
+	elements []Object
+	next     int
+}
+
+func (it *sliceIterator) Next() (Object, bool) {
+	if it.next >= len(it.elements) {
+		return nil, false
+	}
+
+	it.next++
+	return it.elements[it.next-1], true
+}
@@ -0,0 +1,33 @@
+package object
+
+import "fmt"
+
+const MODULE_OBJ = "MODULE"
+
+/*
+A module loaded by `import "lib/strings" as str;`. Scripts reach its exports as str.name, which reads the
+binding in the module's environment every time, so assignments the module makes later are seen too.
+*/
+type Module struct {
+	Name    string // the path it was imported by
+	ID      string // what the module loader identifies it by, e.g. the absolute name of its file
+	Env     *Environment
+	Exports []string
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "module " + m.Name }
+
+func (m *Module) GetAttr(name string) (Object, bool) {
+	for _, export := range m.Exports {
+		if export != name {
+			continue
+		}
+
+		if value, ok := m.Env.Get(name); ok {
+			return value, true
+		}
+	}
+
+	return &Error{Message: fmt.Sprintf("module %s has no export %s", m.Name, name)}, true
+}
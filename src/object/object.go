@@ -4,7 +4,10 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
 	"monkey/ast"
+	"reflect"
+	"strconv"
 	"strings"
 )
 
@@ -21,10 +24,19 @@ const (
 	FUNCTION_OBJ     = "FUNCTION"
 	STRING_OBJ       = "STRING"
 	BUILTIN_OBJ      = "BUILTIN"
+	BOUND_METHOD_OBJ = "BOUND_METHOD"
 	ARRAY_OBJ        = "ARRAY"
 	HASH_OBJ         = "HASH"
 )
 
+// There is only ever one null, true and false. The evaluator compares against these by identity, so host code
+// handing values to a script should use them rather than allocating its own
+var (
+	NULL  = &Null{}
+	TRUE  = &Boolean{Value: true}
+	FALSE = &Boolean{Value: false}
+)
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
@@ -32,32 +44,242 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 }
 
 func NewEnvironment() *Environment {
-	return &Environment{store: make(map[string]Object), outer: nil}
+	return &Environment{store: make(map[string]*Binding), outer: nil}
+}
+
+/*
+A binding is the storage behind a name. Environments hand out pointers to bindings rather than copying values,
+so a closure that captured a name sees later assignments to it and the defining scope sees the closure's.
+
+A binding with a nil Value is declared but not bound yet, e.g. the name of a let while its value is still being
+evaluated, or a name a closure captured before anything defined it. Lookups step over those and carry on
+outwards, as if the binding wasn't there.
+*/
+type Binding struct {
+	Value Object
 }
 
 type Environment struct {
-	store map[string]Object
+	store map[string]*Binding
 	outer *Environment
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
-	if !ok && e.outer != nil {
-		obj, ok = e.outer.Get(name)
+	binding, ok := e.store[name]
+	if ok && binding.Value != nil {
+		return binding.Value, true
 	}
-	return obj, ok
+
+	if e.outer != nil {
+		return e.outer.Get(name)
+	}
+
+	return nil, false
 }
 
+// Binds name in this environment, shadowing any outer binding, and updating the existing binding in place
+// when this environment already has one so closures that captured it see the new value
 func (e *Environment) Set(name string, val Object) Object {
-	e.store[name] = val
+	e.Declare(name).Value = val
 	return val
 }
 
+// Re-binds the nearest existing binding of name, the way `x = 5` does. Reports false when name isn't bound
+// anywhere, nothing is created in that case
+func (e *Environment) Assign(name string, val Object) bool {
+	binding, ok := e.store[name]
+	if ok && binding.Value != nil {
+		binding.Value = val
+		return true
+	}
+
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+
+	return false
+}
+
+// Makes sure this environment has a binding for name, unbound if it's new, and returns it
+func (e *Environment) Declare(name string) *Binding {
+	binding, ok := e.store[name]
+	if !ok {
+		binding = &Binding{}
+		e.store[name] = binding
+	}
+
+	return binding
+}
+
+/*
+Builds the environment a closure keeps: just the bindings for names, shared with the scopes they came from,
+in front of the outermost (global) environment.
+
+A name that isn't declared anywhere yet gets an unbound binding declared here, in the scope the closure is
+created in. That is what makes self and mutual recursion work, `let odd = ...` later on in the same scope fills
+in the binding `even` already captured. If nothing ever binds it locally the lookup falls through to the
+globals, so functions defined at the top level later on are still found.
+*/
+func (e *Environment) Capture(names []string) *Environment {
+	closure := NewEnclosedEnvironment(e.global())
+
+	for _, name := range names {
+		binding := e.lookup(name)
+		if binding == nil {
+			binding = e.Declare(name)
+		}
+
+		closure.store[name] = binding
+	}
+
+	return closure
+}
+
+// the nearest binding for name, bound or not
+func (e *Environment) lookup(name string) *Binding {
+	for env := e; env != nil; env = env.outer {
+		if binding, ok := env.store[name]; ok {
+			return binding
+		}
+	}
+
+	return nil
+}
+
+func (e *Environment) global() *Environment {
+	env := e
+	for env.outer != nil {
+		env = env.outer
+	}
+
+	return env
+}
+
 type Object interface {
 	Type() ObjectType
 	Inspect() string
 }
 
+// Objects that know how to compare themselves by value implement Equaler, everything else is only equal to
+// itself. Host types can implement it to take part in == and != inside scripts
+type Equaler interface {
+	Equals(other Object) bool
+}
+
+// Value equality used by == and !=. Integers and floats compare numerically, arrays and hashes compare their
+// contents, and anything without an Equaler falls back to identity
+func Equal(left, right Object) bool {
+	return equal(left, right, nil)
+}
+
+/*
+The pairs of values holding other values that a comparison has started on and not found unequal. Like
+reflect.DeepEqual, a pair met again is taken to be equal, so values that contain themselves compare without recursing forever and
+are unequal only when something else in them differs.
+*/
+type comparing map[[2]Object]bool
+
+// Values holding other values compare those through equal, passing on the pairs already being compared
+type deepEqualer interface {
+	equals(other Object, seen comparing) bool
+}
+
+func equal(left, right Object, seen comparing) bool {
+	if left == right {
+		return true
+	}
+
+	// ask both sides so a host type on the right still gets a say against a builtin type on the left
+	return equalFrom(left, right, seen) || equalFrom(right, left, seen)
+}
+
+func equalFrom(object, other Object, seen comparing) bool {
+	switch equaler := object.(type) {
+	case deepEqualer:
+		pair := [2]Object{object, other}
+		if seen[pair] {
+			return true
+		}
+
+		if seen == nil {
+			seen = comparing{}
+		}
+		seen[pair] = true
+
+		// a pair found unequal mustn't pass as equal when the other side is asked
+		if !equaler.equals(other, seen) {
+			delete(seen, pair)
+			return false
+		}
+
+		return true
+
+	case Equaler:
+		return equaler.Equals(other)
+	}
+
+	return false
+}
+
+/*
+The values holding other values that an Inspect, IsHashable or HashKey is inside of. Struct fields can be set and
+arrays pushed to, so a script can make a value that holds itself, and meeting a value again while still inside
+it means it is cyclic. A value is left again once it is done, so the same value twice side by side is fine.
+*/
+type visiting map[Object]bool
+
+// Values holding other values inspect those through inspect, passing on the values already being inspected
+type deepInspector interface {
+	inspect(seen visiting) string
+}
+
+// A value met again while it is being inspected prints as ... rather than recursing forever
+func inspect(obj Object, seen visiting) string {
+	inspector, ok := obj.(deepInspector)
+	if !ok {
+		return obj.Inspect()
+	}
+
+	if seen[obj] {
+		return "..."
+	}
+
+	if seen == nil {
+		seen = visiting{}
+	}
+	seen[obj] = true
+	defer delete(seen, obj)
+
+	return inspector.inspect(seen)
+}
+
+// Values holding other values hash those through hashKey, passing on the values already being hashed
+type deepHasher interface {
+	hashKey(seen visiting) HashKey
+}
+
+// A cyclic value isn't hashable, see IsHashable, so meeting a value again only happens when that wasn't asked
+// first. It then hashes as its type alone rather than recursing forever
+func hashKey(key Hashable, seen visiting) HashKey {
+	hasher, ok := key.(deepHasher)
+	if !ok {
+		return key.HashKey()
+	}
+
+	obj := key.(Object)
+	if seen[obj] {
+		return HashKey{Type: obj.Type()}
+	}
+
+	if seen == nil {
+		seen = visiting{}
+	}
+	seen[obj] = true
+	defer delete(seen, obj)
+
+	return hasher.hashKey(seen)
+}
+
 // implements Object and HashKey
 type Integer struct {
 	Value int64
@@ -75,6 +297,24 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+func (i *Integer) Equals(other Object) bool {
+	switch other := other.(type) {
+	case *Integer:
+		return i.Value == other.Value
+	case *Float:
+		return intEqualsFloat(i.Value, other.Value)
+	default:
+		return false
+	}
+}
+
+// Compares exactly rather than through float64(i), which rounds big integers and would make 9007199254740993
+// equal to 9007199254740992.0 even though the two hash apart
+func intEqualsFloat(i int64, f float64) bool {
+	n, ok := FloatToInt(f)
+	return ok && n == i
+}
+
 // implements Object and Hashkey
 type Float struct {
 	Value float64
@@ -84,12 +324,72 @@ func (f *Float) Type() ObjectType {
 	return FLOAT_OBJ
 }
 
+// Floats are printed with the fewest digits that still round-trip back to the same value, and always keep a
+// decimal point or exponent so they can't be confused with integers (1.0 rather than 1). Very large or very
+// small magnitudes switch to exponent notation, the same cut-offs Python uses.
 func (f *Float) Inspect() string {
-	return fmt.Sprintf("%f", f.Value)
+	return FormatFloat(f.Value)
 }
 
+// Since 1 == 1.0 is true, a float holding a whole number hashes exactly like the matching integer so
+// {1: "a"}[1.0] finds the pair. Every other float hashes on its IEEE-754 bits, with -0.0 folded into 0.0 and
+// every NaN folded into a single key.
 func (f *Float) HashKey() HashKey {
-	return HashKey{Type: f.Type(), Value: uint64(f.Value)}
+	if i, ok := FloatToInt(f.Value); ok {
+		return (&Integer{Value: i}).HashKey()
+	}
+
+	if math.IsNaN(f.Value) {
+		return HashKey{Type: f.Type(), Value: math.Float64bits(math.NaN())}
+	}
+
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
+func (f *Float) Equals(other Object) bool {
+	switch other := other.(type) {
+	case *Float:
+		return f.Value == other.Value
+	case *Integer:
+		return intEqualsFloat(other.Value, f.Value)
+	default:
+		return false
+	}
+}
+
+func FormatFloat(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+
+	// shortest representation in exponent form, e.g. 1e+20 or 1.5e-07, which gives us the decimal exponent
+	exponentForm := strconv.FormatFloat(value, 'e', -1, 64)
+	exponent, _ := strconv.Atoi(exponentForm[strings.IndexByte(exponentForm, 'e')+1:])
+
+	if value != 0 && (exponent < -4 || exponent >= 16) {
+		return exponentForm
+	}
+
+	decimalForm := strconv.FormatFloat(value, 'f', -1, 64)
+	if !strings.Contains(decimalForm, ".") {
+		decimalForm += ".0"
+	}
+
+	return decimalForm
+}
+
+// Reports the integer a float is equal to, if it is a whole number that fits into an int64
+func FloatToInt(value float64) (int64, bool) {
+	if value != math.Trunc(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+		return 0, false
+	}
+
+	return int64(value), true
 }
 
 // implement Object and Hashkey
@@ -117,11 +417,20 @@ func (b *Boolean) HashKey() HashKey {
 	return HashKey{Type: b.Type(), Value: value}
 }
 
+func (b *Boolean) Equals(other Object) bool {
+	otherBoolean, ok := other.(*Boolean)
+	return ok && b.Value == otherBoolean.Value
+}
+
 // implements Object
 type Null struct{}
 
 func (n *Null) Type() ObjectType { return NULL_OBJ }
 func (n *Null) Inspect() string  { return "null" }
+func (n *Null) Equals(other Object) bool {
+	_, ok := other.(*Null)
+	return ok
+}
 
 // implements Object
 type ReturnValue struct {
@@ -144,6 +453,7 @@ type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Generator  bool // calling it returns a Generator running the body
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -156,6 +466,9 @@ func (f *Function) Inspect() string {
 	}
 
 	out.WriteString("fn")
+	if f.Generator {
+		out.WriteString("*")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
@@ -178,15 +491,38 @@ func (s *String) HashKey() HashKey {
 
 	return HashKey{Type: s.Type(), Value: h.Sum64()}
 }
+func (s *String) Equals(other Object) bool {
+	otherString, ok := other.(*String)
+	return ok && s.Value == otherString.Value
+}
 
 // implements Object partially
 type Builtin struct {
 	Fn BuiltinFunction
+
+	goFunc reflect.Value // the Go function NewGoBuiltin wrapped, so ToGo can hand it back
 }
 
 func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
 func (b *Builtin) Inspect() string  { return "builtin function" }
 
+// A method looked up on a value, e.g. p.dist. Calling it calls Method with Receiver in front of the arguments
+type BoundMethod struct {
+	Receiver Object
+	Name     string
+	Method   Object
+}
+
+func (bm *BoundMethod) Type() ObjectType { return BOUND_METHOD_OBJ }
+func (bm *BoundMethod) Inspect() string {
+	receiver := string(bm.Receiver.Type())
+	if instance, ok := bm.Receiver.(*Instance); ok {
+		receiver = instance.Struct.Name
+	}
+
+	return fmt.Sprintf("bound method %s.%s", receiver, bm.Name)
+}
+
 // Implement Object
 type Array struct {
 	Elements []Object
@@ -194,11 +530,15 @@ type Array struct {
 
 func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
 func (ao *Array) Inspect() string {
+	return inspect(ao, nil)
+}
+
+func (ao *Array) inspect(seen visiting) string {
 	var out bytes.Buffer
 
 	elements := []string{}
 	for _, e := range ao.Elements {
-		elements = append(elements, e.Inspect())
+		elements = append(elements, inspect(e, seen))
 	}
 	out.WriteString("[")
 	out.WriteString(strings.Join(elements, ", "))
@@ -207,10 +547,72 @@ func (ao *Array) Inspect() string {
 	return out.String()
 }
 
+func (ao *Array) Equals(other Object) bool {
+	return ao.equals(other, nil)
+}
+
+func (ao *Array) equals(other Object, seen comparing) bool {
+	otherArray, ok := other.(*Array)
+	if !ok || len(ao.Elements) != len(otherArray.Elements) {
+		return false
+	}
+
+	for i, element := range ao.Elements {
+		if !equal(element, otherArray.Elements[i], seen) {
+			return false
+		}
+	}
+
+	return true
+}
+
 type Hashable interface {
 	HashKey() HashKey
 }
 
+// Reports whether obj can be used as a hash key. Instances and enum variants are only hashable when all the
+// values they hold are, and not when they hold themselves
+func IsHashable(obj Object) bool {
+	return isHashable(obj, nil)
+}
+
+func isHashable(obj Object, seen visiting) bool {
+	var values []Object
+	switch value := obj.(type) {
+	case *Instance:
+		values = value.Fields
+	case *Variant:
+		values = value.Values
+
+	case Hashable:
+		return true
+	default:
+		return false
+	}
+
+	if seen[obj] {
+		return false
+	}
+
+	if seen == nil {
+		seen = visiting{}
+	}
+	seen[obj] = true
+	defer delete(seen, obj)
+
+	return allHashable(values, seen)
+}
+
+func allHashable(objects []Object, seen visiting) bool {
+	for _, obj := range objects {
+		if !isHashable(obj, seen) {
+			return false
+		}
+	}
+
+	return true
+}
+
 type HashKey struct {
 	Type  ObjectType
 	Value uint64
@@ -221,9 +623,134 @@ type HashPair struct {
 	Value Object
 }
 
-// implements Object
+/*
+A hash keeps its pairs in the order their keys were first set, which is the order Inspect, keys() and for loops
+see them in. Lookups go through a map from HashKey to the pairs with that key, and keys are compared with Equal
+once their HashKeys match, so two keys whose hashes collide are still stored apart.
+
+Deleting leaves a pair with a nil key behind rather than moving every later pair down. The holes are squeezed
+out once they make up half of the pairs, or when the pairs are asked for, so a delete costs O(1) on average.
+
+implements Object
+*/
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	pairs   []HashPair
+	buckets map[HashKey][]int // positions in pairs
+	deleted int               // pairs with a nil key
+}
+
+func NewHash() *Hash {
+	return &Hash{buckets: make(map[HashKey][]int)}
+}
+
+// Looks up the value stored under key, the bool is false when the key is missing
+func (h *Hash) Get(key Hashable) (Object, bool) {
+	if i := h.find(key.(Object), key.HashKey()); i >= 0 {
+		return h.pairs[i].Value, true
+	}
+
+	return nil, false
+}
+
+// Stores value under key. A key that is already there keeps its place. The key must be hashable, see IsHashable
+func (h *Hash) Set(key Object, value Object) {
+	if h.buckets == nil {
+		h.buckets = make(map[HashKey][]int)
+	}
+
+	hashKey := key.(Hashable).HashKey()
+	if i := h.find(key, hashKey); i >= 0 {
+		h.pairs[i].Value = value
+		return
+	}
+
+	h.buckets[hashKey] = append(h.buckets[hashKey], len(h.pairs))
+	h.pairs = append(h.pairs, HashPair{Key: key, Value: value})
+}
+
+// Removes key and its value, the bool is false when the key wasn't there. The keys after it keep their order
+func (h *Hash) Delete(key Hashable) bool {
+	hashKey := key.HashKey()
+	i := h.find(key.(Object), hashKey)
+	if i < 0 {
+		return false
+	}
+
+	bucket := h.buckets[hashKey]
+	for n, position := range bucket {
+		if position == i {
+			bucket = append(bucket[:n], bucket[n+1:]...)
+			break
+		}
+	}
+
+	if len(bucket) == 0 {
+		delete(h.buckets, hashKey)
+	} else {
+		h.buckets[hashKey] = bucket
+	}
+
+	h.pairs[i] = HashPair{}
+	h.deleted++
+
+	if h.deleted*2 >= len(h.pairs) {
+		h.compact()
+	}
+
+	return true
+}
+
+// Drops the pairs left behind by Delete, which moves the rest so the buckets are built again
+func (h *Hash) compact() {
+	if h.deleted == 0 {
+		return
+	}
+
+	pairs := make([]HashPair, 0, len(h.pairs)-h.deleted)
+	h.buckets = make(map[HashKey][]int, len(h.pairs)-h.deleted)
+	for _, pair := range h.pairs {
+		if pair.Key == nil {
+			continue
+		}
+
+		hashKey := pair.Key.(Hashable).HashKey()
+		h.buckets[hashKey] = append(h.buckets[hashKey], len(pairs))
+		pairs = append(pairs, pair)
+	}
+
+	h.pairs = pairs
+	h.deleted = 0
+}
+
+// The pairs in insertion order. The slice belongs to the hash, use Set to change it
+func (h *Hash) Pairs() []HashPair {
+	h.compact()
+	return h.pairs
+}
+
+func (h *Hash) Len() int {
+	return len(h.pairs) - h.deleted
+}
+
+func (h *Hash) find(key Object, hashKey HashKey) int {
+	for _, i := range h.buckets[hashKey] {
+		if sameKey(h.pairs[i].Key, key) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Keys are the same when they are equal, and every NaN is the same key as any other even though NaN != NaN
+func sameKey(a, b Object) bool {
+	if Equal(a, b) {
+		return true
+	}
+
+	left, ok := a.(*Float)
+	right, ok2 := b.(*Float)
+	return ok && ok2 && math.IsNaN(left.Value) && math.IsNaN(right.Value)
 }
 
 func (h *Hash) Type() ObjectType {
@@ -231,11 +758,15 @@ func (h *Hash) Type() ObjectType {
 }
 
 func (h *Hash) Inspect() string {
+	return inspect(h, nil)
+}
+
+func (h *Hash) inspect(seen visiting) string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	for _, pair := range h.Pairs() {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", inspect(pair.Key, seen), inspect(pair.Value, seen)))
 	}
 
 	out.WriteString("{")
@@ -244,3 +775,24 @@ func (h *Hash) Inspect() string {
 
 	return out.String()
 }
+
+// Hashes are equal when they hold equal values under the same keys, in whatever order
+func (h *Hash) Equals(other Object) bool {
+	return h.equals(other, nil)
+}
+
+func (h *Hash) equals(other Object, seen comparing) bool {
+	otherHash, ok := other.(*Hash)
+	if !ok || h.Len() != otherHash.Len() {
+		return false
+	}
+
+	for _, pair := range h.Pairs() {
+		otherValue, ok := otherHash.Get(pair.Key.(Hashable))
+		if !ok || !equal(pair.Value, otherValue, seen) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -1,6 +1,10 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"testing"
+	"time"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -20,3 +24,439 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{0.1, "0.1"},
+		{1, "1.0"},
+		{-2.5, "-2.5"},
+		{0, "0.0"},
+		{1.0 / 3.0, "0.3333333333333333"},
+		{1e15, "1000000000000000.0"},
+		{1e20, "1e+20"},
+		{1.5e-7, "1.5e-07"},
+		{0.0001, "0.0001"},
+		{math.NaN(), "NaN"},
+		{math.Inf(1), "Inf"},
+		{math.Inf(-1), "-Inf"},
+	}
+
+	for _, tc := range tests {
+		float := &Float{Value: tc.value}
+		if float.Inspect() != tc.expected {
+			t.Errorf("Float.Inspect() wrong. expected=%q, got=%q", tc.expected, float.Inspect())
+		}
+	}
+}
+
+func TestFloatHashKey(t *testing.T) {
+	if (&Float{Value: 1.2}).HashKey() == (&Float{Value: 1.7}).HashKey() {
+		t.Errorf("floats with different values have same hash keys")
+	}
+
+	if (&Float{Value: 1.5}).HashKey() != (&Float{Value: 1.5}).HashKey() {
+		t.Errorf("floats with same value have different hash keys")
+	}
+
+	if (&Float{Value: 2}).HashKey() != (&Integer{Value: 2}).HashKey() {
+		t.Errorf("whole float and equal integer have different hash keys")
+	}
+
+	if (&Float{Value: 0}).HashKey() != (&Float{Value: math.Copysign(0, -1)}).HashKey() {
+		t.Errorf("0.0 and -0.0 have different hash keys")
+	}
+
+	if (&Float{Value: math.NaN()}).HashKey() != (&Float{Value: -math.NaN()}).HashKey() {
+		t.Errorf("NaN values have different hash keys")
+	}
+}
+
+type alwaysEqual struct{}
+
+func (a *alwaysEqual) Type() ObjectType         { return "ALWAYS_EQUAL" }
+func (a *alwaysEqual) Inspect() string          { return "alwaysEqual" }
+func (a *alwaysEqual) Equals(other Object) bool { return true }
+
+func TestEqual(t *testing.T) {
+	tests := []struct {
+		left     Object
+		right    Object
+		expected bool
+	}{
+		{&Integer{Value: 1}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &Float{Value: 1}, true},
+		{&Float{Value: 1.5}, &Integer{Value: 1}, false},
+		// exactly, not through a float that rounds the integer
+		{&Integer{Value: 9007199254740993}, &Float{Value: 9007199254740992}, false},
+		{&Float{Value: 9007199254740992}, &Integer{Value: 9007199254740993}, false},
+		{&Integer{Value: 9007199254740992}, &Float{Value: 9007199254740992}, true},
+		{&Integer{Value: math.MaxInt64}, &Float{Value: math.MaxInt64}, false},
+		{&Integer{Value: math.MinInt64}, &Float{Value: math.MinInt64}, true},
+		{&String{Value: "a"}, &String{Value: "a"}, true},
+		{&String{Value: "a"}, &Integer{Value: 1}, false},
+		{&Null{}, &Null{}, true},
+		{&Array{Elements: []Object{&Integer{Value: 1}}}, &Array{Elements: []Object{&Integer{Value: 1}}}, true},
+		{&Array{Elements: []Object{&Integer{Value: 1}}}, &Array{Elements: []Object{}}, false},
+		{&Builtin{}, &Builtin{}, false},
+		// user types implementing Equaler are consulted on either side
+		{&alwaysEqual{}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &alwaysEqual{}, true},
+	}
+
+	for i, tc := range tests {
+		if Equal(tc.left, tc.right) != tc.expected {
+			t.Errorf("tests[%d] - Equal(%s, %s) wrong. expected=%t", i, tc.left.Inspect(), tc.right.Inspect(), tc.expected)
+		}
+	}
+
+	hash := NewHash()
+	hash.Set(&String{Value: "a"}, &Integer{Value: 1})
+	same := NewHash()
+	same.Set(&String{Value: "a"}, &Float{Value: 1})
+
+	if !Equal(hash, same) {
+		t.Errorf("hashes with equal pairs are not equal")
+	}
+}
+
+func TestEqualCyclicValues(t *testing.T) {
+	// each array holds itself
+	cyclic := func(tail Object) *Array {
+		array := &Array{}
+		array.Elements = []Object{array, tail}
+		return array
+	}
+
+	if !Equal(cyclic(&Integer{Value: 1}), cyclic(&Integer{Value: 1})) {
+		t.Errorf("cyclic arrays with equal elements are not equal")
+	}
+
+	if Equal(cyclic(&Integer{Value: 1}), cyclic(&Integer{Value: 2})) {
+		t.Errorf("cyclic arrays with different elements are equal")
+	}
+
+	// a pair found unequal on one side stays unequal when the other side is asked
+	inner := &Array{Elements: []Object{&Array{Elements: []Object{&Integer{Value: 1}}}}}
+	other := &Array{Elements: []Object{&Array{Elements: []Object{&Integer{Value: 2}}}}}
+	if Equal(inner, other) {
+		t.Errorf("arrays with different nested elements are equal")
+	}
+
+	point := &StructType{Name: "P", Fields: []string{"next"}}
+	left, right := &Instance{Struct: point}, &Instance{Struct: point}
+	left.Fields = []Object{right}
+	right.Fields = []Object{left}
+	if !Equal(left, right) {
+		t.Errorf("instances pointing at each other are not equal")
+	}
+}
+
+// Values that hold themselves stop at the cycle instead of overflowing the stack
+func TestCyclicInstances(t *testing.T) {
+	node := &StructType{Name: "N", Fields: []string{"next"}}
+	a := &Instance{Struct: node}
+	a.Fields = []Object{a}
+
+	if a.Inspect() != "N{next: ...}" {
+		t.Errorf("Inspect() wrong for a cyclic instance. got=%q", a.Inspect())
+	}
+
+	if IsHashable(a) {
+		t.Errorf("a cyclic instance is hashable")
+	}
+
+	if key := a.HashKey(); key.Type != INSTANCE_OBJ {
+		t.Errorf("HashKey() wrong for a cyclic instance. got=%+v", key)
+	}
+
+	// through an array as well
+	list := &Array{}
+	b := &Instance{Struct: node, Fields: []Object{list}}
+	list.Elements = []Object{b, &Integer{Value: 1}}
+	if b.Inspect() != "N{next: [..., 1]}" {
+		t.Errorf("Inspect() wrong for an instance in a cyclic array. got=%q", b.Inspect())
+	}
+
+	// the same value twice side by side isn't a cycle
+	leaf := &Instance{Struct: node, Fields: []Object{&Integer{Value: 0}}}
+	pair := &Array{Elements: []Object{leaf, leaf}}
+	if pair.Inspect() != "[N{next: 0}, N{next: 0}]" {
+		t.Errorf("Inspect() wrong for a repeated instance. got=%q", pair.Inspect())
+	}
+
+	if !IsHashable(&Instance{Struct: node, Fields: []Object{leaf}}) {
+		t.Errorf("an instance holding a hashable instance isn't hashable")
+	}
+}
+
+// A variant holding an instance that holds the variant is cyclic as well
+func TestCyclicVariants(t *testing.T) {
+	box := &StructType{Name: "Box", Fields: []string{"value"}}
+	option := NewEnumType("Option")
+	some := option.AddVariant("Some", []string{"value"})
+
+	instance := &Instance{Struct: box, Fields: []Object{&Integer{Value: 0}}}
+	variant := some.Call(instance).(*Variant)
+	instance.Fields[0] = variant
+
+	if variant.Inspect() != "Option.Some(Box{value: ...})" {
+		t.Errorf("Inspect() wrong for a cyclic variant. got=%q", variant.Inspect())
+	}
+
+	if IsHashable(variant) || IsHashable(instance) {
+		t.Errorf("a cyclic variant is hashable")
+	}
+
+	if key := variant.HashKey(); key.Type != VARIANT_OBJ {
+		t.Errorf("HashKey() wrong for a cyclic variant. got=%+v", key)
+	}
+
+	if !IsHashable(some.Call(&Integer{Value: 1})) {
+		t.Errorf("a variant holding an integer isn't hashable")
+	}
+}
+
+func TestHashKeepsInsertionOrder(t *testing.T) {
+	hash := NewHash()
+	hash.Set(&String{Value: "b"}, &Integer{Value: 1})
+	hash.Set(&Integer{Value: 3}, &Integer{Value: 2})
+	hash.Set(&String{Value: "a"}, &Integer{Value: 3})
+	// setting a key again changes its value but not its place
+	hash.Set(&String{Value: "b"}, &Integer{Value: 4})
+	// 3.0 is the same key as 3
+	hash.Set(&Float{Value: 3}, &Integer{Value: 5})
+
+	expected := `{b: 4, 3: 5, a: 3}`
+	for i := 0; i < 10; i++ {
+		if hash.Inspect() != expected {
+			t.Fatalf("Hash.Inspect() wrong. expected=%q, got=%q", expected, hash.Inspect())
+		}
+	}
+
+	if hash.Len() != 3 {
+		t.Errorf("Hash.Len() wrong. expected=3, got=%d", hash.Len())
+	}
+
+	reordered := NewHash()
+	for _, key := range []string{"a", "b"} {
+		value, _ := hash.Get(&String{Value: key})
+		reordered.Set(&String{Value: key}, value)
+	}
+	reordered.Set(&Integer{Value: 3}, &Integer{Value: 5})
+
+	if !Equal(hash, reordered) {
+		t.Errorf("hashes with the same pairs in another order are not equal")
+	}
+}
+
+// A key whose HashKey is always the same, as two strings whose hashes collide would have
+type collidingKey struct{ name string }
+
+func (k *collidingKey) Type() ObjectType { return "COLLIDING" }
+func (k *collidingKey) Inspect() string  { return k.name }
+func (k *collidingKey) HashKey() HashKey { return HashKey{Type: "COLLIDING", Value: 42} }
+func (k *collidingKey) Equals(other Object) bool {
+	o, ok := other.(*collidingKey)
+	return ok && o.name == k.name
+}
+
+func TestHashKeepsCollidingKeysApart(t *testing.T) {
+	hash := NewHash()
+	hash.Set(&collidingKey{"x"}, &Integer{Value: 1})
+	hash.Set(&collidingKey{"y"}, &Integer{Value: 2})
+	hash.Set(&collidingKey{"x"}, &Integer{Value: 3})
+
+	if hash.Inspect() != "{x: 3, y: 2}" {
+		t.Fatalf("Hash.Inspect() wrong. got=%q", hash.Inspect())
+	}
+
+	for name, expected := range map[string]int64{"x": 3, "y": 2} {
+		value, ok := hash.Get(&collidingKey{name})
+		if !ok || value.(*Integer).Value != expected {
+			t.Errorf("Get(%s) wrong. expected=%d, got=%v", name, expected, value)
+		}
+	}
+
+	if _, ok := hash.Get(&collidingKey{"z"}); ok {
+		t.Errorf("Get(z) found a key that was never set")
+	}
+
+	// every NaN is the same key
+	hash.Set(&Float{Value: math.NaN()}, &Integer{Value: 4})
+	if value, ok := hash.Get(&Float{Value: -math.NaN()}); !ok || value.(*Integer).Value != 4 {
+		t.Errorf("Get(NaN) wrong. got=%v", value)
+	}
+}
+
+func TestHashDelete(t *testing.T) {
+	hash := NewHash()
+	for i, key := range []string{"a", "b", "c", "d"} {
+		hash.Set(&String{Value: key}, &Integer{Value: int64(i)})
+	}
+
+	if !hash.Delete(&String{Value: "b"}) {
+		t.Fatalf("Delete(b) didn't find the key")
+	}
+
+	if hash.Delete(&String{Value: "b"}) {
+		t.Errorf("Delete(b) found the key a second time")
+	}
+
+	if hash.Inspect() != "{a: 0, c: 2, d: 3}" {
+		t.Errorf("Hash.Inspect() wrong after Delete. got=%q", hash.Inspect())
+	}
+
+	// the keys after the deleted one can still be found, and a key set again goes to the end
+	if value, ok := hash.Get(&String{Value: "d"}); !ok || value.(*Integer).Value != 3 {
+		t.Errorf("Get(d) wrong after Delete. got=%v", value)
+	}
+
+	hash.Set(&String{Value: "b"}, &Integer{Value: 4})
+	if hash.Inspect() != "{a: 0, c: 2, d: 3, b: 4}" {
+		t.Errorf("Hash.Inspect() wrong after setting a deleted key. got=%q", hash.Inspect())
+	}
+}
+
+// Deleted pairs are only squeezed out now and then, lookups in between must skip them
+func TestHashDeleteMany(t *testing.T) {
+	hash := NewHash()
+	for i := int64(0); i < 1000; i++ {
+		hash.Set(&Integer{Value: i}, &Integer{Value: i * 10})
+	}
+
+	for i := int64(0); i < 1000; i += 3 {
+		if !hash.Delete(&Integer{Value: i}) {
+			t.Fatalf("Delete(%d) didn't find the key", i)
+		}
+	}
+
+	if hash.Len() != 666 {
+		t.Errorf("Len() wrong after deleting. expected=666, got=%d", hash.Len())
+	}
+
+	for i := int64(0); i < 1000; i++ {
+		value, ok := hash.Get(&Integer{Value: i})
+		if ok != (i%3 != 0) || ok && value.(*Integer).Value != i*10 {
+			t.Errorf("Get(%d) wrong after deleting. got=%v, %t", i, value, ok)
+		}
+	}
+
+	pairs := hash.Pairs()
+	if len(pairs) != 666 || pairs[0].Key.(*Integer).Value != 1 || pairs[665].Key.(*Integer).Value != 998 {
+		t.Errorf("Pairs() wrong after deleting. got %d pairs", len(pairs))
+	}
+
+	// deleting one of two keys whose hashes collide leaves the other
+	colliding := NewHash()
+	colliding.Set(&collidingKey{"x"}, &Integer{Value: 1})
+	colliding.Set(&collidingKey{"y"}, &Integer{Value: 2})
+	colliding.Set(&collidingKey{"z"}, &Integer{Value: 3})
+	colliding.Delete(&collidingKey{"x"})
+	if value, ok := colliding.Get(&collidingKey{"y"}); !ok || value.(*Integer).Value != 2 {
+		t.Errorf("Get(y) wrong after deleting x. got=%v", value)
+	}
+
+	if _, ok := colliding.Get(&collidingKey{"x"}); ok {
+		t.Errorf("Get(x) found a deleted key")
+	}
+}
+
+func TestSet(t *testing.T) {
+	set := func(values ...int64) *Set {
+		s := NewSet()
+		for _, value := range values {
+			s.Add(&Integer{Value: value})
+		}
+		return s
+	}
+
+	s := set(3, 1, 2, 1)
+	// a whole float is the same element as the equal integer
+	s.Add(&Float{Value: 3})
+
+	if s.Inspect() != "#{3, 1, 2}" || s.Len() != 3 {
+		t.Errorf("Set.Inspect() wrong. got=%q", s.Inspect())
+	}
+
+	if !s.Has(&Integer{Value: 2}) || s.Has(&Integer{Value: 4}) {
+		t.Errorf("Set.Has() wrong for %s", s.Inspect())
+	}
+
+	if !s.Remove(&Integer{Value: 1}) || s.Remove(&Integer{Value: 1}) || s.Inspect() != "#{3, 2}" {
+		t.Errorf("Set.Remove() wrong. got=%q", s.Inspect())
+	}
+
+	tests := []struct {
+		name     string
+		result   *Set
+		expected string
+	}{
+		{"union", set(1, 2).Union(set(2, 3)), "#{1, 2, 3}"},
+		{"intersection", set(1, 2, 3).Intersection(set(3, 2)), "#{2, 3}"},
+		{"difference", set(1, 2, 3).Difference(set(2)), "#{1, 3}"},
+		{"symmetric difference", set(1, 2, 3).SymmetricDifference(set(3, 4)), "#{1, 2, 4}"},
+		{"copy", set(1, 2).Copy(), "#{1, 2}"},
+	}
+
+	for _, tc := range tests {
+		if tc.result.Inspect() != tc.expected {
+			t.Errorf("%s wrong. expected=%q, got=%q", tc.name, tc.expected, tc.result.Inspect())
+		}
+	}
+
+	if !Equal(set(1, 2), set(2, 1)) || Equal(set(1, 2), set(1)) || Equal(set(1), &Array{Elements: []Object{&Integer{Value: 1}}}) {
+		t.Errorf("Set equality wrong")
+	}
+
+	if !set(1).IsSubset(set(1, 2)) || set(1, 3).IsSubset(set(1, 2)) {
+		t.Errorf("Set.IsSubset() wrong")
+	}
+}
+
+func TestBytes(t *testing.T) {
+	b := &Bytes{Value: []byte("a\"\\\n\x00\xff~ ")}
+	if expected := `b"a\"\\\n\x00\xff~ "`; b.Inspect() != expected {
+		t.Errorf("Bytes.Inspect() wrong. expected=%q, got=%q", expected, b.Inspect())
+	}
+
+	same := &Bytes{Value: []byte("a\"\\\n\x00\xff~ ")}
+	if b.HashKey() != same.HashKey() || !Equal(b, same) {
+		t.Errorf("equal bytes have different hash keys or aren't equal")
+	}
+
+	// bytes are never the same key as a string with the same contents
+	if (&Bytes{Value: []byte("a")}).HashKey() == (&String{Value: "a"}).HashKey() || Equal(&Bytes{Value: []byte("a")}, &String{Value: "a"}) {
+		t.Errorf("bytes and a string are the same key")
+	}
+}
+
+func TestTimeAndDuration(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("no zone data: %s", err)
+	}
+
+	utc := &Time{Value: time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)}
+	local := &Time{Value: utc.Value.In(paris)}
+
+	// the same instant in different zones
+	if !Equal(utc, local) || utc.HashKey() != local.HashKey() {
+		t.Errorf("times at the same instant aren't the same key")
+	}
+
+	if local.Inspect() != "2024-03-10T13:00:00+01:00" {
+		t.Errorf("Time.Inspect() wrong. got=%q", local.Inspect())
+	}
+
+	minute := &Duration{Value: time.Minute}
+	if !Equal(minute, &Duration{Value: 60 * time.Second}) || minute.Inspect() != "1m0s" {
+		t.Errorf("Duration wrong. got=%q", minute.Inspect())
+	}
+
+	if Equal(minute, &Integer{Value: int64(time.Minute)}) || minute.HashKey() == (&Integer{Value: int64(time.Minute)}).HashKey() {
+		t.Errorf("a duration is the same key as an integer")
+	}
+}
@@ -0,0 +1,131 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+)
+
+const SET_OBJ = "SET"
+
+/*
+A set of hashable values, written #{1, 2, 3}. Elements are stored the way hash keys are, so 1 and 1.0 are the
+same element, and they stay in the order they were first added, which is the order Inspect and for loops see.
+*/
+type Set struct {
+	elements *Hash // each element maps to itself
+}
+
+func NewSet() *Set {
+	return &Set{elements: NewHash()}
+}
+
+func (s *Set) Type() ObjectType { return SET_OBJ }
+func (s *Set) Inspect() string {
+	return inspect(s, nil)
+}
+
+func (s *Set) inspect(seen visiting) string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, element := range s.Elements() {
+		elements = append(elements, inspect(element, seen))
+	}
+
+	out.WriteString("#{")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+// Adds element, which must be hashable, see IsHashable. Adding an element that is already there changes nothing
+func (s *Set) Add(element Object) {
+	if !s.Has(element.(Hashable)) {
+		s.elements.Set(element, element)
+	}
+}
+
+// Removes element, the bool is false when it wasn't in the set
+func (s *Set) Remove(element Hashable) bool {
+	return s.elements.Delete(element)
+}
+
+func (s *Set) Has(element Hashable) bool {
+	_, ok := s.elements.Get(element)
+	return ok
+}
+
+func (s *Set) Len() int {
+	return s.elements.Len()
+}
+
+// The elements in the order they were added
+func (s *Set) Elements() []Object {
+	elements := make([]Object, s.Len())
+	for i, pair := range s.elements.Pairs() {
+		elements[i] = pair.Key
+	}
+
+	return elements
+}
+
+// Sets are equal when they have the same elements, in whatever order
+func (s *Set) Equals(other Object) bool {
+	otherSet, ok := other.(*Set)
+	if !ok || s.Len() != otherSet.Len() {
+		return false
+	}
+
+	return s.IsSubset(otherSet)
+}
+
+func (s *Set) IsSubset(other *Set) bool {
+	for _, element := range s.Elements() {
+		if !other.Has(element.(Hashable)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// The elements of either set, those of s first
+func (s *Set) Union(other *Set) *Set {
+	result := s.Copy()
+	for _, element := range other.Elements() {
+		result.Add(element)
+	}
+
+	return result
+}
+
+// The elements of s that are in other too
+func (s *Set) Intersection(other *Set) *Set {
+	return s.filter(func(element Hashable) bool { return other.Has(element) })
+}
+
+// The elements of s that aren't in other
+func (s *Set) Difference(other *Set) *Set {
+	return s.filter(func(element Hashable) bool { return !other.Has(element) })
+}
+
+// The elements that are in one of the sets but not both
+func (s *Set) SymmetricDifference(other *Set) *Set {
+	return s.Difference(other).Union(other.Difference(s))
+}
+
+func (s *Set) Copy() *Set {
+	return s.filter(func(Hashable) bool { return true })
+}
+
+func (s *Set) filter(keep func(Hashable) bool) *Set {
+	result := NewSet()
+	for _, element := range s.Elements() {
+		if keep(element.(Hashable)) {
+			result.Add(element)
+		}
+	}
+
+	return result
+}
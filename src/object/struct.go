@@ -0,0 +1,134 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+const (
+	STRUCT_OBJ   = "STRUCT"
+	INSTANCE_OBJ = "INSTANCE"
+)
+
+// Declared by `struct Point { x, y }`. Calling it with one argument per field creates an Instance
+type StructType struct {
+	Name   string
+	Fields []string
+
+	// added by impl blocks, each takes the instance as its first argument
+	Methods map[string]Object
+}
+
+func (s *StructType) Type() ObjectType { return STRUCT_OBJ }
+func (s *StructType) Inspect() string {
+	return fmt.Sprintf("struct %s { %s }", s.Name, strings.Join(s.Fields, ", "))
+}
+
+// Implements Callable, the constructor takes the field values in declaration order
+func (s *StructType) Call(args ...Object) Object {
+	if len(args) != len(s.Fields) {
+		return &Error{Message: fmt.Sprintf("wrong number of arguments.\nexpected=%d, got=%d", len(s.Fields), len(args))}
+	}
+
+	fields := make([]Object, len(args))
+	copy(fields, args)
+
+	return &Instance{Struct: s, Fields: fields}
+}
+
+// The position of field name in Fields, -1 when the struct has no such field
+func (s *StructType) FieldIndex(name string) int {
+	for i, field := range s.Fields {
+		if field == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// A value of a struct type. Fields holds one value per field of Struct, in the same order
+type Instance struct {
+	Struct *StructType
+	Fields []Object
+}
+
+func (i *Instance) Type() ObjectType { return INSTANCE_OBJ }
+func (i *Instance) Inspect() string {
+	return inspect(i, nil)
+}
+
+func (i *Instance) inspect(seen visiting) string {
+	var out bytes.Buffer
+
+	fields := make([]string, len(i.Fields))
+	for n, value := range i.Fields {
+		fields[n] = fmt.Sprintf("%s: %s", i.Struct.Fields[n], inspect(value, seen))
+	}
+
+	out.WriteString(i.Struct.Name)
+	out.WriteString("{")
+	out.WriteString(strings.Join(fields, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
+func (i *Instance) GetAttr(name string) (Object, bool) {
+	index := i.Struct.FieldIndex(name)
+	if index < 0 {
+		return &Error{Message: fmt.Sprintf("%s has no field %s", i.Struct.Name, name)}, true
+	}
+
+	return i.Fields[index], true
+}
+
+func (i *Instance) SetAttr(name string, value Object) error {
+	index := i.Struct.FieldIndex(name)
+	if index < 0 {
+		return fmt.Errorf("%s has no field %s", i.Struct.Name, name)
+	}
+
+	i.Fields[index] = value
+	return nil
+}
+
+// Instances are equal when they are of the same struct type and their fields are equal
+func (i *Instance) Equals(other Object) bool {
+	return i.equals(other, nil)
+}
+
+func (i *Instance) equals(other Object, seen comparing) bool {
+	otherInstance, ok := other.(*Instance)
+	if !ok || otherInstance.Struct != i.Struct {
+		return false
+	}
+
+	for n, value := range i.Fields {
+		if !equal(value, otherInstance.Fields[n], seen) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Only meaningful when every field is hashable, see IsHashable. Like any other key, an instance mustn't be
+// changed while it is stored in a hash
+func (i *Instance) HashKey() HashKey {
+	return hashKey(i, nil)
+}
+
+func (i *Instance) hashKey(seen visiting) HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(i.Struct.Name))
+
+	for _, value := range i.Fields {
+		key := hashKey(value.(Hashable), seen)
+		fmt.Fprintf(h, "\x00%s:%d", key.Type, key.Value)
+	}
+
+	return HashKey{Type: i.Type(), Value: h.Sum64()}
+}
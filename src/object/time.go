@@ -0,0 +1,38 @@
+package object
+
+import "time"
+
+const (
+	TIME_OBJ     = "TIME"
+	DURATION_OBJ = "DURATION"
+)
+
+// An instant in time along with the zone it is shown in. Times at the same instant are equal whatever their zones
+type Time struct {
+	Value time.Time
+}
+
+func (t *Time) Type() ObjectType { return TIME_OBJ }
+func (t *Time) Inspect() string  { return t.Value.Format(time.RFC3339Nano) }
+func (t *Time) HashKey() HashKey {
+	return HashKey{Type: t.Type(), Value: uint64(t.Value.Unix())*uint64(time.Second) + uint64(t.Value.Nanosecond())}
+}
+func (t *Time) Equals(other Object) bool {
+	otherTime, ok := other.(*Time)
+	return ok && t.Value.Equal(otherTime.Value)
+}
+
+// The time between two instants, shown the way Go shows durations, e.g. 1h30m0s
+type Duration struct {
+	Value time.Duration
+}
+
+func (d *Duration) Type() ObjectType { return DURATION_OBJ }
+func (d *Duration) Inspect() string  { return d.Value.String() }
+func (d *Duration) HashKey() HashKey {
+	return HashKey{Type: d.Type(), Value: uint64(d.Value)}
+}
+func (d *Duration) Equals(other Object) bool {
+	otherDuration, ok := other.(*Duration)
+	return ok && d.Value == otherDuration.Value
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"monkey/ast"
 	"monkey/lexer"
+	"monkey/resolver"
 	"monkey/token"
 	"strconv"
 )
@@ -14,27 +15,44 @@ const (
 	// larger the value, the higher the precedence it has
 	_ int = iota
 	LOWEST
+	ASSIGN      // x = y
 	EQUALS      // ==
-	LESSGREATER // > OR <
+	LESSGREATER // > OR <, and x in xs
+	BIT_OR      // |
+	BIT_XOR     // ^
+	BIT_AND     // &
+	SHIFT       // << or >>
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X or !X
+	POWER       // x ** y, above PREFIX so -2 ** 2 is -(2 ** 2)
 	CALL        // myFunc(X)
-	INDEX       // array[index]
+	INDEX       // array[index] or object.member
 )
 
 var precedences = map[token.TokenType]int{
+	token.ASSIGN:   ASSIGN,
 	token.EQ:       EQUALS,
 	token.NOT_EQ:   EQUALS,
 	token.LT:       LESSGREATER,
 	token.GT:       LESSGREATER,
+	token.IN:       LESSGREATER,
 	token.PLUS:     SUM,
 	token.MINUS:    SUM,
 	token.SLASH:    PRODUCT,
 	token.ASTERISK: PRODUCT,
 	token.MOD:      PRODUCT,
+	token.POWER:    POWER,
+
+	token.BIT_OR:      BIT_OR,
+	token.BIT_XOR:     BIT_XOR,
+	token.BIT_AND:     BIT_AND,
+	token.SHIFT_LEFT:  SHIFT,
+	token.SHIFT_RIGHT: SHIFT,
+
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
+	token.DOT:      INDEX,
 }
 
 type (
@@ -48,6 +66,9 @@ type Parser struct {
 	curToken  token.Token
 	peekToken token.Token
 	errors    []string
+	errorsAt  []token.Token // the token each error was found at, so a placeholder can report where in it they are
+	depth     int           // how many blocks deep curToken is
+	generator bool          // whether curToken is in the body of a fn*
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
@@ -67,14 +88,19 @@ func New(l *lexer.Lexer) *Parser {
 	parser.registerPrefix(token.FLOAT, parser.parseFloatLiteral)
 	parser.registerPrefix(token.BANG, parser.parsePrefixExpression)
 	parser.registerPrefix(token.MINUS, parser.parsePrefixExpression)
+	parser.registerPrefix(token.BIT_NOT, parser.parsePrefixExpression)
 	parser.registerPrefix(token.TRUE, parser.parseBoolean)
 	parser.registerPrefix(token.FALSE, parser.parseBoolean)
 	parser.registerPrefix(token.LPAREN, parser.parseGroupedExpression)
 	parser.registerPrefix(token.IF, parser.parseIfExpression)
 	parser.registerPrefix(token.FUNCTION, parser.parseFunctionLiteral)
 	parser.registerPrefix(token.STRING, parser.parseStringLiteral)
+	parser.registerPrefix(token.TEMPLATE, parser.parseInterpolatedString)
+	parser.registerPrefix(token.BYTES, parser.parseBytesLiteral)
 	parser.registerPrefix(token.LBRACKET, parser.parseArrayLiteral)
 	parser.registerPrefix(token.LBRACE, parser.parseHashLiteral)
+	parser.registerPrefix(token.SET_OPEN, parser.parseSetLiteral)
+	parser.registerPrefix(token.MATCH, parser.parseMatchExpression)
 
 	// initialise the infixParseFns map, and register all infixes to maps
 	parser.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -83,12 +109,21 @@ func New(l *lexer.Lexer) *Parser {
 	parser.registerInfix(token.SLASH, parser.parseInfixExpression)
 	parser.registerInfix(token.ASTERISK, parser.parseInfixExpression)
 	parser.registerInfix(token.MOD, parser.parseInfixExpression)
+	parser.registerInfix(token.POWER, parser.parseInfixExpression)
+	parser.registerInfix(token.BIT_AND, parser.parseInfixExpression)
+	parser.registerInfix(token.BIT_OR, parser.parseInfixExpression)
+	parser.registerInfix(token.BIT_XOR, parser.parseInfixExpression)
+	parser.registerInfix(token.SHIFT_LEFT, parser.parseInfixExpression)
+	parser.registerInfix(token.SHIFT_RIGHT, parser.parseInfixExpression)
 	parser.registerInfix(token.EQ, parser.parseInfixExpression)
 	parser.registerInfix(token.NOT_EQ, parser.parseInfixExpression)
 	parser.registerInfix(token.LT, parser.parseInfixExpression)
 	parser.registerInfix(token.GT, parser.parseInfixExpression)
+	parser.registerInfix(token.IN, parser.parseInfixExpression)
+	parser.registerInfix(token.ASSIGN, parser.parseAssignExpression)
 	parser.registerInfix(token.LPAREN, parser.parseCallExpression)
 	parser.registerInfix(token.LBRACKET, parser.parseIndexExpression)
+	parser.registerInfix(token.DOT, parser.parseMemberExpression)
 
 	return parser
 }
@@ -115,6 +150,20 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
+	case token.IMPL:
+		return p.parseImplStatement()
+	case token.ENUM:
+		return p.parseEnumStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
+	case token.YIELD:
+		return p.parseYieldStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -146,6 +195,302 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return statement
 }
 
+func (p *Parser) parseForStatement() ast.Statement {
+	statement := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	statement.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	statement.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	statement.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseStructStatement() ast.Statement {
+	statement := &ast.StructStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	statement.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		field := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if seen[field.Value] {
+			msg := fmt.Sprintf("duplicate field %s in struct %s", field.Value, statement.Name.Value)
+			p.addError(p.curToken, msg)
+			return nil
+		}
+
+		seen[field.Value] = true
+		statement.Fields = append(statement.Fields, field)
+
+		// fields are comma separated, a trailing comma before the } is fine
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseImplStatement() ast.Statement {
+	statement := &ast.ImplStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	statement.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.FUNCTION) {
+			return nil
+		}
+
+		method := p.parseMethod()
+		if method == nil {
+			return nil
+		}
+
+		if seen[method.Name.Value] {
+			msg := fmt.Sprintf("duplicate method %s in impl %s", method.Name.Value, statement.Name.Value)
+			p.addError(p.curToken, msg)
+			return nil
+		}
+
+		seen[method.Name.Value] = true
+		statement.Methods = append(statement.Methods, method)
+
+		if p.peekTokenIs(token.SEMICOLON) {
+			p.nextToken()
+		}
+	}
+
+	p.nextToken()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+// fn name(params) { body }, with the current token on fn
+func (p *Parser) parseMethod() *ast.Method {
+	literal := &ast.FunctionLiteral{Token: p.curToken}
+
+	if p.peekTokenIs(token.ASTERISK) {
+		p.nextToken()
+		literal.Generator = true
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	method := &ast.Method{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}, Function: literal}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	literal.Parameters = p.parseFunctionParameters()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	p.parseFunctionBody(literal)
+
+	return method
+}
+
+func (p *Parser) parseEnumStatement() ast.Statement {
+	statement := &ast.EnumStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	statement.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		variant := &ast.EnumVariant{Name: &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}}
+		if seen[variant.Name.Value] {
+			msg := fmt.Sprintf("duplicate variant %s in enum %s", variant.Name.Value, statement.Name.Value)
+			p.addError(p.curToken, msg)
+			return nil
+		}
+
+		seen[variant.Name.Value] = true
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+
+			variant.Fields = p.parseFunctionParameters()
+			if variant.Fields == nil {
+				return nil
+			}
+		}
+
+		statement.Variants = append(statement.Variants, variant)
+
+		// variants are comma separated, a trailing comma before the } is fine
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseYieldStatement() ast.Statement {
+	statement := &ast.YieldStatement{Token: p.curToken}
+
+	if !p.generator {
+		p.addError(p.curToken, "yield outside of a generator function")
+		return nil
+	}
+
+	p.nextToken()
+	statement.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseImportStatement() ast.Statement {
+	statement := &ast.ImportStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+
+	statement.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.AS) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	statement.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+func (p *Parser) parseExportStatement() ast.Statement {
+	statement := &ast.ExportStatement{Token: p.curToken}
+
+	// only the top level of a module can be seen from outside it
+	if p.depth > 0 {
+		p.addError(p.curToken, "export is only allowed at the top level")
+		return nil
+	}
+
+	p.nextToken()
+
+	switch p.curToken.Type {
+	case token.LET:
+		// parseLetStatement returns a typed nil on failure, which mustn't end up inside the export
+		let := p.parseLetStatement()
+		if let == nil {
+			return nil
+		}
+
+		statement.Statement = let
+
+	case token.STRUCT:
+		statement.Statement = p.parseStructStatement()
+
+	case token.ENUM:
+		statement.Statement = p.parseEnumStatement()
+
+	default:
+		msg := fmt.Sprintf("expected let, struct or enum after export, got %s instead", p.curToken.Type)
+		p.addError(p.curToken, msg)
+		return nil
+	}
+
+	if statement.Statement == nil {
+		return nil
+	}
+
+	return statement
+}
+
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	var statement = &ast.LetStatement{Token: p.curToken}
 
@@ -267,6 +612,11 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	precedence := p.curPrecedence()
 	// fmt.Printf("precedence is %+v\n", precedence)
 
+	// ** is right associative, 2 ** 3 ** 2 is 2 ** (3 ** 2)
+	if expression.Token.Type == token.POWER {
+		precedence--
+	}
+
 	// e.g. 5 + 5, curToken is 5
 	p.nextToken()
 
@@ -276,6 +626,27 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// Assignment is right associative, a = b = 1 assigns 1 to b and then to a
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{Token: p.curToken, Target: target}
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.MemberExpression:
+	default:
+		if target != nil {
+			msg := fmt.Sprintf("cannot assign to %s", target.String())
+			p.addError(p.curToken, msg)
+		}
+		return nil
+	}
+
+	p.nextToken()
+
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -290,7 +661,6 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken}
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
@@ -303,7 +673,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashLiteralPair{Key: key, Value: value})
 
 		// We expect a comma next for n keys, to get the cond. working, we do not expect a '}' and a ',' at the same time so { key: value , } -> the , }
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
@@ -319,25 +689,188 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	bracket := p.curToken
+	p.nextToken()
+
+	if p.curTokenIs(token.COLON) {
+		return p.parseSliceExpression(bracket, left, nil)
+	}
 
-	// log.Printf("BEFORE expression: %+v", *exp)
+	index := p.parseExpression(LOWEST)
 
-	p.nextToken()
-	exp.Index = p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		return p.parseSliceExpression(bracket, left, index)
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return &ast.IndexExpression{Token: bracket, Left: left, Index: index}
+}
 
-	// log.Printf("expression.Index: %+v", exp.Index)
-	// log.Printf("AFTER expression: %+v", *exp)
+// Called on the colon of left[start:end], start is nil when it was left out
+func (p *Parser) parseSliceExpression(bracket token.Token, left, start ast.Expression) ast.Expression {
+	slice := &ast.SliceExpression{Token: bracket, Left: left, Start: start}
+
+	if !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		slice.End = p.parseExpression(LOWEST)
+	}
 
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
 
-	// log.Printf("RETURN expression: %+v", *exp)
+	return slice
+}
+
+func (p *Parser) parseMemberExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Object: object}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+
+	exp.Property = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
 	return exp
 }
 
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		pattern := p.parsePattern()
+		if pattern == nil {
+			return nil
+		}
+
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+
+		arm := &ast.MatchArm{Token: p.curToken, Pattern: pattern}
+		p.nextToken()
+
+		if p.curTokenIs(token.LBRACE) {
+			arm.Body = p.parseBlockStatement()
+		} else {
+			statement := &ast.ExpressionStatement{Token: p.curToken, Expression: p.parseExpression(LOWEST)}
+			arm.Body = &ast.BlockStatement{Token: arm.Token, Statements: []ast.Statement{statement}}
+		}
+
+		expression.Arms = append(expression.Arms, arm)
+
+		// arms are comma separated, a trailing comma before the } is fine
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	p.nextToken()
+
+	return expression
+}
+
+// Parses the pattern starting at the current token, see the Pattern types in ast
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		if p.curToken.Literal == "_" {
+			return &ast.WildcardPattern{Token: p.curToken}
+		}
+
+		name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.peekTokenIs(token.DOT) {
+			return &ast.BindingPattern{Name: name}
+		}
+
+		p.nextToken()
+		pattern := &ast.VariantPattern{Token: p.curToken, Enum: name}
+
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		pattern.Variant = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+
+			pattern.Arguments = p.parsePatternList()
+			if pattern.Arguments == nil {
+				return nil
+			}
+		}
+
+		return pattern
+
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE:
+		return &ast.LiteralPattern{Value: p.prefixParseFns[p.curToken.Type]()}
+
+	case token.MINUS:
+		if p.peekTokenIs(token.INT) || p.peekTokenIs(token.FLOAT) {
+			return &ast.LiteralPattern{Value: p.parsePrefixExpression()}
+		}
+	}
+
+	msg := fmt.Sprintf("unexpected %s in pattern", p.curToken.Type)
+	p.addError(p.curToken, msg)
+	return nil
+}
+
+// The patterns inside the parentheses of a variant pattern, with the current token on the (
+func (p *Parser) parsePatternList() []ast.Pattern {
+	patterns := []ast.Pattern{}
+
+	if p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		return patterns
+	}
+
+	for {
+		p.nextToken()
+
+		pattern := p.parsePattern()
+		if pattern == nil {
+			return nil
+		}
+
+		patterns = append(patterns, pattern)
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return patterns
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
@@ -348,10 +881,81 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	return array
 }
 
+func (p *Parser) parseBytesLiteral() ast.Expression {
+	value, err := lexer.DecodeBytes(p.curToken.Literal)
+	if err != nil {
+		p.addError(p.curToken, fmt.Sprintf("%s at line %d, column %d", err, p.curToken.Line, p.curToken.Column))
+		return nil
+	}
+
+	return &ast.BytesLiteral{Token: p.curToken, Value: value}
+}
+
+func (p *Parser) parseSetLiteral() ast.Expression {
+	return &ast.SetLiteral{Token: p.curToken, Elements: p.parseExpressionList(token.RBRACE)}
+}
+
 func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+/*
+The expressions of the placeholders are parsed by a parser of their own, over a lexer that starts where they do
+in the source so their tokens carry the right positions. Its errors are reported at the token they were found at.
+*/
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	interpolated := &ast.InterpolatedString{Token: p.curToken}
+
+	parts, ok := lexer.SplitTemplate(p.curToken)
+	if !ok {
+		p.addError(p.curToken, fmt.Sprintf("unterminated placeholder in string at line %d, column %d",
+			p.curToken.Line, p.curToken.Column))
+		return nil
+	}
+
+	for _, part := range parts {
+		if !part.Placeholder {
+			if part.Text != "" {
+				interpolated.Parts = append(interpolated.Parts, &ast.StringLiteral{Token: p.curToken, Value: part.Text})
+			}
+			continue
+		}
+
+		expression, placeholder := parsePlaceholder(part)
+		for i, err := range placeholder.errors {
+			at := placeholder.errorsAt[i]
+			p.addError(at, fmt.Sprintf("%s in placeholder at line %d, column %d", err, at.Line, at.Column))
+		}
+
+		if len(placeholder.errors) > 0 {
+			return nil
+		}
+
+		interpolated.Parts = append(interpolated.Parts, expression)
+	}
+
+	return interpolated
+}
+
+// The closing } is lexed along with the placeholder, so an error at the end of it names and points at the }
+func parsePlaceholder(part lexer.TemplatePart) (ast.Expression, *Parser) {
+	placeholder := New(lexer.NewAt(part.Text+"}", part.Line, part.Column))
+
+	if placeholder.curTokenIs(token.RBRACE) {
+		placeholder.addError(placeholder.curToken, "expected an expression")
+		return nil, placeholder
+	}
+
+	expression := placeholder.parseExpression(LOWEST)
+
+	if len(placeholder.errors) == 0 && !placeholder.peekTokenIs(token.RBRACE) {
+		placeholder.addError(placeholder.peekToken,
+			fmt.Sprintf("expected } after the expression, got %s instead", placeholder.peekToken.Type))
+	}
+
+	return expression, placeholder
+}
+
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
 	exp.Arguments = p.parseExpressionList(token.RPAREN)
@@ -396,6 +1000,11 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	literal := &ast.FunctionLiteral{Token: p.curToken}
 
+	if p.peekTokenIs(token.ASTERISK) {
+		p.nextToken()
+		literal.Generator = true
+	}
+
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
@@ -406,11 +1015,23 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	literal.Body = p.parseBlockStatement()
+	p.parseFunctionBody(literal)
 
 	return literal
 }
 
+// Parses the body of literal, curToken being its {. yield is only allowed directly in the body of a fn*,
+// not in the functions nested inside it
+func (p *Parser) parseFunctionBody(literal *ast.FunctionLiteral) {
+	outer := p.generator
+	p.generator = literal.Generator
+
+	literal.Body = p.parseBlockStatement()
+	literal.FreeVariables = resolver.FreeVariables(literal)
+
+	p.generator = outer
+}
+
 func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	identifiers := make([]*ast.Identifier, 0)
 
@@ -514,6 +1135,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = make([]ast.Statement, 0)
 
+	p.depth++
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
@@ -524,6 +1146,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		p.nextToken()
 	}
 
+	p.depth--
 	return block
 }
 
@@ -545,7 +1168,7 @@ func (p *Parser) parseBoolean() ast.Expression {
 	value, err := strconv.ParseBool(p.curToken.Literal)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as boolean", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.curToken, msg)
 		return nil
 	}
 
@@ -559,7 +1182,7 @@ func (p *Parser) parseFloatLiteral() ast.Expression {
 	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as float", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.curToken, msg)
 		return nil
 	}
 
@@ -573,7 +1196,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.curToken, msg)
 		return nil
 	}
 
@@ -596,7 +1219,7 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 // ----------Helpers-----------
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(p.curToken, msg)
 }
 
 func (p *Parser) peekPrecedence() int {
@@ -610,7 +1233,7 @@ func (p *Parser) peekPrecedence() int {
 func (p *Parser) peekError(t token.TokenType) {
 	var msg = fmt.Sprintf("expected next token to be %s, got %s instead", t, p.peekToken.Type)
 
-	p.errors = append(p.errors, msg)
+	p.addError(p.peekToken, msg)
 }
 
 func (p *Parser) peekTokenIs(t token.TokenType) bool {
@@ -646,6 +1269,11 @@ func (p *Parser) nextToken() {
 	p.peekToken = p.lexer.NextToken()
 }
 
+func (p *Parser) addError(at token.Token, msg string) {
+	p.errors = append(p.errors, msg)
+	p.errorsAt = append(p.errorsAt, at)
+}
+
 func (p *Parser) Errors() []string {
 	return p.errors
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"monkey/ast"
 	"monkey/lexer"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -52,6 +53,381 @@ type ExpectedInfixTest struct {
 	rightValue interface{}
 }
 
+func TestAssignExpressionParsing(t *testing.T) {
+	tests := []ExpectedPrecedenceTest{
+		{"x = 5", "(x = 5)"},
+		{"x = y = 5;", "(x = (y = 5))"},
+		{"x = x + 1 * 2", "(x = (x + (1 * 2)))"},
+		{"x = a == b", "(x = (a == b))"},
+		{"f(x = 1)", "f((x = 1))"},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+}
+
+func TestMemberExpressionParsing(t *testing.T) {
+	tests := []ExpectedPrecedenceTest{
+		{"a.b", "(a.b)"},
+		{"a.b.c", "((a.b).c)"},
+		{"a.b(1)", "(a.b)(1)"},
+		{"a.b[0].c", "(((a.b)[0]).c)"},
+		{"-a.b", "(-(a.b))"},
+		{"a.b + c.d * 2", "((a.b) + ((c.d) * 2))"},
+		{"a.b = c.d = 1", "((a.b) = ((c.d) = 1))"},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, program.String())
+		}
+	}
+}
+
+func TestForStatementParsing(t *testing.T) {
+	l := lexer.New("for (x in xs) { total = total + x; }")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.ForStatement. got=%T", program.Statements[0])
+	}
+
+	if !testIdentifier(t, statement.Variable, "x") || !testIdentifier(t, statement.Iterable, "xs") {
+		return
+	}
+
+	if len(statement.Body.Statements) != 1 {
+		t.Errorf("body has wrong number of statements. got=%d", len(statement.Body.Statements))
+	}
+
+	for _, input := range []string{"for x in xs { x }", "for (1 in xs) { x }", "for (x of xs) { x }"} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestStructStatementParsing(t *testing.T) {
+	tests := []struct {
+		input  string
+		name   string
+		fields []string
+	}{
+		{"struct Point { x, y }", "Point", []string{"x", "y"}},
+		{"struct Point { x, y, };", "Point", []string{"x", "y"}},
+		{"struct Unit {}", "Unit", nil},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.StructStatement)
+		if !ok {
+			t.Fatalf("statement is not *ast.StructStatement. got=%T", program.Statements[0])
+		}
+
+		if statement.Name.Value != tc.name || len(statement.Fields) != len(tc.fields) {
+			t.Errorf("wrong struct. got=%s", statement.String())
+			continue
+		}
+
+		for i, field := range tc.fields {
+			testIdentifier(t, statement.Fields[i], field)
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, x }", "duplicate field x in struct Point"},
+		{"struct { x }", "expected next token to be IDENT, got { instead"},
+		{"struct Point { x y }", "expected next token to be ,, got IDENT instead"},
+	}
+
+	for _, tc := range errors {
+		p := New(lexer.New(tc.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tc.expected {
+			t.Errorf("%s: expected error %q. got=%v", tc.input, tc.expected, p.Errors())
+		}
+	}
+}
+
+func TestImplStatementParsing(t *testing.T) {
+	input := `impl Point { fn dist(self, other) { self.x - other.x }; fn zero(self) { 0 } }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	statement, ok := program.Statements[0].(*ast.ImplStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.ImplStatement. got=%T", program.Statements[0])
+	}
+
+	if statement.Name.Value != "Point" || len(statement.Methods) != 2 {
+		t.Fatalf("wrong impl. got=%s", statement.String())
+	}
+
+	dist := statement.Methods[0]
+	if dist.Name.Value != "dist" || len(dist.Function.Parameters) != 2 {
+		t.Errorf("wrong method. got=%s with %d parameters", dist.Name.Value, len(dist.Function.Parameters))
+	}
+
+	if dist.Function.FreeVariables == nil {
+		t.Errorf("method wasn't resolved")
+	}
+
+	expected := "impl Point { fn dist(self, other) ((self.x) - (other.x)) fn zero(self) 0 }"
+	if statement.String() != expected {
+		t.Errorf("wrong String(). expected=%q, got=%q", expected, statement.String())
+	}
+
+	for _, input := range []string{
+		"impl Point { dist(self) { 1 } }",
+		"impl Point { fn (self) { 1 } }",
+		"impl Point { fn a(self) { 1 } fn a(self) { 2 } }",
+	} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser errors for %q", input)
+		}
+	}
+}
+
+func TestEnumStatementParsing(t *testing.T) {
+	input := `enum Result { Ok(value), Err(message, code), Pending, }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has wrong number of statements. got=%d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.EnumStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.EnumStatement. got=%T", program.Statements[0])
+	}
+
+	testIdentifier(t, statement.Name, "Result")
+
+	expected := []struct {
+		name   string
+		fields []string
+	}{
+		{"Ok", []string{"value"}},
+		{"Err", []string{"message", "code"}},
+		{"Pending", nil},
+	}
+
+	if len(statement.Variants) != len(expected) {
+		t.Fatalf("wrong number of variants. got=%s", statement.String())
+	}
+
+	for i, variant := range expected {
+		testIdentifier(t, statement.Variants[i].Name, variant.name)
+
+		if len(statement.Variants[i].Fields) != len(variant.fields) {
+			t.Errorf("wrong fields for %s. got=%s", variant.name, statement.Variants[i].String())
+			continue
+		}
+
+		for n, field := range variant.fields {
+			testIdentifier(t, statement.Variants[i].Fields[n], field)
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"enum R { A, A }", "duplicate variant A in enum R"},
+		{"enum { A }", "expected next token to be IDENT, got { instead"},
+		{"enum R { A B }", "expected next token to be ,, got IDENT instead"},
+	}
+
+	for _, tc := range errors {
+		p := New(lexer.New(tc.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tc.expected {
+			t.Errorf("%s: expected error %q. got=%v", tc.input, tc.expected, p.Errors())
+		}
+	}
+}
+
+func TestMatchExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { 1 => a, -2.5 => { b }, true => c, _ => 0 }", "match (x) { 1 => a, (-2.5) => b, true => c, _ => 0 }"},
+		{"match (r) { R.A(v, _) => v, R.B => 0, R.C() => 1, }", "match (r) { R.A(v, _) => v, R.B => 0, R.C() => 1 }"},
+		{"match (r) { R.A(Option.Some(v)) => v, other => other }", "match (r) { R.A(Option.Some(v)) => v, other => other }"},
+		{"let y = match (f(x)) { n => n + 1 } * 2", "let y = (match (f(x)) { n => (n + 1) } * 2);"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if got := program.String(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+
+	p := New(lexer.New("match (r) { R.A(v) => v }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	match, ok := statement.Expression.(*ast.MatchExpression)
+	if !ok {
+		t.Fatalf("expression is not *ast.MatchExpression. got=%T", statement.Expression)
+	}
+
+	testIdentifier(t, match.Subject, "r")
+
+	if len(match.Arms) != 1 {
+		t.Fatalf("wrong number of arms. got=%d", len(match.Arms))
+	}
+
+	pattern, ok := match.Arms[0].Pattern.(*ast.VariantPattern)
+	if !ok {
+		t.Fatalf("pattern is not *ast.VariantPattern. got=%T", match.Arms[0].Pattern)
+	}
+
+	testIdentifier(t, pattern.Enum, "R")
+	testIdentifier(t, pattern.Variant, "A")
+
+	if binding, ok := pattern.Arguments[0].(*ast.BindingPattern); !ok || binding.Name.Value != "v" {
+		t.Errorf("argument is not a binding for v. got=%s", pattern.Arguments[0].String())
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { + => 1 }", "unexpected + in pattern"},
+		{"match x { _ => 1 }", "expected next token to be (, got IDENT instead"},
+		{"match (x) { R. => 1 }", "expected next token to be IDENT, got => instead"},
+	}
+
+	for _, tc := range errors {
+		p := New(lexer.New(tc.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tc.expected {
+			t.Errorf("%s: expected error %q. got=%v", tc.input, tc.expected, p.Errors())
+		}
+	}
+}
+
+func TestImportAndExportParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/strings" as str;`, `import "lib/strings" as str;`},
+		{`import "a" as a import "b" as b`, `import "a" as a;import "b" as b;`},
+		{"export let answer = 42;", "export let answer = 42;"},
+		{"export struct Point { x, y }", "export struct Point { x, y }"},
+		{"export enum R { A, B }", "export enum R { A, B }"},
+	}
+
+	for _, tc := range tests {
+		p := New(lexer.New(tc.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if got := program.String(); got != tc.expected {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+
+	p := New(lexer.New(`import "lib/strings" as str;`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	statement, ok := program.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.ImportStatement. got=%T", program.Statements[0])
+	}
+
+	if statement.Path.Value != "lib/strings" {
+		t.Errorf("wrong path. got=%q", statement.Path.Value)
+	}
+
+	testIdentifier(t, statement.Name, "str")
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"import lib as l;", "expected next token to be STRING, got IDENT instead"},
+		{`import "lib";`, "expected next token to be AS, got ; instead"},
+		{"export 1;", "expected let, struct or enum after export, got INT instead"},
+		{"fn() { export let x = 1; }", "export is only allowed at the top level"},
+		{"if (true) { export let x = 1; }", "export is only allowed at the top level"},
+	}
+
+	for _, tc := range errors {
+		p := New(lexer.New(tc.input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != tc.expected {
+			t.Errorf("%s: expected error %q. got=%v", tc.input, tc.expected, p.Errors())
+		}
+	}
+}
+
+func TestAssignExpressionInvalidTarget(t *testing.T) {
+	l := lexer.New("1 = 2")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 || errors[0] != "cannot assign to 1" {
+		t.Errorf("expected invalid assignment target error. got=%v", errors)
+	}
+}
+
 func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 	input := `{"one": 0 + 1, "two": 10 - 8, "three": 15 / 5}`
 
@@ -82,20 +458,20 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 			continue
 		}
 
 		testFunc, ok := tests[literal.String()]
 		if !ok {
-			t.Errorf("No test function for key %q found, expected key=%q", literal.String(), key)
+			t.Errorf("No test function for key %q found, expected key=%q", literal.String(), pair.Key)
 			continue
 		}
 
-		testFunc(value)
+		testFunc(pair.Value)
 	}
 }
 
@@ -136,20 +512,27 @@ func TestParsingHashLiteralStringKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length.\nexpected=3, got=%d", len(hash.Pairs))
 	}
 
-	expected := map[string]int64{
-		"one":   1,
-		"two":   2,
-		"three": 3,
+	expected := []struct {
+		key   string
+		value int64
+	}{
+		{"one", 1},
+		{"two", 2},
+		{"three", 3},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	// the pairs stay in source order
+	for i, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral, got=%T", key)
+			t.Errorf("key is not ast.StringLiteral, got=%T", pair.Key)
+			continue
 		}
 
-		expectedValue := expected[literal.String()]
-		testIntegerLiteral(t, value, expectedValue)
+		if literal.String() != expected[i].key {
+			t.Errorf("pair %d has the wrong key.\nexpected=%q, got=%q", i, expected[i].key, literal.String())
+		}
+		testIntegerLiteral(t, pair.Value, expected[i].value)
 	}
 }
 
@@ -177,6 +560,78 @@ func TestParsingIndexExpression(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"s[1:2]", "(s[1:2])"},
+		{"s[:n - 1]", "(s[:(n - 1)])"},
+		{"s[1:]", "(s[1:])"},
+		{"s[:]", "(s[:])"},
+		{"s[1:][0]", "((s[1:])[0])"},
+		{`{"a": s[1:2]}`, `{a : (s[1:2])}`},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if got := program.String(); got != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, got)
+		}
+	}
+
+	l := lexer.New("s[1:2:3]")
+	p := New(l)
+	p.ParseProgram()
+
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected an error for s[1:2:3]")
+	}
+}
+
+func TestParsingSetLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"#{1, 2 * 2, x}", "#{1, (2 * 2), x}"},
+		{"#{}", "#{}"},
+		{"#{#{1}}", "#{#{1}}"},
+		{"#{1} | #{2}", "(#{1} | #{2})"},
+		{"for (x in #{1, 2}) { x }", "for (x in #{1, 2}) x"},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if got := program.String(); got != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, got)
+		}
+	}
+
+	l := lexer.New("#{1, 2}")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	set, ok := statement.Expression.(*ast.SetLiteral)
+	if !ok {
+		t.Fatalf("expression is not ast.SetLiteral. got=%T", statement.Expression)
+	}
+
+	if len(set.Elements) != 2 {
+		t.Errorf("set.Elements has wrong length. got=%d", len(set.Elements))
+	}
+}
+
 func TestParsingArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 
@@ -223,6 +678,101 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestBytesLiteralParsing(t *testing.T) {
+	input := `b"\x00a\"\n${x}"`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := statement.Expression.(*ast.BytesLiteral)
+	if !ok {
+		t.Fatalf("expression not *ast.BytesLiteral. got=%T", statement.Expression)
+	}
+
+	if expected := []byte("\x00a\"\n${x}"); !reflect.DeepEqual(literal.Value, expected) {
+		t.Errorf("literal.Value not %q. got=%q", expected, literal.Value)
+	}
+
+	// String gives back the source
+	if program.String() != input {
+		t.Errorf("expected=%q, got=%q", input, program.String())
+	}
+
+	errorTests := []struct {
+		input    string
+		expected []string
+	}{
+		{`b"\q"`, []string{`invalid escape "\\q" in bytes literal at line 1, column 1`}},
+		{"let x =\n  b\"\\x4\";", []string{`invalid escape "\\x4" in bytes literal at line 2, column 3`}},
+		{`b"\xzz"`, []string{`invalid escape "\\xzz" in bytes literal at line 1, column 1`}},
+	}
+
+	for _, tc := range errorTests {
+		p := New(lexer.New(tc.input))
+		p.ParseProgram()
+
+		if got := p.Errors(); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
+func TestInterpolatedStringParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"hello ${user.name}, you have ${count + 1} items"`, `"hello ${(user.name)}, you have ${(count + 1)} items"`},
+		{`"${a}${b}"`, `"${a}${b}"`},
+		{`"${"${x}"}"`, `"${"${x}"}"`},
+		{`"${xs[1:]}"`, `"${(xs[1:])}"`},
+	}
+
+	for _, tc := range tests {
+		l := lexer.New(tc.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		statement := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := statement.Expression.(*ast.InterpolatedString); !ok {
+			t.Fatalf("expression not *ast.InterpolatedString. got=%T", statement.Expression)
+		}
+
+		if got := program.String(); got != tc.expected {
+			t.Errorf("expected=%q, got=%q", tc.expected, got)
+		}
+	}
+
+	// errors point into the placeholder they are in
+	errorTests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let s = \"a\n${x +}\";", []string{"no prefix parse function for } found in placeholder at line 2, column 6"}},
+		{`"a ${}"`, []string{"expected an expression in placeholder at line 1, column 6"}},
+		{`"a ${x y}"`, []string{"expected } after the expression, got IDENT instead in placeholder at line 1, column 8"}},
+		{`"ab ${1 + (2 *)}"`, []string{
+			"no prefix parse function for ) found in placeholder at line 1, column 15",
+			"expected next token to be ), got } instead in placeholder at line 1, column 16",
+		}},
+		{"\"${f(\n  1 2)}\"", []string{"expected next token to be ), got INT instead in placeholder at line 2, column 5"}},
+		{`"a ${x"`, []string{"unterminated placeholder in string at line 1, column 1"}},
+	}
+
+	for _, tc := range errorTests {
+		p := New(lexer.New(tc.input))
+		p.ParseProgram()
+
+		if got := p.Errors(); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: expected=%q, got=%q", tc.input, tc.expected, got)
+		}
+	}
+}
+
 func TestCallParameterParsing(t *testing.T) {
 	tests := []ExpectedCallArgumentTest{
 		{
@@ -379,6 +929,63 @@ func TestFunctionLiteralParsing(t *testing.T) {
 
 }
 
+func TestGeneratorParsing(t *testing.T) {
+	p := New(lexer.New("fn*(n) { yield n; yield n + 1 }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	function, ok := statement.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("expression is not *ast.FunctionLiteral. got=%T", statement.Expression)
+	}
+
+	if !function.Generator {
+		t.Errorf("function is not a generator")
+	}
+
+	if len(function.Body.Statements) != 2 {
+		t.Fatalf("wrong number of statements in body. got=%d", len(function.Body.Statements))
+	}
+
+	yield, ok := function.Body.Statements[1].(*ast.YieldStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.YieldStatement. got=%T", function.Body.Statements[1])
+	}
+
+	testInfixExpression(t, yield.Value, "n", "+", 1)
+
+	p = New(lexer.New("impl Tree { fn* items(self) { yield self.value } }"))
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+
+	impl := program.Statements[0].(*ast.ImplStatement)
+	if !impl.Methods[0].Function.Generator {
+		t.Errorf("method is not a generator")
+	}
+
+	errors := []string{
+		"yield 1;",
+		"fn() { yield 1 }",
+		"fn*() { fn() { yield 1 } }",
+		"fn*() { let f = fn() { 1 }; if (true) { fn(x) { yield x } } }",
+	}
+
+	for _, input := range errors {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 || p.Errors()[0] != "yield outside of a generator function" {
+			t.Errorf("%s: expected a yield error. got=%v", input, p.Errors())
+		}
+	}
+
+	// the body of a nested fn* can yield, and the outer one still can after it
+	p = New(lexer.New("fn*() { let inner = fn*() { yield 1 }; yield 2 }"))
+	p.ParseProgram()
+	checkParserErrors(t, p)
+}
+
 func TestIfElifElseExpression(t *testing.T) {
 	input := `if (x < y) { x } else if (x == y) { y } else { z }`
 
@@ -604,6 +1211,42 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"1 + (2 + 3) + 4",
 			"((1 + (2 + 3)) + 4)",
 		},
+		{
+			"2 * 3 ** 2",
+			"(2 * (3 ** 2))",
+		},
+		{
+			"2 ** 3 ** 2",
+			"(2 ** (3 ** 2))",
+		},
+		{
+			"-2 ** 2",
+			"(-(2 ** 2))",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"1 << 2 + 3 & ~x",
+			"((1 << (2 + 3)) & (~x))",
+		},
+		{
+			"x & 1 == 0",
+			"((x & 1) == 0)",
+		},
+		{
+			"a >> 1 < b << 2",
+			"((a >> 1) < (b << 2))",
+		},
+		{
+			"x + 1 in a | b == true",
+			"(((x + 1) in (a | b)) == true)",
+		},
+		{
+			"!(x in xs)",
+			"(!(x in xs))",
+		},
 		{
 			"(5 + 5) * 2",
 			"((5 + 5) * 2)",
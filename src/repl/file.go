@@ -0,0 +1,51 @@
+package repl
+
+import (
+	"fmt"
+	"io"
+	"monkey/checker"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+)
+
+// Runs the script in the file called name, writing its output to out and any errors to errOut. Imports are
+// searched for next to the script, then in modulePath. Reports whether the script ran without errors
+func RunFile(name string, modulePath []string, out io.Writer, errOut io.Writer) bool {
+	source, err := os.ReadFile(name)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return false
+	}
+
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		printFileErrors(errOut, "parser", name, p.Errors())
+		return false
+	}
+
+	if errors := checker.Check(program); len(errors) != 0 {
+		printFileErrors(errOut, "check", name, errors)
+		return false
+	}
+
+	interpreter := evaluator.New(evaluator.Options{Stdout: out, Stderr: errOut, ModulePath: modulePath})
+
+	if evaluated, ok := interpreter.RunFile(name, program).(*object.Error); ok {
+		fmt.Fprintf(errOut, "%s: %s\n", name, evaluated.Message)
+		return false
+	}
+
+	return true
+}
+
+func printFileErrors(out io.Writer, kind string, name string, errors []string) {
+	fmt.Fprintf(out, "%s errors in %s:\n", kind, name)
+
+	for _, msg := range errors {
+		io.WriteString(out, "\t"+msg+"\n")
+	}
+}
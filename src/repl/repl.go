@@ -4,9 +4,9 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"monkey/checker"
 	"monkey/evaluator"
 	"monkey/lexer"
-	"monkey/object"
 	"monkey/parser"
 )
 
@@ -27,7 +27,7 @@ const MONKEY_FACE = `            __,__
 
 func Start(in io.Reader, out io.Writer) {
 	var scanner = bufio.NewScanner(in)
-	var env = object.NewEnvironment()
+	var interpreter = evaluator.New(evaluator.Options{Stdout: out, Stderr: out})
 
 	for {
 		fmt.Fprint(out, PROMPT)
@@ -47,7 +47,12 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
+		if errors := checker.Check(program); len(errors) != 0 {
+			printErrors(out, "check", errors)
+			continue
+		}
+
+		evaluated := interpreter.Run(program)
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
@@ -56,9 +61,14 @@ func Start(in io.Reader, out io.Writer) {
 }
 
 func printParserErrors(out io.Writer, errors []string) {
+	printErrors(out, "parser", errors)
+}
+
+// kind says what found the errors, e.g. "parser errors:"
+func printErrors(out io.Writer, kind string, errors []string) {
 	io.WriteString(out, MONKEY_FACE)
 	io.WriteString(out, "Whoops! We ran into some monkey business here!\n")
-	io.WriteString(out, "parser errors:\n")
+	io.WriteString(out, kind+" errors:\n")
 
 	for _, msg := range errors {
 		io.WriteString(out, "\t"+msg+"\n")
@@ -0,0 +1,270 @@
+/*
+The resolver works out which variables a function literal closes over, so a closure only has to keep those
+bindings alive instead of the entire scope it was created in.
+
+A name is free in a function when the body reads or assigns it before the function itself binds it. Parameters
+are bound for the whole body, a let, struct, enum or import binds its name from the statement after it
+onwards, a for loop binds its variable inside the loop body and a match pattern binds its names inside its
+arm. A let inside an if or loop block only counts until the end of that block, because at runtime the block
+might not run and the name would still come from the outer scope.
+
+Names used by nested function literals that the outer function doesn't bind are free in the outer function too,
+since it has to capture them so the inner one can. The exception is a name a later let in the same or an
+enclosing block binds: the nested function won't normally be called before that let has run, so it refers to
+the local (this is what lets two local functions call each other).
+
+Getting it slightly wrong in the generous direction is harmless, capturing a name the function never ends up
+looking up just keeps one extra binding alive. Missing a name is not, so anything unclear counts as free.
+*/
+package resolver
+
+import (
+	"monkey/ast"
+)
+
+type resolver struct {
+	declared map[string]bool
+	pending  map[string]bool // let-bound somewhere in the enclosing blocks, maybe not yet
+	seen     map[string]bool
+	free     []string
+}
+
+// Returns the free variables of fn in the order they are first used. A literal the parser has already
+// resolved is answered from fn.FreeVariables without walking the body again
+func FreeVariables(fn *ast.FunctionLiteral) []string {
+	if fn.FreeVariables != nil {
+		return fn.FreeVariables
+	}
+
+	r := &resolver{
+		declared: make(map[string]bool),
+		pending:  make(map[string]bool),
+		seen:     make(map[string]bool),
+		free:     []string{},
+	}
+
+	for _, param := range fn.Parameters {
+		r.declared[param.Value] = true
+	}
+
+	r.block(fn.Body)
+
+	return r.free
+}
+
+func (r *resolver) reference(name string) {
+	if r.declared[name] || r.seen[name] {
+		return
+	}
+
+	r.seen[name] = true
+	r.free = append(r.free, name)
+}
+
+// lets inside a block are forgotten once the block ends, see the package comment
+func (r *resolver) block(block *ast.BlockStatement) {
+	if block == nil {
+		return
+	}
+
+	outerDeclared, outerPending := r.declared, r.pending
+	r.declared, r.pending = copySet(outerDeclared), copySet(outerPending)
+
+	for _, statement := range block.Statements {
+		if export, ok := statement.(*ast.ExportStatement); ok {
+			statement = export.Statement
+		}
+
+		switch statement := statement.(type) {
+		case *ast.LetStatement:
+			if statement != nil {
+				r.pending[statement.Name.Value] = true
+			}
+		case *ast.StructStatement:
+			r.pending[statement.Name.Value] = true
+		case *ast.EnumStatement:
+			r.pending[statement.Name.Value] = true
+		case *ast.ImportStatement:
+			r.pending[statement.Name.Value] = true
+		}
+	}
+
+	for _, statement := range block.Statements {
+		r.statement(statement)
+	}
+
+	r.declared, r.pending = outerDeclared, outerPending
+}
+
+func copySet(set map[string]bool) map[string]bool {
+	copied := make(map[string]bool, len(set))
+	for name := range set {
+		copied[name] = true
+	}
+
+	return copied
+}
+
+func (r *resolver) statement(statement ast.Statement) {
+	switch statement := statement.(type) {
+	case *ast.LetStatement:
+		if statement == nil {
+			return
+		}
+
+		// the value is resolved before the name is bound, so `let x = x + 1` still reads the outer x
+		r.expression(statement.Value)
+		r.declared[statement.Name.Value] = true
+
+	case *ast.StructStatement:
+		r.declared[statement.Name.Value] = true
+
+	case *ast.EnumStatement:
+		r.declared[statement.Name.Value] = true
+
+	case *ast.ImportStatement:
+		r.declared[statement.Name.Value] = true
+
+	case *ast.ExportStatement:
+		r.statement(statement.Statement)
+
+	case *ast.ImplStatement:
+		r.reference(statement.Name.Value)
+
+		for _, method := range statement.Methods {
+			r.expression(method.Function)
+		}
+
+	case *ast.YieldStatement:
+		r.expression(statement.Value)
+
+	case *ast.ReturnStatement:
+		if statement == nil {
+			return
+		}
+
+		r.expression(statement.ReturnValue)
+
+	case *ast.ExpressionStatement:
+		if statement == nil {
+			return
+		}
+
+		r.expression(statement.Expression)
+
+	case *ast.BlockStatement:
+		r.block(statement)
+
+	case *ast.ForStatement:
+		r.expression(statement.Iterable)
+
+		// the loop variable is bound for the body only
+		outer := r.declared
+		r.declared = copySet(outer)
+		r.declared[statement.Variable.Value] = true
+		r.block(statement.Body)
+		r.declared = outer
+	}
+}
+
+func (r *resolver) expression(expression ast.Expression) {
+	switch node := expression.(type) {
+	case *ast.Identifier:
+		r.reference(node.Value)
+
+	case *ast.PrefixExpression:
+		r.expression(node.Right)
+
+	case *ast.InfixExpression:
+		r.expression(node.Left)
+		r.expression(node.Right)
+
+	case *ast.AssignExpression:
+		r.expression(node.Target)
+		r.expression(node.Value)
+
+	case *ast.IfExpression:
+		r.expression(node.Condition)
+		r.block(node.Consequence)
+
+		for _, elseIf := range node.ElseIfs {
+			r.expression(elseIf.Condition)
+			r.block(elseIf.Consequence)
+		}
+
+		r.block(node.Alternative)
+
+	case *ast.MatchExpression:
+		r.expression(node.Subject)
+
+		// the names a pattern binds are bound for its arm only
+		for _, arm := range node.Arms {
+			outer := r.declared
+			r.declared = copySet(outer)
+			r.pattern(arm.Pattern)
+			r.block(arm.Body)
+			r.declared = outer
+		}
+
+	case *ast.FunctionLiteral:
+		// whatever the inner function needs and we don't bind ourselves, we have to capture for it
+		for _, name := range FreeVariables(node) {
+			if !r.pending[name] {
+				r.reference(name)
+			}
+		}
+
+	case *ast.CallExpression:
+		r.expression(node.Function)
+		for _, argument := range node.Arguments {
+			r.expression(argument)
+		}
+
+	case *ast.InterpolatedString:
+		for _, part := range node.Parts {
+			r.expression(part)
+		}
+
+	case *ast.ArrayLiteral:
+		for _, element := range node.Elements {
+			r.expression(element)
+		}
+
+	case *ast.SetLiteral:
+		for _, element := range node.Elements {
+			r.expression(element)
+		}
+
+	case *ast.IndexExpression:
+		r.expression(node.Left)
+		r.expression(node.Index)
+
+	case *ast.SliceExpression:
+		r.expression(node.Left)
+		r.expression(node.Start)
+		r.expression(node.End)
+
+	case *ast.MemberExpression:
+		r.expression(node.Object)
+
+	case *ast.HashLiteral:
+		for _, pair := range node.Pairs {
+			r.expression(pair.Key)
+			r.expression(pair.Value)
+		}
+	}
+}
+
+func (r *resolver) pattern(pattern ast.Pattern) {
+	switch pattern := pattern.(type) {
+	case *ast.BindingPattern:
+		r.declared[pattern.Name.Value] = true
+
+	case *ast.VariantPattern:
+		r.reference(pattern.Enum.Value)
+
+		for _, argument := range pattern.Arguments {
+			r.pattern(argument)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// the parser calls into the resolver, so the tests live outside the package to use it
+package resolver_test
+
+import (
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/resolver"
+	"reflect"
+	"testing"
+)
+
+func TestFreeVariables(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"fn() { 1 }", []string{}},
+		{"fn(x) { x }", []string{}},
+		{"fn(x) { x + y }", []string{"y"}},
+		{"fn() { a + b + a }", []string{"a", "b"}},
+		{"fn() { let a = 1; a }", []string{}},
+		// the value of a let is resolved before its name is bound
+		{"fn() { let a = a + 1; a }", []string{"a"}},
+		// used before the let, so it still has to come from outside
+		{"fn() { let b = a; let a = 1; a }", []string{"a"}},
+		// a let inside a block might not run
+		{"fn(c) { if (c) { let a = 1; a } a }", []string{"a"}},
+		{"fn(c) { if (c) { let a = 1; a } else { b } }", []string{"b"}},
+		{"fn(c) { if (c) { 1 } else if (d) { e } }", []string{"d", "e"}},
+		// nested functions pass on what they need and we don't bind
+		{"fn(x) { fn(y) { x + y + z } }", []string{"z"}},
+		{"fn() { let f = fn() { f() }; f }", []string{}},
+		// a later let in the same block binds what a nested function refers to
+		{"fn() { let even = fn() { odd() }; let odd = fn() { even() }; even }", []string{}},
+		{"fn() { let g = fn() { h() }; g }", []string{"h"}},
+		{"fn(c) { if (c) { let g = fn() { h() }; let h = 1; } g }", []string{"g"}},
+		// but a direct use before the let still reads the outer binding
+		{"fn() { let b = a; let f = fn() { a }; let a = 1; a }", []string{"a"}},
+		{"fn() { count = count + 1 }", []string{"count"}},
+		{"fn(xs) { len(xs[i]) }", []string{"len", "i"}},
+		{"fn() { [a, b][c] }", []string{"a", "b", "c"}},
+		{"fn() { return -a; }", []string{"a"}},
+		{"fn() { db.query(sql) }", []string{"db", "sql"}},
+		{"fn() { a.b = c }", []string{"a", "c"}},
+		// the loop variable is only bound in the body
+		{"fn() { for (x in xs) { let y = x; f(y) } x }", []string{"xs", "f", "x"}},
+		{"fn() { for (x in xs) { fn() { x } } }", []string{"xs"}},
+		{"fn() { struct P { x }; P(1).x }", []string{}},
+		{"fn() { let f = fn() { P(1) }; struct P { x }; f }", []string{}},
+		{"fn() { enum R { A(x), B }; R.A(1) }", []string{}},
+		{"fn() { let f = fn() { R.B }; enum R { A(x), B }; f }", []string{}},
+		// pattern bindings are only bound in their own arm, variant patterns refer to the enum
+		{"fn(r) { match (r) { R.A(v) => v + k, other => other } }", []string{"R", "k"}},
+		{"fn(r) { match (r) { R.A(v) => 1, _ => v } }", []string{"R", "v"}},
+		{"fn(r) { match (r) { n => fn() { n + m } } }", []string{"m"}},
+		{"fn() { match (s) { 1 => a, _ => b } }", []string{"s", "a", "b"}},
+		{`fn() { import "lib" as lib; lib.f(x) }`, []string{"x"}},
+		{"fn*(n) { let i = 0; yield i + n + step }", []string{"step"}},
+		{`fn() { let g = fn() { lib.f() }; import "lib" as lib; g }`, []string{}},
+		{`fn(s) { let n = 1; "${s} has ${n + extra} ${xs[a:b]}" }`, []string{"extra", "xs", "a", "b"}},
+		{`fn(x) { #{x, y} | set(zs) }`, []string{"y", "set", "zs"}},
+	}
+
+	for _, tc := range tests {
+		fn := parseFunctionLiteral(t, tc.input)
+
+		// resolve from scratch instead of reading back what the parser stored
+		fn.FreeVariables = nil
+		free := resolver.FreeVariables(fn)
+
+		if !reflect.DeepEqual(free, tc.expected) {
+			t.Errorf("FreeVariables(%s) wrong. expected=%v, got=%v", tc.input, tc.expected, free)
+		}
+	}
+}
+
+func TestParserResolvesFunctionLiterals(t *testing.T) {
+	fn := parseFunctionLiteral(t, "fn(x) { fn() { x + y } }")
+
+	if !reflect.DeepEqual(fn.FreeVariables, []string{"y"}) {
+		t.Errorf("outer FreeVariables wrong. got=%v", fn.FreeVariables)
+	}
+
+	inner := fn.Body.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if !reflect.DeepEqual(inner.FreeVariables, []string{"x", "y"}) {
+		t.Errorf("inner FreeVariables wrong. got=%v", inner.FreeVariables)
+	}
+}
+
+func parseFunctionLiteral(t *testing.T, input string) *ast.FunctionLiteral {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+
+	statement := program.Statements[0].(*ast.ExpressionStatement)
+	fn, ok := statement.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("expression is not ast.FunctionLiteral. got=%T", statement.Expression)
+	}
+
+	return fn
+}
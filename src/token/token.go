@@ -19,11 +19,20 @@ const (
 	ASTERISK = "*"
 	SLASH    = "/"
 	MOD      = "%"
+	POWER    = "**"
+
+	BIT_AND     = "&"
+	BIT_OR      = "|"
+	BIT_XOR     = "^"
+	BIT_NOT     = "~"
+	SHIFT_LEFT  = "<<"
+	SHIFT_RIGHT = ">>"
 
 	LT     = "<"
 	GT     = ">"
 	EQ     = "=="
 	NOT_EQ = "!="
+	ARROW  = "=>"
 
 	// Delimiters
 	COMMA     = ","
@@ -35,8 +44,10 @@ const (
 	LBRACE   = "{"
 	RBRACE   = "}"
 	LBRACKET = "["
+	SET_OPEN = "#{" // opens a set literal
 	RBRACKET = "]"
 	COLON    = ":"
+	DOT      = "."
 
 	// Keywords
 	FUNCTION = "FUNCTION"
@@ -47,14 +58,30 @@ const (
 	ELSE     = "ELSE"
 	ELSEIF   = "ELSEIF"
 	RETURN   = "RETURN"
+	FOR      = "FOR"
+	IN       = "IN"
+	STRUCT   = "STRUCT"
+	IMPL     = "IMPL"
+	ENUM     = "ENUM"
+	MATCH    = "MATCH"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	AS       = "AS"
+	YIELD    = "YIELD"
 
 	// Data structures
-	STRING = "STRING"
+	STRING   = "STRING"
+	TEMPLATE = "TEMPLATE" // a string with ${...} placeholders, the literal is its source between the quotes
+	BYTES    = "BYTES"    // b"...", the literal is its source between the quotes
 )
 
 type Token struct {
 	Type    TokenType
 	Literal string
+
+	// where the token starts in the input, both count from 1
+	Line   int
+	Column int
 }
 
 var keywords = map[string]TokenType{
@@ -66,6 +93,16 @@ var keywords = map[string]TokenType{
 	"else":    ELSE,
 	"else if": ELSEIF,
 	"return":  RETURN,
+	"for":     FOR,
+	"in":      IN,
+	"struct":  STRUCT,
+	"impl":    IMPL,
+	"enum":    ENUM,
+	"match":   MATCH,
+	"import":  IMPORT,
+	"export":  EXPORT,
+	"as":      AS,
+	"yield":   YIELD,
 }
 
 // Check if the identifier is in the hashmap (fn, let, etc.). If its not in the hashmap, we can assume its a variable name